@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/joho/godotenv"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+	"gitlab.com/leorodriguez/grupo-04/internal/reconciliation"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+)
+
+// reconcile compares every account balance with the sum of its transactions
+// and with the ledger, and writes the differences found as JSON and CSV
+// reports. It is meant to run nightly; it exits with status 2 when it leaves
+// discrepancies unfixed. Run it once with -backfill after deploying the
+// ledger, so the balances accounts already had get their opening entries.
+func main() {
+	date := time.Now().UTC().Format("20060102")
+	fix := flag.Bool("fix", false, "record an adjustment transaction or ledger entry for every discrepancy")
+	backfill := flag.Bool("backfill", false, "post opening ledger entries for balances that predate the ledger first")
+	jsonPath := flag.String("json", fmt.Sprintf("reconciliation-%s.json", date), "JSON report path")
+	csvPath := flag.String("csv", fmt.Sprintf("reconciliation-%s.csv", date), "CSV report path")
+	envPath := flag.String("env", "../../.env", "env file with the database settings")
+	flag.Parse()
+
+	logger.Init()
+	if err := godotenv.Load(*envPath); err != nil {
+		log.Fatal(err)
+	}
+
+	dataSource := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", os.Getenv("DB_USER"), os.Getenv("DB_PASS"),
+		os.Getenv("DB_HOST"), os.Getenv("DB_NAME"))
+
+	db, err := sql.Open("mysql", dataSource)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ledgerRepository := ledger.NewRepository(db)
+	if *backfill {
+		opened, err := ledgerRepository.BackfillOpeningBalances(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		logger.Info(fmt.Sprintf("posted %d opening balances", opened))
+	}
+
+	service := reconciliation.NewService(reconciliation.NewRepository(db), ledgerRepository)
+	report, err := service.Reconcile(context.Background(), *fix)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = writeReport(*jsonPath, report, reconciliation.WriteJSON); err != nil {
+		log.Fatal(err)
+	}
+	if err = writeReport(*csvPath, report, reconciliation.WriteCSV); err != nil {
+		log.Fatal(err)
+	}
+
+	unfixed := 0
+	for _, d := range report.Discrepancies {
+		if !d.Fixed() {
+			unfixed++
+		}
+	}
+	logger.Info(fmt.Sprintf("checked %d accounts, found %d discrepancies, %d left unfixed",
+		report.AccountsChecked, len(report.Discrepancies), unfixed))
+
+	if unfixed > 0 {
+		os.Exit(2)
+	}
+}
+
+func writeReport(path string, report reconciliation.Report, write func(io.Writer, reconciliation.Report) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = write(f, report); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -78,42 +78,6 @@ func (t *AccountsHandler) GetUser(ctx *gin.Context) {
 	web.Response(ctx, http.StatusOK, account)
 }
 
-// Transactions  godoc
-// @Summary      Get last five transactions info
-// @Description  Get last five transactions info
-// @Tags         transactions
-// @Accept       json
-// @Produce      json
-// @Param        Authorization  header   string  true  "Authorization"
-// @Param        accountID   path   int   true  "accountID"
-// @Success      200  {object}  []domain.TransactionInfo
-// @Failure      400  {string} string  "invalid id"
-// @Failure      500  {string} string  "Internal error"
-// @Router       /accounts/{accountID}/transactions [get]
-func (t *AccountsHandler) GetTransactionsLastFive(ctx *gin.Context) {
-	token := ctx.GetHeader("Authorization")
-	idParam := ctx.Param("accountID")
-	id, err := strconv.Atoi(idParam)
-	if err != nil {
-		web.Error(ctx, http.StatusBadRequest, "invalid id")
-		return
-	}
-
-	trxs, err := t.service.GetTransactionsLastFive(ctx, id, token)
-	if err != nil {
-
-		web.Error(ctx, http.StatusInternalServerError, "Internal error")
-		return
-	}
-
-	if trxs == nil {
-		web.Response(ctx, http.StatusOK, []domain.Transaction{})
-		return
-	}
-
-	web.Response(ctx, http.StatusOK, trxs)
-}
-
 // Accounts godoc
 // @Summary      Change alias account info
 // @Description  Change alias account info
@@ -122,7 +86,7 @@ func (t *AccountsHandler) GetTransactionsLastFive(ctx *gin.Context) {
 // @Produce      json
 // @Param        accountID   path   int   true  "accountID"
 // @Success      200  {string} string  "Ok"
-// @Failure      400  {string} string  "invalid id, Bad json, Required field or Alias already in use"
+// @Failure      400  {string} string  "invalid id, Bad json, Required field, Invalid alias, Alias not allowed or Alias already in use"
 // @Failure      500  {string} string  "Internal error"
 // @Router       /accounts/{accountID} [patch]
 func (t *AccountsHandler) ChangeAlias() gin.HandlerFunc {
@@ -163,6 +127,10 @@ func (t *AccountsHandler) ChangeAlias() gin.HandlerFunc {
 			switch err {
 			case accounts.ErrAliasAlreadyExists:
 				web.Error(ctx, http.StatusBadRequest, "Alias already in use")
+			case accounts.ErrInvalidAlias:
+				web.Error(ctx, http.StatusBadRequest, "Invalid alias: use 6 to 20 letters, digits, dots or hyphens")
+			case accounts.ErrAliasBlocked:
+				web.Error(ctx, http.StatusBadRequest, "Alias not allowed")
 			default:
 				web.Error(ctx, http.StatusInternalServerError, "Internal error")
 			}
@@ -175,6 +143,99 @@ func (t *AccountsHandler) ChangeAlias() gin.HandlerFunc {
 	}
 }
 
+// Accounts godoc
+// @Summary      Look up a destination account
+// @Description  Get the masked holder name and DNI of the account with the CVU or alias, to confirm it before transferring. Rate limited.
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        cvu   query   string   false  "cvu"
+// @Param        alias   query   string   false  "alias"
+// @Success      200  {object}  domain.AccountLookup
+// @Failure      400  {string} string  "Required fields or Invalid CVU"
+// @Failure      404  {string} string  "Account not found"
+// @Failure      429  {string} string  "Too many requests"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/lookup [get]
+func (t *AccountsHandler) Lookup(ctx *gin.Context) {
+	cvu := ctx.Query("cvu")
+	alias := ctx.Query("alias")
+	if (cvu == "") == (alias == "") {
+		web.Error(ctx, http.StatusBadRequest, "Required fields: cvu or alias")
+		return
+	}
+
+	lookup, err := t.service.Lookup(ctx, cvu, alias)
+	if err != nil {
+		switch err {
+		case accounts.ErrInvalidCVU:
+			web.Error(ctx, http.StatusBadRequest, "Invalid CVU")
+		case accounts.ErrAccountNotFound:
+			web.Error(ctx, http.StatusNotFound, "Account not found")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, lookup)
+}
+
+// Accounts godoc
+// @Summary      Check alias availability
+// @Description  Tell whether an alias is valid and free to be taken
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        alias   path   string   true  "alias"
+// @Success      200  {object}  domain.AliasAvailability
+// @Failure      500  {string} string  "Internal error"
+// @Router       /aliases/{alias}/availability [get]
+func (t *AccountsHandler) CheckAliasAvailability(ctx *gin.Context) {
+	availability, err := t.service.CheckAliasAvailability(ctx, ctx.Param("alias"))
+	if err != nil {
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, availability)
+}
+
+// Accounts godoc
+// @Summary      Get alias history
+// @Description  Get the alias changes of the account, newest first
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Success      200  {object}  []domain.AliasChange
+// @Failure      400  {string} string  "invalid id"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/alias-history [get]
+func (t *AccountsHandler) GetAliasHistory(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	history, err := t.service.GetAliasHistory(ctx, id)
+	if err != nil {
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, history)
+}
+
 // Users godoc
 // @Summary      Update user info
 // @Description  Update user info
@@ -131,29 +131,79 @@ func (ah *AuthHandler) Login() gin.HandlerFunc {
 	}
 }
 
+// Authorization godoc
+// @Summary      Refresh session
+// @Description  new access and refresh tokens for a refresh token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        RefreshRequest   body   domain.RefreshRequest  true  "RefreshRequest"
+// @Success      200  {object} domain.LoginResponse
+// @Failure      400  {string} string  "Refresh token is required"
+// @Failure      401  {string} string  "Session expired. Please login again"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /users/token/refresh [post]
+func (ah *AuthHandler) Refresh() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var rq domain.RefreshRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil || rq.RefreshToken == "" {
+			web.Error(ctx, http.StatusBadRequest, "Refresh token is required")
+
+			return
+		}
+
+		response, err := ah.usersService.Refresh(ctx, rq.RefreshToken)
+		if err != nil {
+			logger.Error(err.Error())
+			switch err {
+			case users.ErrInvalidRefreshToken:
+				web.Error(ctx, http.StatusUnauthorized, "Session expired. Please login again")
+			default:
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusOK, response)
+		return
+	}
+}
+
 // Authorization godoc
 // @Summary      Logout session
-// @Description  user logout
+// @Description  user logout, revokes the refresh token of the user of the access token
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        Authorization  header   string  true  "Authorization"
+// @Param        RefreshRequest   body   domain.RefreshRequest  true  "RefreshRequest"
 // @Success      200  {string} string  "ok"
+// @Failure      400  {string} string  "Refresh token is required or Invalid refresh token"
+// @Failure      401  {string} string  "Invalid token"
 // @Failure      500  {string} string  "Internal error"
 // @Router       /users/logout [get]
+// @Router       /users/logout [post]
 func (ah *AuthHandler) Logout() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token, ok := ctx.Request.Header["Authorization"]
-		if !ok {
-			web.Error(ctx, http.StatusBadRequest, "Internal error")
+		var rq domain.RefreshRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil || rq.RefreshToken == "" {
+			web.Error(ctx, http.StatusBadRequest, "Refresh token is required")
 
 			return
 		}
 
-		err := ah.usersService.Logout(ctx, strings.Split(token[0], " ")[1])
+		err := ah.usersService.Logout(ctx, ctx.GetHeader("Authorization"), rq.RefreshToken)
 		if err != nil {
 			logger.Error(err.Error())
-			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			switch err {
+			case users.ErrInvalidRefreshToken:
+				web.Error(ctx, http.StatusBadRequest, "Invalid refresh token")
+			case users.ErrInvalidToken:
+				web.Error(ctx, http.StatusUnauthorized, "Invalid token")
+			default:
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
 
 			return
 		}
@@ -184,8 +234,9 @@ func (ah *AuthHandler) ForgotPassword() gin.HandlerFunc {
 			return
 		}
 
+		// unknown emails get the same answer, so they cannot be told apart
 		err := ah.usersService.ForgotPassword(ctx, rq.Email)
-		if err != nil {
+		if err != nil && err != users.ErrUserNotExists {
 			logger.Error(err.Error())
 			web.Error(ctx, http.StatusInternalServerError, "Internal error")
 
@@ -197,6 +248,82 @@ func (ah *AuthHandler) ForgotPassword() gin.HandlerFunc {
 	}
 }
 
+// Authorization godoc
+// @Summary      Verify email
+// @Description  verify the email with the code sent to it, when the identity provider does not host that page
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        VerifyEmailRequest   body   domain.VerifyEmailRequest  true  "VerifyEmailRequest"
+// @Success      200  {string} string  "ok"
+// @Failure      400  {string} string  "Code is required or Invalid or expired code"
+// @Failure      501  {string} string  "Not supported by the identity provider"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /users/verify-email [post]
+func (ah *AuthHandler) VerifyEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var rq domain.VerifyEmailRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil || rq.Code == "" {
+			web.Error(ctx, http.StatusBadRequest, "Code is required")
+
+			return
+		}
+
+		if err := ah.usersService.VerifyEmail(ctx, rq.Code); err != nil {
+			emailCodeError(ctx, err)
+
+			return
+		}
+
+		web.Response(ctx, http.StatusOK, "ok")
+		return
+	}
+}
+
+// Authorization godoc
+// @Summary      Reset password
+// @Description  set a new password with the code of the forgot password email, when the identity provider does not host that page
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        ResetPasswordRequest   body   domain.ResetPasswordRequest  true  "ResetPasswordRequest"
+// @Success      200  {string} string  "ok"
+// @Failure      400  {string} string  "All fields are required or Invalid or expired code"
+// @Failure      501  {string} string  "Not supported by the identity provider"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /users/reset-password [post]
+func (ah *AuthHandler) ResetPassword() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var rq domain.ResetPasswordRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil || rq.Code == "" || rq.Password == "" {
+			web.Error(ctx, http.StatusBadRequest, "All fields are required")
+
+			return
+		}
+
+		if err := ah.usersService.ResetPassword(ctx, rq.Code, rq.Password); err != nil {
+			emailCodeError(ctx, err)
+
+			return
+		}
+
+		web.Response(ctx, http.StatusOK, "ok")
+		return
+	}
+}
+
+func emailCodeError(ctx *gin.Context, err error) {
+	logger.Error(err.Error())
+	switch err {
+	case users.ErrInvalidCode:
+		web.Error(ctx, http.StatusBadRequest, "Invalid or expired code")
+	case users.ErrUnsupported:
+		web.Error(ctx, http.StatusNotImplemented, "Not supported by the identity provider")
+	default:
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+	}
+}
+
 func (ah *AuthHandler) HasToken(ctx *gin.Context) {
 	token := ctx.GetHeader("Authorization")
 	if token == "" {
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
 	"gitlab.com/leorodriguez/grupo-04/internal/users"
 	"net/http"
@@ -19,18 +20,18 @@ type usersMock struct {
 	users.Service
 }
 
-func (u *usersMock) Register(ctx context.Context, rq domain.RegisterRequest) (users.UserDto, error) {
+func (u *usersMock) Login(ctx context.Context, rq domain.LoginRequest) (domain.LoginResponse, error) {
 	args := u.Called(rq)
-	return args.Get(0).(users.UserDto), args.Error(1)
+	return args.Get(0).(domain.LoginResponse), args.Error(1)
 }
 
-func (u *usersMock) Login(ctx context.Context, rq domain.LoginRequest) (domain.LoginResponse, error) {
-	args := u.Called(rq)
+func (u *usersMock) Refresh(ctx context.Context, refreshToken string) (domain.LoginResponse, error) {
+	args := u.Called(refreshToken)
 	return args.Get(0).(domain.LoginResponse), args.Error(1)
 }
 
-func (u *usersMock) Logout(ctx context.Context, token string) error {
-	args := u.Called(token)
+func (u *usersMock) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	args := u.Called(accessToken, refreshToken)
 	return args.Error(0)
 }
 
@@ -39,6 +40,27 @@ func (u *usersMock) ForgotPassword(ctx context.Context, email string) error {
 	return args.Error(0)
 }
 
+func (u *usersMock) VerifyEmail(ctx context.Context, code string) error {
+	args := u.Called(code)
+	return args.Error(0)
+}
+
+func (u *usersMock) ResetPassword(ctx context.Context, code string, password string) error {
+	args := u.Called(code, password)
+	return args.Error(0)
+}
+
+type accountsMock struct {
+	mock.Mock
+	accounts.Service
+}
+
+func (a *accountsMock) Register(ctx context.Context, rq domain.RegisterRequest) (*users.UserDto, error) {
+	args := a.Called(rq)
+	user := args.Get(0).(users.UserDto)
+	return &user, args.Error(1)
+}
+
 func createRequest(method string, url string, body string) (*http.Request, *httptest.ResponseRecorder) {
 	req := httptest.NewRequest(method, url, bytes.NewBuffer([]byte(body)))
 	req.Header.Add("Content-Type", "application/json")
@@ -65,7 +87,7 @@ func TestRegister(t *testing.T) {
 				"password": "password"
 			}`,
 			handler: func() AuthHandler {
-				serviceMock := new(usersMock)
+				serviceMock := new(accountsMock)
 
 				rq := domain.RegisterRequest{
 					Name:     "name",
@@ -88,7 +110,7 @@ func TestRegister(t *testing.T) {
 				serviceMock.On("Register", rq).
 					Return(user, nil)
 
-				handler := NewAuthHandler(serviceMock)
+				handler := NewAuthHandler(new(usersMock), serviceMock)
 				return handler
 			}(),
 			responseStatus: http.StatusOK,
@@ -100,8 +122,8 @@ func TestRegister(t *testing.T) {
 				"name": name,
 			}`,
 			handler: func() AuthHandler {
-				serviceMock := new(usersMock)
-				handler := NewAuthHandler(serviceMock)
+				serviceMock := new(accountsMock)
+				handler := NewAuthHandler(new(usersMock), serviceMock)
 				return handler
 			}(),
 			responseStatus: http.StatusBadRequest,
@@ -115,8 +137,8 @@ func TestRegister(t *testing.T) {
 				"dni": 21312731
 			}`,
 			handler: func() AuthHandler {
-				serviceMock := new(usersMock)
-				handler := NewAuthHandler(serviceMock)
+				serviceMock := new(accountsMock)
+				handler := NewAuthHandler(new(usersMock), serviceMock)
 				return handler
 			}(),
 			responseStatus: http.StatusBadRequest,
@@ -133,7 +155,7 @@ func TestRegister(t *testing.T) {
 				"password": "password"
 			}`,
 			handler: func() AuthHandler {
-				serviceMock := new(usersMock)
+				serviceMock := new(accountsMock)
 
 				rq := domain.RegisterRequest{
 					Name:     "name",
@@ -146,7 +168,7 @@ func TestRegister(t *testing.T) {
 				serviceMock.On("Register", rq).
 					Return(users.UserDto{}, users.ErrEmailAlreadyRegistered)
 
-				handler := NewAuthHandler(serviceMock)
+				handler := NewAuthHandler(new(usersMock), serviceMock)
 				return handler
 			}(),
 			responseStatus: http.StatusBadRequest,
@@ -163,7 +185,7 @@ func TestRegister(t *testing.T) {
 				"password": "password"
 			}`,
 			handler: func() AuthHandler {
-				serviceMock := new(usersMock)
+				serviceMock := new(accountsMock)
 
 				rq := domain.RegisterRequest{
 					Name:     "name",
@@ -176,7 +198,7 @@ func TestRegister(t *testing.T) {
 				serviceMock.On("Register", rq).
 					Return(users.UserDto{}, errors.New("internal error"))
 
-				handler := NewAuthHandler(serviceMock)
+				handler := NewAuthHandler(new(usersMock), serviceMock)
 				return handler
 			}(),
 			responseStatus: http.StatusInternalServerError,
@@ -225,16 +247,19 @@ func TestLogin(t *testing.T) {
 					Password: "password",
 				}
 				response := domain.LoginResponse{
-					Token: "asdasd",
+					Token:            "asdasd",
+					ExpiresIn:        300,
+					RefreshToken:     "refresh",
+					RefreshExpiresIn: 1800,
 				}
 				serviceMock.On("Login", rq).
 					Return(response, nil)
 
-				handler := NewAuthHandler(serviceMock)
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
 				return handler
 			}(),
 			responseStatus: http.StatusOK,
-			responseBody:   `{"token":"asdasd"}`,
+			responseBody:   `{"token":"asdasd","expires_in":300,"refresh_token":"refresh","refresh_expires_in":1800}`,
 		},
 	}
 
@@ -257,29 +282,169 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestRefresh(t *testing.T) {
+	var tests = []struct {
+		name           string
+		body           string
+		handler        AuthHandler
+		responseStatus int
+		responseBody   string
+	}{
+		{
+			name: "refresh - ok",
+			body: `{"refresh_token": "refresh"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				response := domain.LoginResponse{
+					Token:            "new",
+					ExpiresIn:        300,
+					RefreshToken:     "newRefresh",
+					RefreshExpiresIn: 1800,
+				}
+				serviceMock.On("Refresh", "refresh").
+					Return(response, nil)
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusOK,
+			responseBody:   `{"token":"new","expires_in":300,"refresh_token":"newRefresh","refresh_expires_in":1800}`,
+		},
+		{
+			name:           "refresh - missing token",
+			body:           `{}`,
+			handler:        NewAuthHandler(new(usersMock), new(accountsMock)),
+			responseStatus: http.StatusBadRequest,
+			responseBody:   `{"code":"bad_request","message":"Refresh token is required"}`,
+		},
+		{
+			name: "refresh - expired token",
+			body: `{"refresh_token": "expired"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				serviceMock.On("Refresh", "expired").
+					Return(domain.LoginResponse{}, users.ErrInvalidRefreshToken)
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusUnauthorized,
+			responseBody:   `{"code":"unauthorized","message":"Session expired. Please login again"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := gin.Default()
+			rg := r.Group("/api")
+			rg.POST("/users/token/refresh", tt.handler.Refresh())
+
+			req, rr := createRequest(http.MethodPost, "http://localhost:8080/api/users/token/refresh", tt.body)
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.responseStatus, rr.Code)
+			assert.Equal(t, tt.responseBody, rr.Body.String())
+		})
+	}
+}
+
 func TestLogout(t *testing.T) {
 	var tests = []struct {
 		name           string
+		method         string
 		token          string
+		body           string
 		handler        AuthHandler
 		responseStatus int
 		responseBody   string
 	}{
 		{
-			name:  "login - ok",
+			name:  "logout - ok",
+			token: "Bearer tokenAsd",
+			body:  `{"refresh_token": "refresh"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				serviceMock.On("Logout", "Bearer tokenAsd", "refresh").
+					Return(nil)
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusOK,
+			responseBody:   `"ok"`,
+		},
+		{
+			name:  "logout - revoked token",
 			token: "Bearer tokenAsd",
+			body:  `{"refresh_token": "revoked"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				serviceMock.On("Logout", "Bearer tokenAsd", "revoked").
+					Return(users.ErrInvalidRefreshToken)
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusBadRequest,
+			responseBody:   `{"code":"bad_request","message":"Invalid refresh token"}`,
+		},
+		{
+			name:   "logout - with GET",
+			method: http.MethodGet,
+			token:  "Bearer tokenAsd",
+			body:   `{"refresh_token": "refresh"}`,
 			handler: func() AuthHandler {
 				serviceMock := new(usersMock)
 
-				serviceMock.On("Logout", "tokenAsd").
+				serviceMock.On("Logout", "Bearer tokenAsd", "refresh").
 					Return(nil)
 
-				handler := NewAuthHandler(serviceMock)
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
 				return handler
 			}(),
 			responseStatus: http.StatusOK,
 			responseBody:   `"ok"`,
 		},
+		{
+			name:  "logout - refresh token of another user",
+			token: "Bearer tokenAsd",
+			body:  `{"refresh_token": "other"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				serviceMock.On("Logout", "Bearer tokenAsd", "other").
+					Return(users.ErrInvalidRefreshToken)
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusBadRequest,
+			responseBody:   `{"code":"bad_request","message":"Invalid refresh token"}`,
+		},
+		{
+			name:  "logout - invalid access token",
+			token: "Bearer expired",
+			body:  `{"refresh_token": "refresh"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				serviceMock.On("Logout", "Bearer expired", "refresh").
+					Return(users.ErrInvalidToken)
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusUnauthorized,
+			responseBody:   `{"code":"unauthorized","message":"Invalid token"}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -290,12 +455,15 @@ func TestLogout(t *testing.T) {
 			r := gin.Default()
 			rg := r.Group("/api")
 			rg.GET("/users/logout", tt.handler.HasToken, tt.handler.Logout())
+			rg.POST("/users/logout", tt.handler.HasToken, tt.handler.Logout())
 
-			req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/api/users/logout", nil)
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req, rr := createRequest(method, "http://localhost:8080/api/users/logout", tt.body)
 			req.Header.Add("Authorization", tt.token)
 
-			rr := httptest.NewRecorder()
-
 			r.ServeHTTP(rr, req)
 
 			assert.Equal(t, tt.responseStatus, rr.Code)
@@ -323,12 +491,46 @@ func TestForgotPassword(t *testing.T) {
 				serviceMock.On("ForgotPassword", "email@c.com").
 					Return(nil)
 
-				handler := NewAuthHandler(serviceMock)
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusOK,
+			responseBody:   `"ok"`,
+		},
+		{
+			name: "unknown email - ok",
+			body: `{
+				"email": "nobody@c.com"
+			}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				serviceMock.On("ForgotPassword", "nobody@c.com").
+					Return(users.ErrUserNotExists)
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
 				return handler
 			}(),
 			responseStatus: http.StatusOK,
 			responseBody:   `"ok"`,
 		},
+		{
+			name: "provider error",
+			body: `{
+				"email": "email@c.com"
+			}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+
+				serviceMock.On("ForgotPassword", "email@c.com").
+					Return(errors.New("connection refused"))
+
+				handler := NewAuthHandler(serviceMock, new(accountsMock))
+				return handler
+			}(),
+			responseStatus: http.StatusInternalServerError,
+			responseBody:   `{"code":"internal_server_error","message":"Internal error"}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -351,3 +553,112 @@ func TestForgotPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyEmail(t *testing.T) {
+	var tests = []struct {
+		name           string
+		body           string
+		handler        AuthHandler
+		responseStatus int
+		responseBody   string
+	}{
+		{
+			name: "verify - ok",
+			body: `{"code": "abc"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+				serviceMock.On("VerifyEmail", "abc").Return(nil)
+				return NewAuthHandler(serviceMock, new(accountsMock))
+			}(),
+			responseStatus: http.StatusOK,
+			responseBody:   `"ok"`,
+		},
+		{
+			name: "verify - invalid code",
+			body: `{"code": "abc"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+				serviceMock.On("VerifyEmail", "abc").Return(users.ErrInvalidCode)
+				return NewAuthHandler(serviceMock, new(accountsMock))
+			}(),
+			responseStatus: http.StatusBadRequest,
+			responseBody:   `{"code":"bad_request","message":"Invalid or expired code"}`,
+		},
+		{
+			name: "verify - done on the provider pages",
+			body: `{"code": "abc"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+				serviceMock.On("VerifyEmail", "abc").Return(users.ErrUnsupported)
+				return NewAuthHandler(serviceMock, new(accountsMock))
+			}(),
+			responseStatus: http.StatusNotImplemented,
+			responseBody:   `{"code":"not_implemented","message":"Not supported by the identity provider"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := gin.Default()
+			rg := r.Group("/api")
+			rg.POST("/users/verify-email", tt.handler.VerifyEmail())
+
+			req, rr := createRequest(http.MethodPost, "http://localhost:8080/api/users/verify-email", tt.body)
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.responseStatus, rr.Code)
+			assert.Equal(t, tt.responseBody, rr.Body.String())
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	var tests = []struct {
+		name           string
+		body           string
+		handler        AuthHandler
+		responseStatus int
+		responseBody   string
+	}{
+		{
+			name: "reset - ok",
+			body: `{"code": "abc", "password": "new"}`,
+			handler: func() AuthHandler {
+				serviceMock := new(usersMock)
+				serviceMock.On("ResetPassword", "abc", "new").Return(nil)
+				return NewAuthHandler(serviceMock, new(accountsMock))
+			}(),
+			responseStatus: http.StatusOK,
+			responseBody:   `"ok"`,
+		},
+		{
+			name:           "reset - missing password",
+			body:           `{"code": "abc"}`,
+			handler:        NewAuthHandler(new(usersMock), new(accountsMock)),
+			responseStatus: http.StatusBadRequest,
+			responseBody:   `{"code":"bad_request","message":"All fields are required"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := gin.Default()
+			rg := r.Group("/api")
+			rg.POST("/users/reset-password", tt.handler.ResetPassword())
+
+			req, rr := createRequest(http.MethodPost, "http://localhost:8080/api/users/reset-password", tt.body)
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.responseStatus, rr.Code)
+			assert.Equal(t, tt.responseBody, rr.Body.String())
+		})
+	}
+}
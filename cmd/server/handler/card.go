@@ -2,6 +2,7 @@ package handler
 
 import "C"
 import (
+	"errors"
 	"net/http"
 	"reflect"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
 	"gitlab.com/leorodriguez/grupo-04/internal/cards"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/processor"
 	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
 	"gitlab.com/leorodriguez/grupo-04/pkg/web"
 )
@@ -36,7 +38,7 @@ func NewCardHandler(cardsService cards.Service, accountsService accounts.Service
 // @Param        accountID   path   int   true  "accountID"
 // @Param        NewCard   body  domain.CardDto  true  "NewCard"
 // @Success      200  {string} string  "Ok"
-// @Failure      400  {string} string  "invalid id, bad json, Required fields, Card already associated to another account"
+// @Failure      400  {string} string  "invalid id, bad json, Required fields, Invalid card (with the failing fields), Card already associated to another account"
 // @Failure      500  {string} string  "Internal error"
 // @Router       /accounts/{accountID}/cards [post]
 func (c *CardHandler) NewCard() gin.HandlerFunc {
@@ -60,8 +62,9 @@ func (c *CardHandler) NewCard() gin.HandlerFunc {
 		rqRefType := reflect.TypeOf(rq)
 		var valuesNil []string
 		for i := 0; i < rqRefValue.NumField(); i++ {
-			if e := rqRefValue.Field(i); e.IsZero() {
-				valuesNil = append(valuesNil, rqRefType.Field(i).Tag.Get("json"))
+			tag := rqRefType.Field(i).Tag.Get("json")
+			if e := rqRefValue.Field(i); e.IsZero() && tag != "-" {
+				valuesNil = append(valuesNil, tag)
 			}
 		}
 
@@ -73,6 +76,12 @@ func (c *CardHandler) NewCard() gin.HandlerFunc {
 		err = c.cardsService.Save(ctx, id, rq)
 		if err != nil {
 			logger.Error(err.Error())
+			var validationErr *cards.ValidationError
+			if errors.As(err, &validationErr) {
+				web.ErrorWithDetails(ctx, http.StatusBadRequest, validationErr.Fields, "Invalid card")
+				return
+			}
+
 			switch err {
 			case cards.ErrCardAlreadyAssociated:
 				web.Error(ctx, http.StatusBadRequest, "Card already associated to another account")
@@ -157,20 +166,85 @@ func (c *CardHandler) GetByCardID(ctx *gin.Context) {
 	web.Response(ctx, http.StatusOK, card)
 }
 
+// Card godoc
+// @Summary      Update card
+// @Description  Change the holder name or nickname of a card, or make it the default card for deposits
+// @Tags         card
+// @Accept       json
+// @Produce      json
+// @Param        accountID   path   int   true  "accountID"
+// @Param        cardID   path   int   true  "cardID"
+// @Param        CardUpdate   body  domain.CardUpdate  true  "CardUpdate"
+// @Success      200  {object}  domain.Card
+// @Failure      400  {string} string  "invalid account id, invalid card id, Bad json, Invalid card (with the failing fields)"
+// @Failure      404  {string} string  "Card not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/cards/{cardID} [patch]
+func (c *CardHandler) Update() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accountID, err := strconv.Atoi(ctx.Param("accountID"))
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid account id")
+			return
+		}
+
+		cardID, err := strconv.Atoi(ctx.Param("cardID"))
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid card id")
+			return
+		}
+
+		var rq domain.CardUpdate
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		card, err := c.cardsService.Update(ctx, accountID, cardID, rq)
+		if err != nil {
+			var validationErr *cards.ValidationError
+			if errors.As(err, &validationErr) {
+				web.ErrorWithDetails(ctx, http.StatusBadRequest, validationErr.Fields, "Invalid card")
+				return
+			}
+
+			switch err {
+			case cards.ErrCardNotFound:
+				web.Error(ctx, http.StatusNotFound, "Card not found")
+			default:
+				logger.Error(err.Error())
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+			return
+		}
+
+		web.Response(ctx, http.StatusOK, card)
+	}
+}
+
 // Card godoc
 // @Summary      Delete card by id
-// @Description  Delete card by id
+// @Description  Delete card by id. The card is kept for the history of the deposits made with it
 // @Tags         card
 // @Accept       json
 // @Produce      json
 // @Param        accountID   path   int   true  "accountID"
 // @Param        cardID   path   int   true  "cardID"
 // @Success      200  {string} string  "ok"
-// @Failure      400  {string} string  "invalid card id"
+// @Failure      400  {string} string  "invalid account id, invalid card id"
 // @Failure      404  {string} string  "Card not found"
 // @Failure      500  {string} string  "Internal error"
 // @Router       /accounts/{accountID}/cards/{cardID} [delete]
 func (c *CardHandler) DeleteByCardID(ctx *gin.Context) {
+	accountIDParam := ctx.Param("accountID")
+	accountID, err := strconv.Atoi(accountIDParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
 	cardIDParam := ctx.Param("cardID")
 	cardID, err := strconv.Atoi(cardIDParam)
 	if err != nil {
@@ -178,7 +252,7 @@ func (c *CardHandler) DeleteByCardID(ctx *gin.Context) {
 		return
 	}
 
-	err = c.cardsService.DeleteByCardID(ctx, cardID)
+	err = c.cardsService.DeleteByCardID(ctx, accountID, cardID)
 	if err != nil {
 		switch err {
 		case cards.ErrCardNotFound:
@@ -190,3 +264,122 @@ func (c *CardHandler) DeleteByCardID(ctx *gin.Context) {
 	}
 	web.Response(ctx, http.StatusOK, "OK")
 }
+
+// Card godoc
+// @Summary      Start card verification
+// @Description  Charge the card two small random amounts that have to be confirmed to verify it. Starting again replaces the previous charges
+// @Tags         card
+// @Accept       json
+// @Produce      json
+// @Param        accountID   path   int   true  "accountID"
+// @Param        cardID   path   int   true  "cardID"
+// @Success      201  {object}  domain.CardVerificationResult
+// @Failure      400  {string} string  "invalid account id, invalid card id"
+// @Failure      402  {string} string  "Payment declined"
+// @Failure      404  {string} string  "Card not found"
+// @Failure      409  {string} string  "Card already verified, Card locked"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/cards/{cardID}/verification [post]
+func (c *CardHandler) StartVerification(ctx *gin.Context) {
+	accountID, cardID, ok := cardParams(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := c.cardsService.StartVerification(ctx, accountID, cardID)
+	if err != nil {
+		switch err {
+		case cards.ErrCardNotFound:
+			web.Error(ctx, http.StatusNotFound, "Card not found")
+		case cards.ErrCardAlreadyVerified:
+			web.Error(ctx, http.StatusConflict, "Card already verified")
+		case cards.ErrCardLocked:
+			web.Error(ctx, http.StatusConflict, "Card locked")
+		case processor.ErrPaymentDeclined:
+			web.Error(ctx, http.StatusPaymentRequired, "Payment declined")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+		return
+	}
+
+	web.Response(ctx, http.StatusCreated, result)
+}
+
+// Card godoc
+// @Summary      Verify card
+// @Description  Confirm the amounts charged to the card by the verification. The card is locked after 3 wrong attempts
+// @Tags         card
+// @Accept       json
+// @Produce      json
+// @Param        accountID   path   int   true  "accountID"
+// @Param        cardID   path   int   true  "cardID"
+// @Param        CardVerifyRequest   body  domain.CardVerifyRequest  true  "CardVerifyRequest"
+// @Success      200  {object}  domain.CardVerificationResult
+// @Failure      400  {string} string  "invalid account id, invalid card id, Bad json, Invalid amounts"
+// @Failure      404  {string} string  "Card not found"
+// @Failure      409  {string} string  "Verification not started, Card already verified, Card locked"
+// @Failure      422  {string} string  "Amounts do not match (with the attempts left)"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/cards/{cardID}/verify [post]
+func (c *CardHandler) Verify() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accountID, cardID, ok := cardParams(ctx)
+		if !ok {
+			return
+		}
+
+		var rq domain.CardVerifyRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		result, err := c.cardsService.Verify(ctx, accountID, cardID, rq)
+		if err != nil {
+			var validationErr *cards.ValidationError
+			if errors.As(err, &validationErr) {
+				web.ErrorWithDetails(ctx, http.StatusBadRequest, validationErr.Fields, "Invalid amounts")
+				return
+			}
+
+			switch err {
+			case cards.ErrAmountsMismatch:
+				web.ErrorWithDetails(ctx, http.StatusUnprocessableEntity, result, "Amounts do not match")
+			case cards.ErrCardLocked:
+				web.ErrorWithDetails(ctx, http.StatusConflict, result, "Card locked")
+			case cards.ErrCardNotFound:
+				web.Error(ctx, http.StatusNotFound, "Card not found")
+			case cards.ErrVerificationNotStarted:
+				web.Error(ctx, http.StatusConflict, "Verification not started")
+			case cards.ErrCardAlreadyVerified:
+				web.Error(ctx, http.StatusConflict, "Card already verified")
+			default:
+				logger.Error(err.Error())
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+			return
+		}
+
+		web.Response(ctx, http.StatusOK, result)
+	}
+}
+
+func cardParams(ctx *gin.Context) (int, int, bool) {
+	accountID, err := strconv.Atoi(ctx.Param("accountID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid account id")
+		return 0, 0, false
+	}
+
+	cardID, err := strconv.Atoi(ctx.Param("cardID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid card id")
+		return 0, 0, false
+	}
+
+	return accountID, cardID, true
+}
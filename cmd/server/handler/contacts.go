@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/contacts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type ContactsHandler struct {
+	service contacts.Service
+}
+
+func NewContactsHandler(service contacts.Service) *ContactsHandler {
+	return &ContactsHandler{service: service}
+}
+
+// Contacts  godoc
+// @Summary      Save a contact
+// @Description  Save a destination, given by CVU or alias, under a nickname
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        ContactRequest   body  domain.ContactRequest  true  "ContactRequest"
+// @Success      201  {object}  domain.Contact
+// @Failure      400  {string} string  "invalid id, Bad json, Required fields, Invalid nickname, Invalid destination CVU or Cannot save the own account as a contact"
+// @Failure      404  {string} string  "Destination account not found"
+// @Failure      409  {string} string  "Destination is already a contact"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/contacts [post]
+func (c *ContactsHandler) Create() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("accountID"))
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var rq domain.ContactRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		if rq.Destination == "" || rq.Nickname == "" {
+			web.Error(ctx, http.StatusBadRequest, "Required fields: destination, nickname")
+			return
+		}
+
+		contact, err := c.service.Create(ctx, id, rq)
+		if err != nil {
+			switch err {
+			case contacts.ErrInvalidNickname:
+				web.Error(ctx, http.StatusBadRequest, "Invalid nickname")
+			case contacts.ErrSelfContact:
+				web.Error(ctx, http.StatusBadRequest, "Cannot save the own account as a contact")
+			case accounts.ErrInvalidDestination:
+				web.Error(ctx, http.StatusBadRequest, "Invalid destination CVU")
+			case accounts.ErrDestinationNotFound:
+				web.Error(ctx, http.StatusNotFound, "Destination account not found")
+			case contacts.ErrContactAlreadyExists:
+				web.Error(ctx, http.StatusConflict, "Destination is already a contact")
+			default:
+				logger.Error(err.Error())
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusCreated, contact)
+	}
+}
+
+// Contacts  godoc
+// @Summary      List contacts
+// @Description  List the saved contacts of the account sorted by nickname
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Success      200  {object}  []domain.Contact
+// @Failure      400  {string} string  "invalid id"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/contacts [get]
+func (c *ContactsHandler) GetAll(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("accountID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	saved, err := c.service.GetAll(ctx, id)
+	if err != nil {
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, saved)
+}
+
+// Contacts  godoc
+// @Summary      Rename a contact
+// @Description  Change the nickname of a saved contact
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        contactID   path   int   true  "contactID"
+// @Param        RenameContactRequest   body  domain.RenameContactRequest  true  "RenameContactRequest"
+// @Success      200  {object}  domain.Contact
+// @Failure      400  {string} string  "invalid id, invalid contact id, Bad json or Invalid nickname"
+// @Failure      404  {string} string  "Contact not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/contacts/{contactID} [patch]
+func (c *ContactsHandler) Rename() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, contactID, ok := contactParams(ctx)
+		if !ok {
+			return
+		}
+
+		var rq domain.RenameContactRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		contact, err := c.service.Rename(ctx, id, contactID, rq)
+		if err != nil {
+			switch err {
+			case contacts.ErrInvalidNickname:
+				web.Error(ctx, http.StatusBadRequest, "Invalid nickname")
+			case contacts.ErrContactNotFound:
+				web.Error(ctx, http.StatusNotFound, "Contact not found")
+			default:
+				logger.Error(err.Error())
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusOK, contact)
+	}
+}
+
+// Contacts  godoc
+// @Summary      Delete a contact
+// @Description  Delete a saved contact
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        contactID   path   int   true  "contactID"
+// @Success      200  {string} string  "ok"
+// @Failure      400  {string} string  "invalid id, invalid contact id"
+// @Failure      404  {string} string  "Contact not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/contacts/{contactID} [delete]
+func (c *ContactsHandler) Delete(ctx *gin.Context) {
+	id, contactID, ok := contactParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Delete(ctx, id, contactID); err != nil {
+		switch err {
+		case contacts.ErrContactNotFound:
+			web.Error(ctx, http.StatusNotFound, "Contact not found")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, "OK")
+}
+
+// Contacts  godoc
+// @Summary      List recent destinations
+// @Description  List the accounts recently transferred to, ranked by how often and how recently they were used
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Success      200  {object}  []domain.RecentDestination
+// @Failure      400  {string} string  "invalid id"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/recent-destinations [get]
+func (c *ContactsHandler) GetRecentDestinations(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("accountID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	destinations, err := c.service.GetRecentDestinations(ctx, id)
+	if err != nil {
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, destinations)
+}
+
+func contactParams(ctx *gin.Context) (int, int, bool) {
+	id, err := strconv.Atoi(ctx.Param("accountID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return 0, 0, false
+	}
+
+	contactID, err := strconv.Atoi(ctx.Param("contactID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid contact id")
+		return 0, 0, false
+	}
+
+	return id, contactID, true
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/exchange"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type ExchangeHandler struct {
+	service exchange.Service
+}
+
+func NewExchangeHandler(service exchange.Service) *ExchangeHandler {
+	return &ExchangeHandler{service: service}
+}
+
+// Exchange  godoc
+// @Summary      Get account balances
+// @Description  Get the balance of the account in every currency it holds
+// @Tags         exchange
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Success      200  {object}  []domain.Balance
+// @Failure      400  {string} string  "invalid id"
+// @Failure      404  {string} string  "Account not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/balances [get]
+func (e *ExchangeHandler) GetBalances(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	balances, err := e.service.GetBalances(ctx, id)
+	if err != nil {
+		switch err {
+		case accounts.ErrAccountNotFound:
+			web.Error(ctx, http.StatusNotFound, "Account not found")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, balances)
+}
+
+// Exchange  godoc
+// @Summary      Convert between currencies
+// @Description  Convert money between two currency sub-accounts of the account at the current rate
+// @Tags         exchange
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        ConversionRequest   body  domain.ConversionRequest  true  "ConversionRequest"
+// @Success      201  {object}  domain.Conversion
+// @Failure      400  {string} string  "invalid id, Bad json, Required fields, Unsupported currency, Same currency or Invalid amount"
+// @Failure      409  {string} string  "Insufficient funds"
+// @Failure      500  {string} string  "Internal error"
+// @Failure      503  {string} string  "Exchange rate not available"
+// @Router       /accounts/{accountID}/conversions [post]
+func (e *ExchangeHandler) Convert() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idParam := ctx.Param("accountID")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var rq domain.ConversionRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		if rq.From == "" || rq.To == "" {
+			web.Error(ctx, http.StatusBadRequest, "Required fields: from, to, amount")
+			return
+		}
+
+		conversion, err := e.service.Convert(ctx, id, rq)
+		if err != nil {
+			switch err {
+			case currency.ErrUnsupportedCurrency:
+				web.Error(ctx, http.StatusBadRequest, "Unsupported currency")
+			case exchange.ErrSameCurrency:
+				web.Error(ctx, http.StatusBadRequest, "Same currency")
+			case exchange.ErrInvalidAmount:
+				web.Error(ctx, http.StatusBadRequest, "Invalid amount")
+			case exchange.ErrInsufficientFunds:
+				web.Error(ctx, http.StatusConflict, "Insufficient funds")
+			case currency.ErrRateNotFound:
+				web.Error(ctx, http.StatusServiceUnavailable, "Exchange rate not available")
+			default:
+				logger.Error(err.Error())
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusCreated, conversion)
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/fees"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type FeesHandler struct {
+	service fees.Service
+}
+
+func NewFeesHandler(service fees.Service) *FeesHandler {
+	return &FeesHandler{service: service}
+}
+
+// Fees  godoc
+// @Summary      Preview the fee of an operation
+// @Description  Get the fee a transfer or deposit would be charged, before confirming it
+// @Tags         fees
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        operation   query   string   true  "transfer or deposit"
+// @Param        amount   query   string   true  "amount"
+// @Success      200  {object}  domain.FeePreview
+// @Failure      400  {string} string  "invalid id, Required fields, Unknown operation or Invalid amount"
+// @Failure      422  {string} string  "Fee exceeds the amount"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/fees/preview [get]
+func (f *FeesHandler) Preview(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	operation := ctx.Query("operation")
+	amountParam := ctx.Query("amount")
+	if operation == "" || amountParam == "" {
+		web.Error(ctx, http.StatusBadRequest, "Required fields: operation, amount")
+		return
+	}
+
+	amount, err := decimal.NewFromString(amountParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "Invalid amount")
+		return
+	}
+
+	preview, err := f.service.Preview(ctx, id, operation, amount)
+	if err != nil {
+		switch err {
+		case fees.ErrUnknownOperation:
+			web.Error(ctx, http.StatusBadRequest, "Unknown operation")
+		case fees.ErrInvalidAmount:
+			web.Error(ctx, http.StatusBadRequest, "Invalid amount")
+		case fees.ErrFeeExceedsAmount:
+			web.Error(ctx, http.StatusUnprocessableEntity, "Fee exceeds the amount")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, preview)
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/leorodriguez/grupo-04/internal/idempotency"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+type Idempotency struct {
+	repository idempotency.Repository
+	ttl        time.Duration
+}
+
+func NewIdempotency(repository idempotency.Repository, ttl time.Duration) Idempotency {
+	return Idempotency{
+		repository: repository,
+		ttl:        ttl,
+	}
+}
+
+// responseRecorder keeps a copy of the response body so it can be stored.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Handle makes a POST safe to retry. The first request with a given
+// Idempotency-Key runs normally and its response is stored; retries with the
+// same key and body get the stored response back without running the handler
+// again. Requests without the header are not affected.
+func (i *Idempotency) Handle(ctx *gin.Context) {
+	key := ctx.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		ctx.Next()
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		web.Error(ctx, http.StatusBadRequest, "Idempotency-Key too long")
+		ctx.Abort()
+		return
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "Bad request")
+		ctx.Abort()
+		return
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	// keys are scoped to the route and the account in its path
+	scope := ctx.Request.Method + " " + ctx.Request.URL.Path
+	sum := sha256.Sum256(body)
+	requestHash := hex.EncodeToString(sum[:])
+
+	record, err := i.repository.Get(ctx, key, scope)
+	switch err {
+	case nil:
+		i.replay(ctx, record, requestHash)
+		return
+	case idempotency.ErrKeyNotFound:
+	default:
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		ctx.Abort()
+		return
+	}
+
+	err = i.repository.Reserve(ctx, idempotency.Record{
+		Key:         key,
+		Scope:       scope,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().UTC().Add(i.ttl),
+	})
+	if err != nil {
+		switch err {
+		case idempotency.ErrKeyAlreadyExists:
+			web.Error(ctx, http.StatusConflict, "A request with this Idempotency-Key is in progress")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		ctx.Abort()
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+	ctx.Writer = recorder
+
+	// the request context may be canceled once the response is written
+	storeCtx := context.Background()
+	defer func() {
+		// a panic is a server error too, release the key before the recovery
+		// middleware answers 500
+		if r := recover(); r != nil {
+			if err := i.repository.Release(storeCtx, key, scope); err != nil {
+				logger.Error(err.Error())
+			}
+			panic(r)
+		}
+	}()
+
+	ctx.Next()
+
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		// server errors are not final, let the client retry with the same key
+		if err := i.repository.Release(storeCtx, key, scope); err != nil {
+			logger.Error(err.Error())
+		}
+		return
+	}
+
+	if err := i.repository.Complete(storeCtx, key, scope, status, recorder.body.Bytes()); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func (i *Idempotency) replay(ctx *gin.Context, record idempotency.Record, requestHash string) {
+	defer ctx.Abort()
+
+	if record.RequestHash != requestHash {
+		web.Error(ctx, http.StatusUnprocessableEntity, "Idempotency-Key already used with a different request")
+		return
+	}
+
+	if record.StatusCode == 0 {
+		web.Error(ctx, http.StatusConflict, "A request with this Idempotency-Key is in progress")
+		return
+	}
+
+	ctx.Header(IdempotencyReplayedHeader, "true")
+	ctx.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/idempotency"
+)
+
+type idempotencyStore struct {
+	idempotency.Repository
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{records: map[string]idempotency.Record{}}
+}
+
+func (s *idempotencyStore) Get(ctx context.Context, key, scope string) (idempotency.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key+scope]
+	if !ok {
+		return idempotency.Record{}, idempotency.ErrKeyNotFound
+	}
+	return record, nil
+}
+
+func (s *idempotencyStore) Reserve(ctx context.Context, record idempotency.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[record.Key+record.Scope]; ok {
+		return idempotency.ErrKeyAlreadyExists
+	}
+	s.records[record.Key+record.Scope] = record
+	return nil
+}
+
+func (s *idempotencyStore) Complete(ctx context.Context, key, scope string, statusCode int, responseBody []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.records[key+scope]
+	record.StatusCode = statusCode
+	record.ResponseBody = responseBody
+	s.records[key+scope] = record
+	return nil
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, key, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key+scope)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	var tests = []struct {
+		name           string
+		requests       []struct{ key, body string }
+		handlerStatus  int
+		responseStatus []int
+		handlerCalls   int
+	}{
+		{
+			name: "without key every request runs",
+			requests: []struct{ key, body string }{
+				{"", `{"amount": 10}`},
+				{"", `{"amount": 10}`},
+			},
+			handlerStatus:  http.StatusCreated,
+			responseStatus: []int{http.StatusCreated, http.StatusCreated},
+			handlerCalls:   2,
+		},
+		{
+			name: "retry is replayed",
+			requests: []struct{ key, body string }{
+				{"key-1", `{"amount": 10}`},
+				{"key-1", `{"amount": 10}`},
+			},
+			handlerStatus:  http.StatusCreated,
+			responseStatus: []int{http.StatusCreated, http.StatusCreated},
+			handlerCalls:   1,
+		},
+		{
+			name: "same key with another body",
+			requests: []struct{ key, body string }{
+				{"key-1", `{"amount": 10}`},
+				{"key-1", `{"amount": 20}`},
+			},
+			handlerStatus:  http.StatusCreated,
+			responseStatus: []int{http.StatusCreated, http.StatusUnprocessableEntity},
+			handlerCalls:   1,
+		},
+		{
+			name: "client errors are replayed",
+			requests: []struct{ key, body string }{
+				{"key-1", `{"amount": 10}`},
+				{"key-1", `{"amount": 10}`},
+			},
+			handlerStatus:  http.StatusConflict,
+			responseStatus: []int{http.StatusConflict, http.StatusConflict},
+			handlerCalls:   1,
+		},
+		{
+			name: "server errors can be retried",
+			requests: []struct{ key, body string }{
+				{"key-1", `{"amount": 10}`},
+				{"key-1", `{"amount": 10}`},
+			},
+			handlerStatus:  http.StatusInternalServerError,
+			responseStatus: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			handlerCalls:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			middleware := NewIdempotency(newIdempotencyStore(), time.Hour)
+
+			r := gin.New()
+			r.POST("/api/accounts/:accountID/transfers", middleware.Handle, func(ctx *gin.Context) {
+				calls++
+				ctx.JSON(tt.handlerStatus, gin.H{"call": calls})
+			})
+
+			var firstBody string
+			for i, request := range tt.requests {
+				req := httptest.NewRequest(http.MethodPost, "/api/accounts/1/transfers", strings.NewReader(request.body))
+				if request.key != "" {
+					req.Header.Set(IdempotencyKeyHeader, request.key)
+				}
+				rr := httptest.NewRecorder()
+
+				r.ServeHTTP(rr, req)
+
+				assert.Equal(t, tt.responseStatus[i], rr.Code)
+				if i == 0 {
+					firstBody = rr.Body.String()
+				} else if tt.handlerCalls == 1 && rr.Code == tt.responseStatus[0] {
+					assert.Equal(t, firstBody, rr.Body.String())
+					assert.Equal(t, "true", rr.Header().Get(IdempotencyReplayedHeader))
+				}
+			}
+
+			assert.Equal(t, tt.handlerCalls, calls)
+		})
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := newIdempotencyStore()
+	middleware := NewIdempotency(store, time.Hour)
+	assert.NoError(t, store.Reserve(context.Background(), idempotency.Record{
+		Key:         "key-1",
+		Scope:       "POST /api/accounts/1/transfers",
+		RequestHash: "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+	}))
+
+	r := gin.New()
+	r.POST("/api/accounts/:accountID/transfers", middleware.Handle, func(ctx *gin.Context) {
+		t.Fatal("handler must not run while the first request is in progress")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/accounts/1/transfers", strings.NewReader("{}"))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	rr := httptest.NewRecorder()
+
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestIdempotencyPanic(t *testing.T) {
+	store := newIdempotencyStore()
+	middleware := NewIdempotency(store, time.Hour)
+
+	calls := 0
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.POST("/api/accounts/:accountID/transfers", middleware.Handle, func(ctx *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		ctx.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	for _, status := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		req := httptest.NewRequest(http.MethodPost, "/api/accounts/1/transfers", strings.NewReader("{}"))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		rr := httptest.NewRecorder()
+
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, status, rr.Code)
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/limits"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type LimitsHandler struct {
+	service limits.Service
+}
+
+func NewLimitsHandler(service limits.Service) *LimitsHandler {
+	return &LimitsHandler{service: service}
+}
+
+// Limits  godoc
+// @Summary      Get account limits
+// @Description  Get the outgoing transfer limits of the account and how much of them has been used
+// @Tags         limits
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Success      200  {object}  domain.AccountLimits
+// @Failure      400  {string} string  "invalid id"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/limits [get]
+func (l *LimitsHandler) GetLimits(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	accountLimits, err := l.service.GetLimits(ctx, id)
+	if err != nil {
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, accountLimits)
+}
+
+// Limits  godoc
+// @Summary      Update account limits
+// @Description  Set the tier of the account and override its limits. Empty limits take the tier default. Requires the admin role.
+// @Tags         limits
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        LimitsRequest   body  domain.LimitsRequest  true  "LimitsRequest"
+// @Success      200  {object}  domain.AccountLimits
+// @Failure      400  {string} string  "invalid id, Bad json, Required fields, Unknown tier or Invalid limit"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/limits [put]
+func (l *LimitsHandler) Update() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idParam := ctx.Param("accountID")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var rq domain.LimitsRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		if rq.Tier == "" {
+			web.Error(ctx, http.StatusBadRequest, "Required fields: tier")
+			return
+		}
+
+		accountLimits, err := l.service.Update(ctx, id, rq)
+		if err != nil {
+			logger.Error(err.Error())
+			switch err {
+			case limits.ErrUnknownTier:
+				web.Error(ctx, http.StatusBadRequest, "Unknown tier")
+			case limits.ErrInvalidLimit:
+				web.Error(ctx, http.StatusBadRequest, "Invalid limit")
+			default:
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusOK, accountLimits)
+	}
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
 	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/ratelimit"
 	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+	"math"
 	"net/http"
 	"strconv"
 )
@@ -44,6 +47,8 @@ func (m *Middlewares) IsAuthorized(ctx *gin.Context) {
 		switch err {
 		case accounts.ErrTokenExpired:
 			web.Error(ctx, http.StatusUnauthorized, "Session expired. Please login again")
+		case accounts.ErrInvalidToken:
+			web.Error(ctx, http.StatusUnauthorized, "Invalid token")
 		default:
 			web.Error(ctx, http.StatusInternalServerError, "Internal error")
 		}
@@ -61,3 +66,99 @@ func (m *Middlewares) IsAuthorized(ctx *gin.Context) {
 
 	ctx.Next()
 }
+
+// IsAuthenticated lets the request through if it carries the token of any
+// logged in user, for routes that are not about one account.
+func (m *Middlewares) IsAuthenticated(ctx *gin.Context) {
+	token := ctx.GetHeader("Authorization")
+	if token == "" {
+		web.Error(ctx, http.StatusBadRequest, "Token not sent")
+		ctx.Abort()
+		return
+	}
+
+	isAuthenticated, err := m.accountsService.IsAuthenticated(ctx, token)
+	if err != nil {
+		switch err {
+		case accounts.ErrTokenExpired:
+			web.Error(ctx, http.StatusUnauthorized, "Session expired. Please login again")
+		case accounts.ErrInvalidToken:
+			web.Error(ctx, http.StatusUnauthorized, "Invalid token")
+		default:
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		logger.Error(err.Error())
+		ctx.Abort()
+		return
+	}
+
+	if !isAuthenticated {
+		web.Error(ctx, http.StatusUnauthorized, "Invalid token")
+		ctx.Abort()
+		return
+	}
+
+	ctx.Next()
+}
+
+// HasRole lets the request through only if the token grants the realm role.
+func (m *Middlewares) HasRole(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.GetHeader("Authorization")
+		if token == "" {
+			web.Error(ctx, http.StatusBadRequest, "Token not sent")
+			ctx.Abort()
+			return
+		}
+
+		hasRole, err := m.accountsService.HasRole(ctx, token, role)
+		if err != nil {
+			switch err {
+			case accounts.ErrTokenExpired:
+				web.Error(ctx, http.StatusUnauthorized, "Session expired. Please login again")
+			case accounts.ErrInvalidToken:
+				web.Error(ctx, http.StatusUnauthorized, "Invalid token")
+			default:
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			logger.Error(err.Error())
+			ctx.Abort()
+			return
+		}
+
+		if !hasRole {
+			web.Error(ctx, http.StatusForbidden, "Not authorized")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RateLimit counts every request against both the client IP and the user its
+// token was issued to, so neither logging in again nor rotating IPs gets more
+// calls through.
+func (m *Middlewares) RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		keys := []string{"ip:" + ctx.ClientIP()}
+		if token := ctx.GetHeader("Authorization"); token != "" {
+			if authID, err := m.accountsService.GetAuthID(ctx, token); err == nil {
+				keys = append(keys, "user:"+authID)
+			}
+		}
+
+		for _, key := range keys {
+			if allowed, retryAfter := limiter.Allow(key); !allowed {
+				ctx.Header("Retry-After", fmt.Sprint(math.Ceil(retryAfter.Seconds())))
+				web.Error(ctx, http.StatusTooManyRequests, "Too many requests")
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/pkg/ratelimit"
+)
+
+func (a *accountsMock) GetAuthID(ctx context.Context, token string) (string, error) {
+	args := a.Called(token)
+	return args.String(0), args.Error(1)
+}
+
+func TestRateLimit(t *testing.T) {
+	accountsService := new(accountsMock)
+	accountsService.On("GetAuthID", "Bearer first").Return("user-1", nil)
+	accountsService.On("GetAuthID", "Bearer second").Return("user-1", nil)
+	accountsService.On("GetAuthID", "Bearer other").Return("user-2", nil)
+	accountsService.On("GetAuthID", "Bearer expired").Return("", accounts.ErrTokenExpired)
+	middlewares := NewMiddlewares(accountsService)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/lookup", middlewares.RateLimit(ratelimit.New(1, time.Minute)), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	var tests = []struct {
+		name           string
+		remoteAddr     string
+		token          string
+		responseStatus int
+	}{
+		{name: "first call", remoteAddr: "10.0.0.1:1000", token: "Bearer first", responseStatus: http.StatusOK},
+		{name: "same user with a new token and IP", remoteAddr: "10.0.0.2:1000", token: "Bearer second", responseStatus: http.StatusTooManyRequests},
+		{name: "other user from a new IP", remoteAddr: "10.0.0.3:1000", token: "Bearer other", responseStatus: http.StatusOK},
+		{name: "same IP without token", remoteAddr: "10.0.0.3:2000", responseStatus: http.StatusTooManyRequests},
+		{name: "unusable token from a new IP", remoteAddr: "10.0.0.4:1000", token: "Bearer expired", responseStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.responseStatus, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/leorodriguez/grupo-04/internal/receipts"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type ReceiptsHandler struct {
+	service receipts.Service
+}
+
+func NewReceiptsHandler(service receipts.Service) *ReceiptsHandler {
+	return &ReceiptsHandler{service: service}
+}
+
+// Receipts godoc
+// @Summary      Download transaction receipt
+// @Description  Download the PDF receipt of one transaction of the account
+// @Tags         transactions
+// @Produce      application/pdf
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        transactionID   path   int   true  "transactionID"
+// @Success      200  {file}  file
+// @Failure      400  {string} string  "invalid id, invalid transaction id"
+// @Failure      404  {string} string  "Transaction not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/activity/{transactionID}/receipt [get]
+func (r *ReceiptsHandler) GetReceipt(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	transactionIDParam := ctx.Param("transactionID")
+	transactionID, err := strconv.Atoi(transactionIDParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	receipt, err := r.service.GetReceipt(ctx, id, transactionID)
+	if err != nil {
+		switch err {
+		case transactions.ErrTransactionNotFound:
+			web.Error(ctx, http.StatusNotFound, "Transaction not found")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	pdf, err := receipts.RenderPDF(receipt)
+	if err != nil {
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=receipt-%d.pdf", receipt.TransactionID))
+	ctx.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/scheduled"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type ScheduledTransfersHandler struct {
+	service scheduled.Service
+}
+
+func NewScheduledTransfersHandler(service scheduled.Service) *ScheduledTransfersHandler {
+	return &ScheduledTransfersHandler{service: service}
+}
+
+// ScheduledTransfers  godoc
+// @Summary      Schedule a transfer
+// @Description  Schedule a one-off or recurring (daily, weekly, monthly) transfer to another account
+// @Tags         scheduled-transfers
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        ScheduledTransferRequest   body  domain.ScheduledTransferRequest  true  "ScheduledTransferRequest"
+// @Success      201  {object}  domain.ScheduledTransfer
+// @Failure      400  {string} string  "invalid id, Bad json, Required fields, Invalid amount, Invalid description, Invalid destination CVU, Invalid frequency, Invalid day of month or Start date is in the past"
+// @Failure      404  {string} string  "Destination account not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/scheduled-transfers [post]
+func (s *ScheduledTransfersHandler) Create() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idParam := ctx.Param("accountID")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var rq domain.ScheduledTransferRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		if rq.Destination == "" || rq.Amount.IsZero() || rq.Frequency == "" {
+			web.Error(ctx, http.StatusBadRequest, "Required fields: destination, amount, frequency")
+			return
+		}
+
+		st, err := s.service.Create(ctx, id, rq)
+		if err != nil {
+			logger.Error(err.Error())
+			switch err {
+			case transactions.ErrInvalidAmount:
+				web.Error(ctx, http.StatusBadRequest, "Invalid amount")
+			case transactions.ErrDescriptionTooLong:
+				invalidDescription(ctx)
+			case transactions.ErrSelfTransfer:
+				web.Error(ctx, http.StatusBadRequest, "Cannot transfer to the same account")
+			case scheduled.ErrInvalidFrequency:
+				web.Error(ctx, http.StatusBadRequest, "Invalid frequency")
+			case scheduled.ErrInvalidDayOfMonth:
+				web.Error(ctx, http.StatusBadRequest, "Invalid day of month")
+			case scheduled.ErrStartInPast:
+				web.Error(ctx, http.StatusBadRequest, "Start date is in the past")
+			case transactions.ErrDestinationNotFound:
+				web.Error(ctx, http.StatusNotFound, "Destination account not found")
+			case transactions.ErrInvalidDestination:
+				web.Error(ctx, http.StatusBadRequest, "Invalid destination CVU")
+			case accounts.ErrAccountNotFound:
+				web.Error(ctx, http.StatusNotFound, "Account not found")
+			default:
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusCreated, st)
+	}
+}
+
+// ScheduledTransfers  godoc
+// @Summary      List scheduled transfers
+// @Description  List the scheduled transfers of the account
+// @Tags         scheduled-transfers
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Success      200  {object}  []domain.ScheduledTransfer
+// @Failure      400  {string} string  "invalid id"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/scheduled-transfers [get]
+func (s *ScheduledTransfersHandler) GetAll(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	scheduledTransfers, err := s.service.GetAll(ctx, id)
+	if err != nil {
+		logger.Error(err.Error())
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, scheduledTransfers)
+}
+
+// ScheduledTransfers  godoc
+// @Summary      List scheduled transfer executions
+// @Description  List the runs of a scheduled transfer, including the failed ones
+// @Tags         scheduled-transfers
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        scheduledTransferID   path   int   true  "scheduledTransferID"
+// @Success      200  {object}  []domain.ScheduledTransferExecution
+// @Failure      400  {string} string  "invalid id, invalid scheduled transfer id"
+// @Failure      404  {string} string  "Scheduled transfer not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/scheduled-transfers/{scheduledTransferID}/executions [get]
+func (s *ScheduledTransfersHandler) GetExecutions(ctx *gin.Context) {
+	id, scheduledTransferID, ok := scheduledTransferParams(ctx)
+	if !ok {
+		return
+	}
+
+	executions, err := s.service.GetExecutions(ctx, id, scheduledTransferID)
+	if err != nil {
+		switch err {
+		case scheduled.ErrScheduledTransferNotFound:
+			web.Error(ctx, http.StatusNotFound, "Scheduled transfer not found")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, executions)
+}
+
+// ScheduledTransfers  godoc
+// @Summary      Pause a scheduled transfer
+// @Description  Pause an active scheduled transfer
+// @Tags         scheduled-transfers
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        scheduledTransferID   path   int   true  "scheduledTransferID"
+// @Success      200  {object}  domain.ScheduledTransfer
+// @Failure      400  {string} string  "invalid id, invalid scheduled transfer id"
+// @Failure      404  {string} string  "Scheduled transfer not found"
+// @Failure      409  {string} string  "Invalid status transition"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/scheduled-transfers/{scheduledTransferID}/pause [post]
+func (s *ScheduledTransfersHandler) Pause(ctx *gin.Context) {
+	s.changeStatus(ctx, s.service.Pause)
+}
+
+// ScheduledTransfers  godoc
+// @Summary      Resume a scheduled transfer
+// @Description  Resume a paused scheduled transfer, skipping the runs missed while paused
+// @Tags         scheduled-transfers
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        scheduledTransferID   path   int   true  "scheduledTransferID"
+// @Success      200  {object}  domain.ScheduledTransfer
+// @Failure      400  {string} string  "invalid id, invalid scheduled transfer id"
+// @Failure      404  {string} string  "Scheduled transfer not found"
+// @Failure      409  {string} string  "Invalid status transition"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/scheduled-transfers/{scheduledTransferID}/resume [post]
+func (s *ScheduledTransfersHandler) Resume(ctx *gin.Context) {
+	s.changeStatus(ctx, s.service.Resume)
+}
+
+// ScheduledTransfers  godoc
+// @Summary      Cancel a scheduled transfer
+// @Description  Cancel an active or paused scheduled transfer
+// @Tags         scheduled-transfers
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        scheduledTransferID   path   int   true  "scheduledTransferID"
+// @Success      200  {object}  domain.ScheduledTransfer
+// @Failure      400  {string} string  "invalid id, invalid scheduled transfer id"
+// @Failure      404  {string} string  "Scheduled transfer not found"
+// @Failure      409  {string} string  "Invalid status transition"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/scheduled-transfers/{scheduledTransferID} [delete]
+func (s *ScheduledTransfersHandler) Cancel(ctx *gin.Context) {
+	s.changeStatus(ctx, s.service.Cancel)
+}
+
+func (s *ScheduledTransfersHandler) changeStatus(ctx *gin.Context,
+	change func(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error)) {
+	id, scheduledTransferID, ok := scheduledTransferParams(ctx)
+	if !ok {
+		return
+	}
+
+	st, err := change(ctx, id, scheduledTransferID)
+	if err != nil {
+		switch err {
+		case scheduled.ErrScheduledTransferNotFound:
+			web.Error(ctx, http.StatusNotFound, "Scheduled transfer not found")
+		case scheduled.ErrInvalidStatusTransition:
+			web.Error(ctx, http.StatusConflict, "Invalid status transition")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, st)
+}
+
+func scheduledTransferParams(ctx *gin.Context) (int, int, bool) {
+	id, err := strconv.Atoi(ctx.Param("accountID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return 0, 0, false
+	}
+
+	scheduledTransferID, err := strconv.Atoi(ctx.Param("scheduledTransferID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid scheduled transfer id")
+		return 0, 0, false
+	}
+
+	return id, scheduledTransferID, true
+}
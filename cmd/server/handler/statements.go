@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/statements"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type StatementsHandler struct {
+	service statements.Service
+}
+
+func NewStatementsHandler(service statements.Service) *StatementsHandler {
+	return &StatementsHandler{service: service}
+}
+
+// Statements godoc
+// @Summary      Download account statement
+// @Description  Download the account statement of a month or a date range as CSV, OFX or PDF
+// @Tags         transactions
+// @Produce      text/csv
+// @Produce      application/x-ofx
+// @Produce      application/pdf
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        format   query   string   false  "csv (default), ofx or pdf"
+// @Param        month   query   string   false  "month as 2006-01, defaults to the current month"
+// @Param        from   query   string   false  "first day as 2006-01-02, used with to instead of month"
+// @Param        to   query   string   false  "last day as 2006-01-02, used with from instead of month"
+// @Success      200  {file}  file
+// @Failure      400  {string} string  "invalid id, Invalid period or Unsupported format"
+// @Failure      404  {string} string  "Account not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/statements [get]
+func (s *StatementsHandler) GetStatement(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	format := ctx.DefaultQuery("format", statements.FormatCSV)
+
+	from, to, err := parsePeriod(ctx)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "Invalid period")
+		return
+	}
+
+	statement, err := s.service.GetStatement(ctx, id, from, to)
+	if err != nil {
+		switch err {
+		case statements.ErrInvalidPeriod:
+			web.Error(ctx, http.StatusBadRequest, "Invalid period")
+		case accounts.ErrAccountNotFound:
+			web.Error(ctx, http.StatusNotFound, "Account not found")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	content, contentType, err := statements.Render(statement, format)
+	if err != nil {
+		switch err {
+		case statements.ErrUnsupportedFormat:
+			web.Error(ctx, http.StatusBadRequest, "Unsupported format")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s.%s", statement.CVU, from.Format("20060102"), format)
+	ctx.Header("Content-Disposition", "attachment; filename="+filename)
+	ctx.Data(http.StatusOK, contentType, content)
+}
+
+// parsePeriod returns the statement period as [from, to). A month takes
+// precedence over a date range, and without both the current month is used.
+func parsePeriod(ctx *gin.Context) (time.Time, time.Time, error) {
+	if month := ctx.Query("month"); month != "" {
+		from, err := time.Parse("2006-01", month)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return from, from.AddDate(0, 1, 0), nil
+	}
+
+	fromParam, toParam := ctx.Query("from"), ctx.Query("to")
+	if fromParam == "" && toParam == "" {
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, 0), nil
+	}
+
+	from, err := time.Parse("2006-01-02", fromParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := time.Parse("2006-01-02", toParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
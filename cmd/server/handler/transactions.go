@@ -0,0 +1,479 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/cards"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/fees"
+	"gitlab.com/leorodriguez/grupo-04/internal/limits"
+	"gitlab.com/leorodriguez/grupo-04/internal/processor"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"gitlab.com/leorodriguez/grupo-04/pkg/web"
+)
+
+type TransactionsHandler struct {
+	service transactions.Service
+}
+
+func NewTransactionsHandler(service transactions.Service) *TransactionsHandler {
+	return &TransactionsHandler{service: service}
+}
+
+// Transactions  godoc
+// @Summary      Get last five transactions info
+// @Description  Get last five transactions info
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Success      200  {object}  []domain.TransactionInfo
+// @Failure      400  {string} string  "invalid id"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/transactions [get]
+func (t *TransactionsHandler) GetTransactionsLastFive(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	trxs, err := t.service.GetTransactionsLastFive(ctx, id)
+	if err != nil {
+
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		return
+	}
+
+	if trxs == nil {
+		web.Response(ctx, http.StatusOK, []domain.TransactionInfo{})
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, trxs)
+}
+
+// Transactions  godoc
+// @Summary      Get transaction detail
+// @Description  Get one transaction of the account
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        transactionID   path   int   true  "transactionID"
+// @Success      200  {object}  domain.TransactionInfo
+// @Failure      400  {string} string  "invalid id, invalid transaction id"
+// @Failure      404  {string} string  "Transaction not found"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/activity/{transactionID} [get]
+func (t *TransactionsHandler) GetByID(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	transactionIDParam := ctx.Param("transactionID")
+	transactionID, err := strconv.Atoi(transactionIDParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	trx, err := t.service.GetByID(ctx, id, transactionID)
+	if err != nil {
+		switch err {
+		case transactions.ErrTransactionNotFound:
+			web.Error(ctx, http.StatusNotFound, "Transaction not found")
+		default:
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, trx)
+}
+
+// Transactions  godoc
+// @Summary      Transfer money to another account
+// @Description  Transfer money to another account by CVU or alias
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        TransferRequest   body  domain.TransferRequest  true  "TransferRequest"
+// @Success      201  {object}  domain.TransactionInfo
+// @Failure      400  {string} string  "invalid id, Bad json, Required fields, Invalid amount, Invalid description, Invalid destination CVU or Cannot transfer to the same account"
+// @Failure      404  {string} string  "Destination account not found"
+// @Failure      409  {string} string  "Insufficient funds or Limit exceeded"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/transfers [post]
+func (t *TransactionsHandler) Transfer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idParam := ctx.Param("accountID")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var rq domain.TransferRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		if rq.Destination == "" || rq.Amount.IsZero() {
+			web.Error(ctx, http.StatusBadRequest, "Required fields: destination, amount")
+			return
+		}
+
+		trx, err := t.service.Transfer(ctx, id, rq)
+		if err != nil {
+			logger.Error(err.Error())
+
+			var limitErr *limits.ErrLimitExceeded
+			if errors.As(err, &limitErr) {
+				web.Error(ctx, http.StatusConflict, "Limit exceeded (%s), remaining allowance %s", limitErr.Limit, limitErr.Remaining.String())
+				return
+			}
+
+			switch err {
+			case transactions.ErrInvalidAmount:
+				web.Error(ctx, http.StatusBadRequest, "Invalid amount")
+			case transactions.ErrDescriptionTooLong:
+				invalidDescription(ctx)
+			case transactions.ErrSelfTransfer:
+				web.Error(ctx, http.StatusBadRequest, "Cannot transfer to the same account")
+			case transactions.ErrDestinationNotFound:
+				web.Error(ctx, http.StatusNotFound, "Destination account not found")
+			case transactions.ErrInvalidDestination:
+				web.Error(ctx, http.StatusBadRequest, "Invalid destination CVU")
+			case accounts.ErrAccountNotFound:
+				web.Error(ctx, http.StatusNotFound, "Account not found")
+			case transactions.ErrInsufficientFunds:
+				web.Error(ctx, http.StatusConflict, "Insufficient funds")
+			default:
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusCreated, trx)
+	}
+}
+
+// Transactions  godoc
+// @Summary      Deposit money from a card
+// @Description  Deposit money into the account charging one of its cards
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        DepositRequest   body  domain.DepositRequest  true  "DepositRequest"
+// @Success      201  {object}  domain.TransactionInfo
+// @Failure      400  {string} string  "invalid id, Bad json, Required fields, Invalid amount or No default card"
+// @Failure      402  {string} string  "Payment declined"
+// @Failure      404  {string} string  "Card not found"
+// @Failure      409  {string} string  "Card is not verified"
+// @Failure      422  {string} string  "Fee exceeds the amount"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/deposits [post]
+func (t *TransactionsHandler) Deposit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idParam := ctx.Param("accountID")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var rq domain.DepositRequest
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+
+			return
+		}
+
+		if rq.Amount.IsZero() {
+			web.Error(ctx, http.StatusBadRequest, "Required fields: amount")
+			return
+		}
+
+		trx, err := t.service.Deposit(ctx, id, rq)
+		if err != nil {
+			logger.Error(err.Error())
+			switch err {
+			case transactions.ErrInvalidAmount:
+				web.Error(ctx, http.StatusBadRequest, "Invalid amount")
+			case cards.ErrCardNotFound:
+				web.Error(ctx, http.StatusNotFound, "Card not found")
+			case cards.ErrCardNotVerified:
+				web.Error(ctx, http.StatusConflict, "Card is not verified")
+			case cards.ErrNoDefaultCard:
+				web.Error(ctx, http.StatusBadRequest, "No card given and the account has no default card")
+			case accounts.ErrAccountNotFound:
+				web.Error(ctx, http.StatusNotFound, "Account not found")
+			case processor.ErrPaymentDeclined:
+				web.Error(ctx, http.StatusPaymentRequired, "Payment declined")
+			case fees.ErrFeeExceedsAmount:
+				web.Error(ctx, http.StatusUnprocessableEntity, "Fee exceeds the amount")
+			default:
+				web.Error(ctx, http.StatusInternalServerError, "Internal error")
+			}
+
+			return
+		}
+
+		web.Response(ctx, http.StatusCreated, trx)
+	}
+}
+
+// Transactions  godoc
+// @Summary      Get account activity
+// @Description  Get account transactions newest first, paginated with a cursor
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        from   query   string   false  "from date (2006-01-02 or RFC3339)"
+// @Param        to   query   string   false  "to date (2006-01-02 or RFC3339)"
+// @Param        type   query   string   false  "comma separated types: deposit, transfer_in, transfer_out, adjustment, reversal, fee, conversion"
+// @Param        min_amount   query   number   false  "min amount"
+// @Param        max_amount   query   number   false  "max amount"
+// @Param        q   query   string   false  "text to match in the description"
+// @Param        cursor   query   string   false  "next_cursor of the previous page"
+// @Param        limit   query   int   false  "page size, up to 100"
+// @Success      200  {object}  domain.ActivityPage
+// @Failure      400  {string} string  "invalid id, Invalid filters or Invalid cursor"
+// @Failure      500  {string} string  "Internal error"
+// @Router       /accounts/{accountID}/activity [get]
+func (t *TransactionsHandler) GetActivity(ctx *gin.Context) {
+	idParam := ctx.Param("accountID")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	filters, err := parseActivityFilters(ctx)
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "Invalid filters: %s", err.Error())
+		return
+	}
+
+	page, err := t.service.GetActivity(ctx, id, filters)
+	if err != nil {
+		logger.Error(err.Error())
+		switch err {
+		case transactions.ErrInvalidCursor:
+			web.Error(ctx, http.StatusBadRequest, "Invalid cursor")
+		case transactions.ErrInvalidFilter:
+			web.Error(ctx, http.StatusBadRequest, "Invalid filters")
+		default:
+			web.Error(ctx, http.StatusInternalServerError, "Internal error")
+		}
+
+		return
+	}
+
+	web.Response(ctx, http.StatusOK, page)
+}
+
+func parseActivityFilters(ctx *gin.Context) (domain.ActivityFilters, error) {
+	var filters domain.ActivityFilters
+	var err error
+
+	if from := ctx.Query("from"); from != "" {
+		if filters.From, err = parseDate(from, false); err != nil {
+			return domain.ActivityFilters{}, errors.New("from")
+		}
+	}
+	if to := ctx.Query("to"); to != "" {
+		if filters.To, err = parseDate(to, true); err != nil {
+			return domain.ActivityFilters{}, errors.New("to")
+		}
+	}
+	if types := ctx.Query("type"); types != "" {
+		filters.Types = strings.Split(types, ",")
+	}
+	if minAmount := ctx.Query("min_amount"); minAmount != "" {
+		if filters.MinAmount.Decimal, err = decimal.NewFromString(minAmount); err != nil {
+			return domain.ActivityFilters{}, errors.New("min_amount")
+		}
+		filters.MinAmount.Valid = true
+	}
+	if maxAmount := ctx.Query("max_amount"); maxAmount != "" {
+		if filters.MaxAmount.Decimal, err = decimal.NewFromString(maxAmount); err != nil {
+			return domain.ActivityFilters{}, errors.New("max_amount")
+		}
+		filters.MaxAmount.Valid = true
+	}
+	if limit := ctx.Query("limit"); limit != "" {
+		if filters.Limit, err = strconv.Atoi(limit); err != nil || filters.Limit < 1 {
+			return domain.ActivityFilters{}, errors.New("limit")
+		}
+	}
+	filters.Description = ctx.Query("q")
+	filters.Cursor = ctx.Query("cursor")
+
+	return filters, nil
+}
+
+// parseDate accepts a plain date or a RFC3339 timestamp. A plain date used as
+// the end of a range covers the whole day.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			date = date.Add(24*time.Hour - time.Nanosecond)
+		}
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// Transactions  godoc
+// @Summary      Reverse a received transfer or a card deposit
+// @Description  Give back a transfer received by the account, or a card deposit made by it. The whole amount is returned to the origin account or refunded to the card.
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        accountID   path   int   true  "accountID"
+// @Param        transactionID   path   int   true  "transactionID"
+// @Param        ReversalRequest   body  domain.ReversalRequest  false  "ReversalRequest"
+// @Success      201  {object}  domain.TransactionInfo
+// @Failure      400  {string} string  "invalid id, invalid transaction id, Bad json, Invalid amount or Partial reversals are not allowed"
+// @Failure      404  {string} string  "Transaction not found"
+// @Failure      409  {string} string  "Transaction cannot be reversed, Transaction already reversed or Insufficient funds"
+// @Failure      500  {string} string  "Internal error"
+// @Failure      502  {string} string  "Card refund failed"
+// @Router       /accounts/{accountID}/activity/{transactionID}/reversal [post]
+func (t *TransactionsHandler) Reverse() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idParam := ctx.Param("accountID")
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			web.Error(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		transactionID, rq, ok := parseReversal(ctx)
+		if !ok {
+			return
+		}
+
+		trx, err := t.service.Reverse(ctx, id, transactionID, rq)
+		if err != nil {
+			reversalError(ctx, err)
+			return
+		}
+
+		web.Response(ctx, http.StatusCreated, trx)
+	}
+}
+
+// Transactions  godoc
+// @Summary      Reverse a transfer or a card deposit as operator
+// @Description  Reverse any transfer, given either of its transactions, or card deposit. Requires the operator role.
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header   string  true  "Authorization"
+// @Param        transactionID   path   int   true  "transactionID"
+// @Param        ReversalRequest   body  domain.ReversalRequest  false  "ReversalRequest"
+// @Success      201  {object}  domain.TransactionInfo
+// @Failure      400  {string} string  "invalid transaction id, Bad json, Invalid amount or Partial reversals are not allowed"
+// @Failure      404  {string} string  "Transaction not found"
+// @Failure      409  {string} string  "Transaction cannot be reversed, Transaction already reversed or Insufficient funds"
+// @Failure      500  {string} string  "Internal error"
+// @Failure      502  {string} string  "Card refund failed"
+// @Router       /operations/transactions/{transactionID}/reversal [post]
+func (t *TransactionsHandler) ReverseAsOperator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		transactionID, rq, ok := parseReversal(ctx)
+		if !ok {
+			return
+		}
+
+		trx, err := t.service.ReverseAsOperator(ctx, transactionID, rq)
+		if err != nil {
+			reversalError(ctx, err)
+			return
+		}
+
+		web.Response(ctx, http.StatusCreated, trx)
+	}
+}
+
+// parseReversal reads the transaction id and the optional body of a reversal.
+func parseReversal(ctx *gin.Context) (int, domain.ReversalRequest, bool) {
+	var rq domain.ReversalRequest
+
+	transactionID, err := strconv.Atoi(ctx.Param("transactionID"))
+	if err != nil {
+		web.Error(ctx, http.StatusBadRequest, "invalid transaction id")
+		return 0, rq, false
+	}
+
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&rq); err != nil {
+			logger.Error(err.Error())
+			web.Error(ctx, http.StatusBadRequest, "Bad json")
+			return 0, rq, false
+		}
+	}
+
+	return transactionID, rq, true
+}
+
+func reversalError(ctx *gin.Context, err error) {
+	logger.Error(err.Error())
+	switch err {
+	case transactions.ErrInvalidAmount:
+		web.Error(ctx, http.StatusBadRequest, "Invalid amount")
+	case transactions.ErrPartialReversal:
+		web.Error(ctx, http.StatusBadRequest, "Partial reversals are not allowed")
+	case transactions.ErrTransactionNotFound:
+		web.Error(ctx, http.StatusNotFound, "Transaction not found")
+	case transactions.ErrNotReversible:
+		web.Error(ctx, http.StatusConflict, "Transaction cannot be reversed")
+	case transactions.ErrAlreadyReversed:
+		web.Error(ctx, http.StatusConflict, "Transaction already reversed")
+	case transactions.ErrInsufficientFunds:
+		web.Error(ctx, http.StatusConflict, "Insufficient funds")
+	case transactions.ErrRefundFailed:
+		web.Error(ctx, http.StatusBadGateway, "Card refund failed, the reversal is recorded and the refund can be retried")
+	default:
+		web.Error(ctx, http.StatusInternalServerError, "Internal error")
+	}
+}
+
+// invalidDescription answers a description that does not fit in the
+// transactions table with the invalid field.
+func invalidDescription(ctx *gin.Context) {
+	details := []gin.H{{"field": "description", "message": transactions.ErrDescriptionTooLong.Error()}}
+	web.ErrorWithDetails(ctx, http.StatusBadRequest, details, "Invalid description")
+}
@@ -12,6 +12,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 	"gitlab.com/leorodriguez/grupo-04/cmd/server/routes"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
 )
 
 // @title           Grupo 4 Swagger
@@ -40,7 +41,10 @@ func main() {
 		panic(err)
 
 	}
-	aliasWords := strings.Split(string(aliasWordsRaw), "\n")
+	aliasWords, err := accounts.AliasWords(string(aliasWordsRaw))
+	if err != nil {
+		panic(err)
+	}
 
 	dbHost := os.Getenv("DB_HOST")
 	dbName := os.Getenv("DB_NAME")
@@ -55,6 +59,15 @@ func main() {
 	}
 
 	r := gin.Default()
+	// only the proxies in TRUSTED_PROXIES, comma separated, may give the client
+	// IP in X-Forwarded-For; otherwise it is the address of the connection
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err = r.SetTrustedProxies(trustedProxies); err != nil {
+		panic(err)
+	}
 
 	router := routes.NewRouter(r, db, aliasWords)
 	router.MapRoutes()
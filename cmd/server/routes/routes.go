@@ -1,7 +1,9 @@
 package routes
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"github.com/Nerzal/gocloak/v12"
 	"github.com/gin-gonic/gin"
 	"gitlab.com/leorodriguez/grupo-04/cmd/server/handler"
@@ -9,14 +11,39 @@ import (
 	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
 	"gitlab.com/leorodriguez/grupo-04/internal/auth"
 	"gitlab.com/leorodriguez/grupo-04/internal/cards"
+	"gitlab.com/leorodriguez/grupo-04/internal/contacts"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+	"gitlab.com/leorodriguez/grupo-04/internal/cvu"
+	"gitlab.com/leorodriguez/grupo-04/internal/exchange"
+	"gitlab.com/leorodriguez/grupo-04/internal/fees"
+	"gitlab.com/leorodriguez/grupo-04/internal/idempotency"
+	"gitlab.com/leorodriguez/grupo-04/internal/limits"
+	"gitlab.com/leorodriguez/grupo-04/internal/processor"
+	"gitlab.com/leorodriguez/grupo-04/internal/receipts"
+	"gitlab.com/leorodriguez/grupo-04/internal/scheduled"
+	"gitlab.com/leorodriguez/grupo-04/internal/statements"
 	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
 	"gitlab.com/leorodriguez/grupo-04/internal/users"
+	"gitlab.com/leorodriguez/grupo-04/internal/vault"
+	"gitlab.com/leorodriguez/grupo-04/pkg/jwks"
+	"gitlab.com/leorodriguez/grupo-04/pkg/ratelimit"
 	"os"
+	"strings"
+	"time"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+const (
+	defaultIdempotencyTTL  = 24 * time.Hour
+	idempotencyCleanupTick = time.Hour
+	scheduledTransfersTick = time.Minute
+	defaultTokenAudience   = "account"
+	lookupRateLimit        = 10
+	lookupRateWindow       = time.Minute
+)
+
 type Router interface {
 	MapRoutes()
 }
@@ -34,49 +61,252 @@ func NewRouter(r *gin.Engine, db *sql.DB, aliasWords []string) Router {
 }
 
 func (r *router) MapRoutes() {
-	keycloakSettings := auth.KeycloakSettings{
-		GoCloak:      gocloak.NewClient(os.Getenv("KEYCLOAK_URL")),
-		ClientId:     os.Getenv("KEYCLOAK_CLIENT_ID"),
-		ClientSecret: os.Getenv("KEYCLOAK_CLIENT_SECRET"),
-		Realm:        os.Getenv("KEYCLOAK_REALM"),
-	}
-
-	keycloakService := auth.NewAuth(keycloakSettings)
+	identityService := identityProvider()
 	authRepository := users.NewRepository(r.db)
 	accountsRepository := accounts.NewRepository(r.db)
 	transactionsRepository := transactions.NewRepository(r.db)
 	cardsRepository := cards.NewRepository(r.db)
+	idempotencyRepository := idempotency.NewRepository(r.db)
+	scheduledRepository := scheduled.NewRepository(r.db)
+	limitsRepository := limits.NewRepository(r.db)
+	exchangeRepository := exchange.NewRepository(r.db)
+	contactsRepository := contacts.NewRepository(r.db)
+	cardVault := vault.New(vault.NewRepository(r.db), vaultCipher())
+	// there is no real acquirer integration yet, card charges go to the in-process fake
+	cardProcessor := processor.NewFake(cardVault)
 
-	authService := users.NewUsers(keycloakService, authRepository, r.aliasWords)
-	accountsService := accounts.NewService(authService, accountsRepository, transactionsRepository, keycloakService, r.aliasWords)
-	cardService := cards.NewService(cardsRepository)
+	authService := users.NewUsers(identityService, authRepository, r.aliasWords)
+	accountsService := accounts.NewService(authService, accountsRepository, identityService, r.aliasWords, cvuGenerator())
+	limitsService := limits.NewService(limitsRepository)
+	feesService := fees.NewService(r.feeRules(), limitsRepository)
+	transactionsService := transactions.NewService(transactionsRepository, accountsRepository, cardsRepository, cardProcessor, feesService)
+	cardService := cards.NewService(cardsRepository, cardVault, cardProcessor)
+	receiptsService := receipts.NewService(transactionsRepository, accountsRepository, receiptSecret())
+	statementsService := statements.NewService(transactionsRepository, accountsRepository)
+	scheduledService := scheduled.NewService(scheduledRepository, accountsRepository)
+	exchangeService := exchange.NewService(exchangeRepository, accountsRepository, rateProvider())
+	contactsService := contacts.NewService(contactsRepository, accountsRepository)
 
 	authHandler := handler.NewAuthHandler(authService, accountsService)
 	accountsHandler := handler.NewAccountsHandler(accountsService)
+	transactionsHandler := handler.NewTransactionsHandler(transactionsService)
+	receiptsHandler := handler.NewReceiptsHandler(receiptsService)
+	statementsHandler := handler.NewStatementsHandler(statementsService)
+	scheduledTransfersHandler := handler.NewScheduledTransfersHandler(scheduledService)
+	limitsHandler := handler.NewLimitsHandler(limitsService)
+	feesHandler := handler.NewFeesHandler(feesService)
+	exchangeHandler := handler.NewExchangeHandler(exchangeService)
+	contactsHandler := handler.NewContactsHandler(contactsService)
 	cardsHandler := handler.NewCardHandler(cardService, accountsService)
 	middlewares := handler.NewMiddlewares(accountsService)
+	idempotencyMiddleware := handler.NewIdempotency(idempotencyRepository, idempotencyTTL())
+
+	go idempotency.RunCleanup(context.Background(), idempotencyRepository, idempotencyCleanupTick)
+	go scheduled.NewWorker(scheduledRepository, transactionsService).Run(context.Background(), scheduledTransfersTick)
 
 	r.rg = r.r.Group("/api")
 
 	accountsGroup := r.rg.Group("/accounts")
+	accountsGroup.GET("/lookup", middlewares.IsAuthenticated, middlewares.RateLimit(ratelimit.New(lookupRateLimit, lookupRateWindow)), accountsHandler.Lookup)
 	accountsGroup.GET("/:accountID", middlewares.IsAuthorized, accountsHandler.GetAccount)
 	accountsGroup.PATCH("/:accountID", middlewares.IsAuthorized, accountsHandler.ChangeAlias())
-	accountsGroup.GET("/:accountID/transactions", middlewares.IsAuthorized, accountsHandler.GetTransactionsLastFive)
+	accountsGroup.GET("/:accountID/alias-history", middlewares.IsAuthorized, accountsHandler.GetAliasHistory)
+	accountsGroup.GET("/:accountID/limits", middlewares.IsAuthorized, limitsHandler.GetLimits)
+	accountsGroup.PUT("/:accountID/limits", middlewares.HasRole(accounts.AdminRole), limitsHandler.Update())
+	accountsGroup.GET("/:accountID/fees/preview", middlewares.IsAuthorized, feesHandler.Preview)
+	accountsGroup.GET("/:accountID/balances", middlewares.IsAuthorized, exchangeHandler.GetBalances)
+	accountsGroup.POST("/:accountID/conversions", middlewares.IsAuthorized, idempotencyMiddleware.Handle, exchangeHandler.Convert())
+	accountsGroup.GET("/:accountID/transactions", middlewares.IsAuthorized, transactionsHandler.GetTransactionsLastFive)
+	accountsGroup.GET("/:accountID/activity", middlewares.IsAuthorized, transactionsHandler.GetActivity)
+	accountsGroup.GET("/:accountID/activity/:transactionID", middlewares.IsAuthorized, transactionsHandler.GetByID)
+	accountsGroup.GET("/:accountID/activity/:transactionID/receipt", middlewares.IsAuthorized, receiptsHandler.GetReceipt)
+	accountsGroup.POST("/:accountID/activity/:transactionID/reversal", middlewares.IsAuthorized, idempotencyMiddleware.Handle, transactionsHandler.Reverse())
+	accountsGroup.GET("/:accountID/statements", middlewares.IsAuthorized, statementsHandler.GetStatement)
+	accountsGroup.POST("/:accountID/transfers", middlewares.IsAuthorized, idempotencyMiddleware.Handle, transactionsHandler.Transfer())
+	accountsGroup.POST("/:accountID/deposits", middlewares.IsAuthorized, idempotencyMiddleware.Handle, transactionsHandler.Deposit())
+	accountsGroup.POST("/:accountID/scheduled-transfers", middlewares.IsAuthorized, idempotencyMiddleware.Handle, scheduledTransfersHandler.Create())
+	accountsGroup.GET("/:accountID/scheduled-transfers", middlewares.IsAuthorized, scheduledTransfersHandler.GetAll)
+	accountsGroup.GET("/:accountID/scheduled-transfers/:scheduledTransferID/executions", middlewares.IsAuthorized, scheduledTransfersHandler.GetExecutions)
+	accountsGroup.POST("/:accountID/scheduled-transfers/:scheduledTransferID/pause", middlewares.IsAuthorized, scheduledTransfersHandler.Pause)
+	accountsGroup.POST("/:accountID/scheduled-transfers/:scheduledTransferID/resume", middlewares.IsAuthorized, scheduledTransfersHandler.Resume)
+	accountsGroup.DELETE("/:accountID/scheduled-transfers/:scheduledTransferID", middlewares.IsAuthorized, scheduledTransfersHandler.Cancel)
+	accountsGroup.POST("/:accountID/contacts", middlewares.IsAuthorized, contactsHandler.Create())
+	accountsGroup.GET("/:accountID/contacts", middlewares.IsAuthorized, contactsHandler.GetAll)
+	accountsGroup.PATCH("/:accountID/contacts/:contactID", middlewares.IsAuthorized, contactsHandler.Rename())
+	accountsGroup.DELETE("/:accountID/contacts/:contactID", middlewares.IsAuthorized, contactsHandler.Delete)
+	accountsGroup.GET("/:accountID/recent-destinations", middlewares.IsAuthorized, contactsHandler.GetRecentDestinations)
 
 	cardsGroup := r.rg.Group("/accounts")
-	cardsGroup.POST("/:accountID/cards", middlewares.IsAuthorized, cardsHandler.NewCard())
+	cardsGroup.POST("/:accountID/cards", middlewares.IsAuthorized, idempotencyMiddleware.Handle, cardsHandler.NewCard())
 	cardsGroup.GET("/:accountID/cards", middlewares.IsAuthorized, cardsHandler.GetAll)
 	cardsGroup.GET("/:accountID/cards/:cardID", middlewares.IsAuthorized, cardsHandler.GetByCardID)
+	cardsGroup.PATCH("/:accountID/cards/:cardID", middlewares.IsAuthorized, cardsHandler.Update())
 	cardsGroup.DELETE("/:accountID/cards/:cardID", middlewares.IsAuthorized, cardsHandler.DeleteByCardID)
+	cardsGroup.POST("/:accountID/cards/:cardID/verification", middlewares.IsAuthorized, idempotencyMiddleware.Handle, cardsHandler.StartVerification)
+	cardsGroup.POST("/:accountID/cards/:cardID/verify", middlewares.IsAuthorized, cardsHandler.Verify())
+
+	aliasesGroup := r.rg.Group("/aliases")
+	aliasesGroup.GET("/:alias/availability", middlewares.IsAuthenticated, accountsHandler.CheckAliasAvailability)
+
+	operationsGroup := r.rg.Group("/operations")
+	operationsGroup.POST("/transactions/:transactionID/reversal", middlewares.HasRole(accounts.OperatorRole), idempotencyMiddleware.Handle, transactionsHandler.ReverseAsOperator())
 
 	usersGroup := r.rg.Group("/users")
 	usersGroup.POST("/", authHandler.Register())
 	usersGroup.GET("/:userID", middlewares.IsAuthorized, accountsHandler.GetUser)
 	usersGroup.PATCH("/:accountID", middlewares.IsAuthorized, accountsHandler.UpdateAccount())
 	usersGroup.POST("/login", authHandler.Login())
+	usersGroup.POST("/token/refresh", authHandler.Refresh())
 	usersGroup.GET("/logout", authHandler.HasToken, authHandler.Logout())
+	usersGroup.POST("/logout", authHandler.HasToken, authHandler.Logout())
 	usersGroup.POST("/forgot", authHandler.ForgotPassword())
+	usersGroup.POST("/verify-email", authHandler.VerifyEmail())
+	usersGroup.POST("/reset-password", authHandler.ResetPassword())
 
 	docs.SwaggerInfo.Host = "localhost:8080"
 	r.rg.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
+
+func idempotencyTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultIdempotencyTTL
+	}
+
+	return ttl
+}
+
+// feeRules reads the fee rules from FEE_RULES_FILE if it is set, and from the
+// fee_rules table otherwise.
+func (r *router) feeRules() fees.Source {
+	path := os.Getenv("FEE_RULES_FILE")
+	if path == "" {
+		return fees.NewRepository(r.db)
+	}
+
+	source, err := fees.NewFileSource(path)
+	if err != nil {
+		panic(err)
+	}
+
+	return source
+}
+
+// cvuGenerator builds the CVUs of new accounts with the entity prefix in
+// CVU_ENTITY_PREFIX, or cvu.DefaultPrefix if it is not set.
+func cvuGenerator() *cvu.Generator {
+	prefix := os.Getenv("CVU_ENTITY_PREFIX")
+	if prefix == "" {
+		prefix = cvu.DefaultPrefix
+	}
+
+	generator, err := cvu.NewGenerator(prefix)
+	if err != nil {
+		panic(err)
+	}
+
+	return generator
+}
+
+// vaultCipher encrypts card numbers with the base64 encoded 32 byte master key in
+// CARD_ENCRYPTION_KEY. There is no default: cards cannot be stored without it.
+func vaultCipher() *vault.Cipher {
+	cipher, err := vault.NewCipherFromBase64(os.Getenv("CARD_ENCRYPTION_KEY"))
+	if err != nil {
+		panic(err)
+	}
+
+	return cipher
+}
+
+// rateProvider reads exchange rates from the JSON file in EXCHANGE_RATES_FILE.
+// The file must exist and parse at startup, or no conversion could be made.
+func rateProvider() currency.RateProvider {
+	provider, err := currency.NewFileProvider(os.Getenv("EXCHANGE_RATES_FILE"))
+	if err != nil {
+		panic(err)
+	}
+
+	return provider
+}
+
+// receiptSecret is the key in RECEIPT_SECRET that signs the verification code
+// of receipts. There is no default: an empty key would let anyone forge codes.
+func receiptSecret() string {
+	secret := os.Getenv("RECEIPT_SECRET")
+	if err := receipts.ValidateSecret(secret); err != nil {
+		panic(err)
+	}
+
+	return secret
+}
+
+// identityProvider is Keycloak, unless IDENTITY_PROVIDER is "memory": then
+// users and sessions live in the process and are lost on restart, which is
+// only meant for development and integration tests. Users registering with
+// one of the comma separated emails of MEMORY_ADMIN_EMAILS or
+// MEMORY_OPERATOR_EMAILS get that role.
+func identityProvider() auth.Auth {
+	switch provider := os.Getenv("IDENTITY_PROVIDER"); provider {
+	case "", "keycloak":
+		return auth.NewAuth(auth.KeycloakSettings{
+			GoCloak:      gocloak.NewClient(os.Getenv("KEYCLOAK_URL")),
+			ClientId:     os.Getenv("KEYCLOAK_CLIENT_ID"),
+			ClientSecret: os.Getenv("KEYCLOAK_CLIENT_SECRET"),
+			Realm:        os.Getenv("KEYCLOAK_REALM"),
+			Verifier:     tokenVerifier(),
+		})
+	case "memory":
+		roles := make(map[string][]string)
+		for env, role := range map[string]string{"MEMORY_ADMIN_EMAILS": accounts.AdminRole, "MEMORY_OPERATOR_EMAILS": accounts.OperatorRole} {
+			for _, email := range strings.Split(os.Getenv(env), ",") {
+				if email = strings.TrimSpace(email); email != "" {
+					roles[email] = append(roles[email], role)
+				}
+			}
+		}
+
+		memory, err := auth.NewMemory(auth.MemorySettings{Roles: roles})
+		if err != nil {
+			panic(err)
+		}
+		return memory
+	default:
+		panic(fmt.Sprintf("invalid IDENTITY_PROVIDER %q", provider))
+	}
+}
+
+// tokenVerifier checks access tokens against the realm keys. The issuer is
+// KEYCLOAK_ISSUER, for when Keycloak is reached at another URL than the one it
+// puts in its tokens, and the realm URL otherwise. The audience is
+// KEYCLOAK_AUDIENCE or defaultTokenAudience, and TOKEN_LEEWAY the allowed
+// clock skew: jwks.DefaultLeeway when unset, none when "0".
+func tokenVerifier() *jwks.Verifier {
+	realmURL := strings.TrimSuffix(os.Getenv("KEYCLOAK_URL"), "/") + "/realms/" + os.Getenv("KEYCLOAK_REALM")
+
+	issuer := os.Getenv("KEYCLOAK_ISSUER")
+	if issuer == "" {
+		issuer = realmURL
+	}
+
+	audience := os.Getenv("KEYCLOAK_AUDIENCE")
+	if audience == "" {
+		audience = defaultTokenAudience
+	}
+
+	var leeway *time.Duration
+	if value := os.Getenv("TOKEN_LEEWAY"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			panic(fmt.Sprintf("invalid TOKEN_LEEWAY %q", value))
+		}
+		leeway = &parsed
+	}
+
+	return jwks.NewVerifier(jwks.VerifierSettings{
+		Keys:     jwks.NewRemote(realmURL + "/protocol/openid-connect/certs"),
+		Issuer:   issuer,
+		Audience: audience,
+		Leeway:   leeway,
+	})
+}
@@ -2,5 +2,21 @@ CREATE DATABASE digitalmoneyhouse;
 USE digitalmoneyhouse;
 CREATE TABLE users(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, dni INT, phone INT);
 CREATE TABLE accounts(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, user_id int not null, auth_id VARCHAR(255), cvu VARCHAR(22), alias VARCHAR(255), balance DECIMAL(15, 2) DEFAULT "0.00");
-CREATE TABLE transactions(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, account_id int not null, origin_cvu VARCHAR(22),  destination_cvu VARCHAR(22),  description VARCHAR(50), amount DECIMAL(15, 2), date_time datetime, type VARCHAR(20));
-CREATE TABLE cards(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, account_id int not null, pan VARCHAR(20), holder_name VARCHAR(255), expiration_date datetime, cid VARCHAR(4), type VARCHAR(20));
\ No newline at end of file
+CREATE TABLE transactions(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, account_id int not null, origin_cvu VARCHAR(22),  destination_cvu VARCHAR(22),  description VARCHAR(50), amount DECIMAL(15, 2), currency CHAR(3) NOT NULL DEFAULT "ARS", date_time datetime, type VARCHAR(20), journal_entry_id INT, reversal_of INT, reversed_by INT, processor_reference VARCHAR(255), refund_status VARCHAR(20), INDEX transactions_journal_entry_idx (journal_entry_id));
+CREATE TABLE cards(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, account_id int not null, last_four CHAR(4) NOT NULL, holder_name VARCHAR(255), expiration_date datetime, type VARCHAR(20), brand VARCHAR(20) NOT NULL DEFAULT "", nickname VARCHAR(30) NOT NULL DEFAULT "", is_default BOOLEAN NOT NULL DEFAULT FALSE, verification_status VARCHAR(20) NOT NULL DEFAULT "pending", token VARCHAR(64) NOT NULL, deleted_at datetime, active_token VARCHAR(64) AS (IF(deleted_at IS NULL, token, NULL)) STORED, UNIQUE INDEX cards_active_token_idx (active_token), INDEX cards_account_idx (account_id, deleted_at));
+CREATE INDEX transactions_account_date_idx ON transactions(account_id, date_time, id);
+CREATE TABLE idempotency_keys(idempotency_key VARCHAR(255) NOT NULL, scope VARCHAR(255) NOT NULL, request_hash CHAR(64) NOT NULL, status_code INT, response_body MEDIUMBLOB, expires_at datetime NOT NULL, PRIMARY KEY (idempotency_key, scope), INDEX idempotency_keys_expires_at_idx (expires_at));
+CREATE TABLE journal_entries(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, kind VARCHAR(20) NOT NULL, description VARCHAR(255), created_at datetime NOT NULL);
+CREATE TABLE postings(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, journal_entry_id INT NOT NULL, ledger_account VARCHAR(64) NOT NULL, amount DECIMAL(15, 2) NOT NULL, INDEX postings_ledger_account_idx (ledger_account), FOREIGN KEY (journal_entry_id) REFERENCES journal_entries(id));
+CREATE TABLE scheduled_transfers(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, account_id INT NOT NULL, destination VARCHAR(255) NOT NULL, destination_account_id INT NOT NULL, amount DECIMAL(15, 2) NOT NULL, description VARCHAR(50), frequency VARCHAR(10) NOT NULL, day_of_month INT, next_run_at datetime NOT NULL, run_after datetime NOT NULL, status VARCHAR(20) NOT NULL, attempts INT NOT NULL DEFAULT 0, last_error VARCHAR(255), last_run_at datetime, created_at datetime NOT NULL, INDEX scheduled_transfers_account_idx (account_id), INDEX scheduled_transfers_due_idx (status, run_after));
+CREATE TABLE scheduled_transfer_executions(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, scheduled_transfer_id INT NOT NULL, transaction_id INT, status VARCHAR(20) NOT NULL, error VARCHAR(255), executed_at datetime NOT NULL, FOREIGN KEY (scheduled_transfer_id) REFERENCES scheduled_transfers(id));
+CREATE TABLE scheduled_transfer_runs(scheduled_transfer_id INT NOT NULL, run_at datetime NOT NULL, transaction_id INT NOT NULL, PRIMARY KEY (scheduled_transfer_id, run_at), FOREIGN KEY (scheduled_transfer_id) REFERENCES scheduled_transfers(id));
+CREATE TABLE account_limits(account_id INT NOT NULL PRIMARY KEY, tier VARCHAR(20) NOT NULL, per_transaction DECIMAL(15, 2), daily DECIMAL(15, 2), monthly DECIMAL(15, 2), max_transfers_per_hour INT);
+CREATE TABLE fee_rules(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, operation VARCHAR(20) NOT NULL, tier VARCHAR(20), min_amount DECIMAL(15, 2) NOT NULL DEFAULT "0.00", max_amount DECIMAL(15, 2), percentage DECIMAL(7, 4) NOT NULL DEFAULT "0.0000", fixed DECIMAL(15, 2) NOT NULL DEFAULT "0.00", priority INT NOT NULL DEFAULT 0);
+CREATE TABLE currency_accounts(account_id INT NOT NULL, currency CHAR(3) NOT NULL, balance DECIMAL(15, 2) NOT NULL DEFAULT "0.00", PRIMARY KEY (account_id, currency), FOREIGN KEY (account_id) REFERENCES accounts(id));
+CREATE UNIQUE INDEX accounts_alias_idx ON accounts(alias);
+CREATE TABLE alias_history(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, account_id INT NOT NULL, old_alias VARCHAR(255) NOT NULL, new_alias VARCHAR(255) NOT NULL, changed_at DATETIME NOT NULL, INDEX alias_history_account_idx (account_id, changed_at), FOREIGN KEY (account_id) REFERENCES accounts(id));
+CREATE TABLE contacts(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, account_id INT NOT NULL, destination_account_id INT NOT NULL, nickname VARCHAR(30) NOT NULL, created_at DATETIME NOT NULL, UNIQUE INDEX contacts_account_destination_idx (account_id, destination_account_id), FOREIGN KEY (account_id) REFERENCES accounts(id), FOREIGN KEY (destination_account_id) REFERENCES accounts(id));
+CREATE TABLE vault_tokens(token VARCHAR(64) NOT NULL PRIMARY KEY, fingerprint CHAR(64) NOT NULL, ciphertext VARCHAR(255) NOT NULL, data_key VARCHAR(255) NOT NULL, last_four CHAR(4) NOT NULL, created_at DATETIME NOT NULL, UNIQUE INDEX vault_tokens_fingerprint_idx (fingerprint));
+CREATE TABLE vault_access_log(id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, token VARCHAR(64) NOT NULL, action VARCHAR(20) NOT NULL, purpose VARCHAR(50), success BOOLEAN NOT NULL, created_at DATETIME NOT NULL, INDEX vault_access_log_token_idx (token, created_at));
+CREATE TABLE card_verifications(card_id INT NOT NULL PRIMARY KEY, first_amount DECIMAL(15, 2) NOT NULL, second_amount DECIMAL(15, 2) NOT NULL, first_reference VARCHAR(255) NOT NULL, second_reference VARCHAR(255) NOT NULL, attempts INT NOT NULL DEFAULT 0, created_at DATETIME NOT NULL, FOREIGN KEY (card_id) REFERENCES cards(id));
@@ -0,0 +1,103 @@
+package accounts
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidAlias = errors.New("alias must be 6 to 20 letters, digits, dots or hyphens")
+	ErrAliasBlocked = errors.New("alias contains a blocked word")
+	ErrNoFreeAlias  = errors.New("no free alias found")
+)
+
+var (
+	aliasRegexp     = regexp.MustCompile(`^[a-z0-9.-]{6,20}$`)
+	aliasWordRegexp = regexp.MustCompile(`^[a-z0-9]+$`)
+)
+
+const (
+	// aliasWords is how many words a generated alias has.
+	aliasWords = 3
+	// maxAliasWordLength keeps any aliasWords words, with the dots between
+	// them, within the 20 characters of an alias.
+	maxAliasWordLength = 6
+	// minAliasWords is the least usable words there must be for generated
+	// aliases not to run out.
+	minAliasWords = 100
+	// maxAliasAttempts is how many aliases are drawn before giving up.
+	maxAliasAttempts = 20
+)
+
+// accents maps the accented letters of Spanish words to the plain ones.
+var accents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// aliasBlocklist holds words an alias cannot contain, so nobody can pass as
+// the wallet staff, a bank or the regulator.
+var aliasBlocklist = []string{
+	"admin",
+	"soporte",
+	"support",
+	"oficial",
+	"official",
+	"banco",
+	"bcra",
+	"afip",
+	"digitalmoney",
+	"dmhouse",
+}
+
+// NormalizeAlias returns the form aliases are stored and compared in.
+// Aliases are case-insensitive.
+func NormalizeAlias(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
+
+// AliasWords returns the words of the list, one per line, that generated
+// aliases can be made of: lowercased, without accents, short enough and not
+// blocked. It fails when fewer than minAliasWords are left.
+func AliasWords(list string) ([]string, error) {
+	var words []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(list, "\n") {
+		word := accents.Replace(NormalizeAlias(line))
+		if len(word) > maxAliasWordLength || !aliasWordRegexp.MatchString(word) || blocked(word) || seen[word] {
+			continue
+		}
+
+		seen[word] = true
+		words = append(words, word)
+	}
+
+	if len(words) < minAliasWords {
+		return nil, fmt.Errorf("alias word list has %d usable words, at least %d are needed", len(words), minAliasWords)
+	}
+
+	return words, nil
+}
+
+// ValidateAlias checks the normalized alias against the format rules and the
+// blocklist.
+func ValidateAlias(alias string) error {
+	if !aliasRegexp.MatchString(alias) {
+		return ErrInvalidAlias
+	}
+
+	if blocked(alias) {
+		return ErrAliasBlocked
+	}
+
+	return nil
+}
+
+func blocked(alias string) bool {
+	for _, word := range aliasBlocklist {
+		if strings.Contains(alias, word) {
+			return true
+		}
+	}
+
+	return false
+}
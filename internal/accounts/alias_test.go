@@ -0,0 +1,71 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+func TestValidateAlias(t *testing.T) {
+	testCases := []struct {
+		name          string
+		alias         string
+		expectedError error
+	}{
+		{name: "Words with dots", alias: "casa.perro.gato"},
+		{name: "Digits and hyphens", alias: "mate-2023.sol"},
+		{name: "Shortest", alias: "abcdef"},
+		{name: "Longest", alias: "abcdefghij.klmnopqrs"},
+		{name: "Too short", alias: "abcde", expectedError: ErrInvalidAlias},
+		{name: "Too long", alias: "abcdefghij.klmnopqrst", expectedError: ErrInvalidAlias},
+		{name: "Spaces", alias: "casa perro", expectedError: ErrInvalidAlias},
+		{name: "Accents", alias: "ñandu.veloz", expectedError: ErrInvalidAlias},
+		{name: "Uppercase is not normalized", alias: "Casa.Perro", expectedError: ErrInvalidAlias},
+		{name: "Blocked word", alias: "soporte.dmh", expectedError: ErrAliasBlocked},
+		{name: "Blocked word inside", alias: "el.bancopopular", expectedError: ErrAliasBlocked},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedError, ValidateAlias(testCase.alias))
+		})
+	}
+}
+
+func TestNormalizeAlias(t *testing.T) {
+	assert.Equal(t, "casa.perro.gato", NormalizeAlias(" Casa.PERRO.gato "))
+}
+
+func TestAliasWords(t *testing.T) {
+	var lines []string
+	for i := 0; i < minAliasWords; i++ {
+		lines = append(lines, fmt.Sprintf("w%d\r", i))
+	}
+	lines = append(lines, "Ñandú", "  Casa ", "casa", "mariposa", "dos palabras", "banco", "")
+
+	words, err := AliasWords(strings.Join(lines, "\n"))
+	assert.NoError(t, err)
+	assert.Len(t, words, minAliasWords+2)
+	assert.Equal(t, "w0", words[0])
+	assert.Equal(t, []string{"nandu", "casa"}, words[minAliasWords:])
+
+	_, err = AliasWords(strings.Join(lines[minAliasWords-1:], "\n"))
+	assert.Error(t, err)
+}
+
+func TestGetNewAlias(t *testing.T) {
+	ctx := context.Background()
+
+	accountsService := &service{accountsRepository: repositoryStub{}, aliasWords: []string{"casa"}}
+	alias, err := accountsService.getNewAlias(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "casa.casa.casa", alias)
+
+	taken := repositoryStub{accounts: []domain.Account{{ID: 1, Alias: "casa.casa.casa"}}}
+	accountsService = &service{accountsRepository: taken, aliasWords: []string{"casa"}}
+	_, err = accountsService.getNewAlias(ctx)
+	assert.Equal(t, ErrNoFreeAlias, err)
+}
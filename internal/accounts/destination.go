@@ -0,0 +1,51 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/cvu"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var (
+	ErrDestinationNotFound = errors.New("destination account not found")
+	ErrInvalidDestination  = errors.New("invalid destination cvu")
+)
+
+var cvuRegexp = regexp.MustCompile(`^[0-9]{22}$`)
+
+// GetDestination resolves the account money is sent to, which can be given
+// either by CVU or by alias.
+func GetDestination(ctx context.Context, repository Repository, destination string) (domain.Account, error) {
+	var account domain.Account
+	var err error
+	if cvuRegexp.MatchString(destination) {
+		account, err = getAccountByCVU(ctx, repository, destination)
+	} else {
+		account, err = repository.GetAccountByAlias(ctx, NormalizeAlias(destination))
+	}
+
+	switch err {
+	case nil:
+		return account, nil
+	case ErrInvalidCVU:
+		return domain.Account{}, ErrInvalidDestination
+	case ErrAccountNotFound:
+		return domain.Account{}, ErrDestinationNotFound
+	default:
+		return domain.Account{}, err
+	}
+}
+
+// getAccountByCVU returns ErrInvalidCVU for a CVU whose check digits are
+// wrong, without looking it up, so a mistyped CVU is told apart from an
+// unknown one.
+func getAccountByCVU(ctx context.Context, repository Repository, number string) (domain.Account, error) {
+	if err := cvu.Validate(number); err != nil {
+		return domain.Account{}, ErrInvalidCVU
+	}
+
+	return repository.GetAccountByCVU(ctx, number)
+}
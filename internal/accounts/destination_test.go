@@ -0,0 +1,66 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+// repositoryStub finds the accounts it holds by CVU and alias.
+type repositoryStub struct {
+	Repository
+	accounts []domain.Account
+}
+
+func (r repositoryStub) GetAccountByCVU(ctx context.Context, cvu string) (domain.Account, error) {
+	for _, account := range r.accounts {
+		if account.CVU == cvu {
+			return account, nil
+		}
+	}
+	return domain.Account{}, ErrAccountNotFound
+}
+
+func (r repositoryStub) GetAccountByAlias(ctx context.Context, alias string) (domain.Account, error) {
+	for _, account := range r.accounts {
+		if account.Alias == alias {
+			return account, nil
+		}
+	}
+	return domain.Account{}, ErrAccountNotFound
+}
+
+func (r repositoryStub) AliasExist(ctx context.Context, alias string) bool {
+	_, err := r.GetAccountByAlias(ctx, alias)
+	return err == nil
+}
+
+func TestGetDestination(t *testing.T) {
+	checked := domain.Account{ID: 1, CVU: "0000003100000000000017", Alias: "casa.perro.gato"}
+	legacy := domain.Account{ID: 2, CVU: "1234567890123456789012"}
+	repository := repositoryStub{accounts: []domain.Account{checked, legacy}}
+
+	testCases := []struct {
+		name           string
+		destination    string
+		expectedError  error
+		expectedResult domain.Account
+	}{
+		{name: "By CVU", destination: checked.CVU, expectedResult: checked},
+		{name: "Held CVU with wrong check digits", destination: legacy.CVU, expectedError: ErrInvalidDestination},
+		{name: "By alias in any case", destination: "Casa.Perro.Gato", expectedResult: checked},
+		{name: "Mistyped CVU", destination: "0000003100000000000018", expectedError: ErrInvalidDestination},
+		{name: "Unknown CVU", destination: "0000003100000000000024", expectedError: ErrDestinationNotFound},
+		{name: "Unknown alias", destination: "no.existe.alias", expectedError: ErrDestinationNotFound},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			account, err := GetDestination(context.Background(), repository, testCase.destination)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, account)
+		})
+	}
+}
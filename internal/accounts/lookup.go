@@ -0,0 +1,79 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var ErrInvalidCVU = errors.New("invalid cvu")
+
+// Lookup returns what a payer may see of the account with the CVU or alias
+// before transferring to it: enough to recognise the holder, not to identify
+// them.
+func (s *service) Lookup(ctx context.Context, destinationCVU, alias string) (domain.AccountLookup, error) {
+	var account domain.Account
+	var err error
+	if destinationCVU != "" {
+		account, err = getAccountByCVU(ctx, s.accountsRepository, destinationCVU)
+	} else {
+		account, err = s.accountsRepository.GetAccountByAlias(ctx, NormalizeAlias(alias))
+	}
+	if err != nil {
+		return domain.AccountLookup{}, err
+	}
+
+	user, err := s.usersService.GetByID(ctx, account.User.ID)
+	if err != nil {
+		return domain.AccountLookup{}, err
+	}
+
+	authUsers, err := s.auth.GetUsers(ctx, domain.GetUserFilters{AuthID: account.AuthID})
+	if err != nil {
+		return domain.AccountLookup{}, err
+	}
+	if len(authUsers) == 0 {
+		return domain.AccountLookup{}, ErrAccountNotFound
+	}
+
+	holder := authUsers[0]
+	var name []string
+	for _, part := range []string{holder.FirstName, holder.LastName} {
+		if part != "" {
+			name = append(name, part)
+		}
+	}
+
+	return domain.AccountLookup{
+		HolderName: maskName(strings.Join(name, " ")),
+		DNI:        maskDNI(user.DNI),
+		Alias:      account.Alias,
+		CVU:        account.CVU,
+		Active:     holder.Enabled,
+	}, nil
+}
+
+// maskName keeps the first letter of every word of the name.
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + strings.Repeat("*", utf8.RuneCountInString(word[size:]))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// maskDNI keeps the last three digits of the DNI.
+func maskDNI(dni int) string {
+	digits := fmt.Sprint(dni)
+	if len(digits) <= 3 {
+		return strings.Repeat("*", len(digits))
+	}
+
+	return strings.Repeat("*", len(digits)-3) + digits[len(digits)-3:]
+}
@@ -0,0 +1,18 @@
+package accounts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskName(t *testing.T) {
+	assert.Equal(t, "J*** P****", maskName("Juan Pérez"))
+	assert.Equal(t, "Á**** M** N****", maskName("Ángel  Mía Núñez"))
+	assert.Equal(t, "", maskName(""))
+}
+
+func TestMaskDNI(t *testing.T) {
+	assert.Equal(t, "*****678", maskDNI(12345678))
+	assert.Equal(t, "**", maskDNI(12))
+}
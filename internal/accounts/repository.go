@@ -3,9 +3,13 @@ package accounts
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
 	"gitlab.com/leorodriguez/grupo-04/internal/users"
 )
@@ -14,12 +18,18 @@ type Repository interface {
 	SaveAccount(ctx context.Context, accountDto domain.AccountDto) (*users.UserDto, error)
 	GetAccountByID(ctx context.Context, id int) (domain.Account, error)
 	GetAccountByUserID(ctx context.Context, userID int) (domain.Account, error)
+	GetAccountByCVU(ctx context.Context, cvu string) (domain.Account, error)
+	GetAccountByAlias(ctx context.Context, alias string) (domain.Account, error)
 	CVUExist(ctx context.Context, cvu string) bool
 	AliasExist(ctx context.Context, alias string) bool
 	IsAuthorized(ctx context.Context, accountID int, isUserID bool, authID string) (bool, error)
 	UpdateAlias(ctx context.Context, accountID int, alias string) error
+	GetAliasHistory(ctx context.Context, accountID int) ([]domain.AliasChange, error)
+	GetBalances(ctx context.Context, accountID int) ([]domain.Balance, error)
 }
 
+const mysqlDuplicateEntry = 1062
+
 type repository struct {
 	db *sql.DB
 }
@@ -74,6 +84,44 @@ func (r *repository) GetAccountByUserID(ctx context.Context, userID int) (domain
 	return account, nil
 }
 
+func (r *repository) GetAccountByCVU(ctx context.Context, cvu string) (domain.Account, error) {
+	query := "SELECT * FROM accounts WHERE cvu = ?;"
+	rows := r.db.QueryRow(query, cvu)
+
+	var account domain.Account
+
+	err := rows.Scan(&account.ID, &account.User.ID, &account.AuthID, &account.CVU, &account.Alias, &account.Balance)
+	if err != nil {
+		switch err.Error() {
+		case "sql: no rows in result set":
+			return domain.Account{}, ErrAccountNotFound
+		default:
+			return domain.Account{}, err
+		}
+	}
+
+	return account, nil
+}
+
+func (r *repository) GetAccountByAlias(ctx context.Context, alias string) (domain.Account, error) {
+	query := "SELECT * FROM accounts WHERE alias = ?;"
+	rows := r.db.QueryRow(query, alias)
+
+	var account domain.Account
+
+	err := rows.Scan(&account.ID, &account.User.ID, &account.AuthID, &account.CVU, &account.Alias, &account.Balance)
+	if err != nil {
+		switch err.Error() {
+		case "sql: no rows in result set":
+			return domain.Account{}, ErrAccountNotFound
+		default:
+			return domain.Account{}, err
+		}
+	}
+
+	return account, nil
+}
+
 func (r *repository) IsAuthorized(ctx context.Context, accountID int, isUserID bool, authID string) (bool, error) {
 	var idName string
 	if isUserID {
@@ -118,6 +166,127 @@ func Save(ctx context.Context, tx *sql.Tx, account accountDB) (int, error) {
 	return int(id), nil
 }
 
+// GetBalanceForUpdate reads the account balance locking the row until tx ends.
+func GetBalanceForUpdate(ctx context.Context, tx *sql.Tx, accountID int) (decimal.Decimal, error) {
+	query := "SELECT balance FROM accounts WHERE id = ? FOR UPDATE;"
+	row := tx.QueryRowContext(ctx, query, accountID)
+
+	var balance decimal.Decimal
+	err := row.Scan(&balance)
+	if err != nil {
+		switch err.Error() {
+		case "sql: no rows in result set":
+			return decimal.Decimal{}, ErrAccountNotFound
+		default:
+			return decimal.Decimal{}, err
+		}
+	}
+
+	return balance, nil
+}
+
+// UpdateBalance adds amount (which may be negative) to the account balance.
+func UpdateBalance(ctx context.Context, tx *sql.Tx, accountID int, amount decimal.Decimal) error {
+	query := "UPDATE accounts SET balance = balance + ? WHERE id = ?;"
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, amount, accountID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected < 1 {
+		return ErrAccountNotFound
+	}
+
+	return nil
+}
+
+// GetCurrencyBalance returns the balance the account holds in code. The
+// primary currency balance is accounts.balance; a sub-account never credited
+// yet has a zero balance. Callers must hold the lock of the account row.
+func GetCurrencyBalance(ctx context.Context, tx *sql.Tx, accountID int, code string) (decimal.Decimal, error) {
+	if code == currency.Primary {
+		return GetBalanceForUpdate(ctx, tx, accountID)
+	}
+
+	query := "SELECT balance FROM currency_accounts WHERE account_id = ? AND currency = ? FOR UPDATE;"
+	row := tx.QueryRowContext(ctx, query, accountID, code)
+
+	var balance decimal.Decimal
+	err := row.Scan(&balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return decimal.Zero, nil
+		}
+		return decimal.Decimal{}, err
+	}
+
+	return balance, nil
+}
+
+// UpdateCurrencyBalance adds amount (which may be negative) to the balance
+// the account holds in code, opening the sub-account if needed.
+func UpdateCurrencyBalance(ctx context.Context, tx *sql.Tx, accountID int, code string, amount decimal.Decimal) error {
+	if code == currency.Primary {
+		return UpdateBalance(ctx, tx, accountID, amount)
+	}
+
+	query := "INSERT INTO currency_accounts(account_id, currency, balance) VALUES(?, ?, ?) ON DUPLICATE KEY UPDATE balance = balance + VALUES(balance);"
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, accountID, code, amount)
+	return err
+}
+
+// GetBalances returns the account balance in every currency it holds, the
+// primary one first.
+func (r *repository) GetBalances(ctx context.Context, accountID int) ([]domain.Balance, error) {
+	query := "SELECT ?, balance FROM accounts WHERE id = ? " +
+		"UNION ALL SELECT currency, balance FROM currency_accounts WHERE account_id = ?;"
+	rows, err := r.db.QueryContext(ctx, query, currency.Primary, accountID, accountID)
+	if err != nil {
+		return []domain.Balance{}, err
+	}
+	defer rows.Close()
+
+	var balances []domain.Balance
+
+	for rows.Next() {
+		var balance domain.Balance
+		if err = rows.Scan(&balance.Currency, &balance.Balance); err != nil {
+			return []domain.Balance{}, err
+		}
+
+		balances = append(balances, balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []domain.Balance{}, err
+	}
+
+	if len(balances) == 0 {
+		return []domain.Balance{}, ErrAccountNotFound
+	}
+
+	return balances, nil
+}
+
 func (r *repository) SaveAccount(ctx context.Context, accountDto domain.AccountDto) (*users.UserDto, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -180,22 +349,72 @@ func (r *repository) AliasExist(ctx context.Context, alias string) bool {
 	return err == nil
 }
 
+// UpdateAlias changes the alias of the account and records the change in its
+// alias history. The unique index on accounts.alias settles races between
+// accounts taking the same alias.
 func (r *repository) UpdateAlias(ctx context.Context, accountID int, alias string) error {
-	query := "UPDATE accounts SET alias = ? WHERE id = ?;"
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(&alias, &accountID)
+	var oldAlias sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT alias FROM accounts WHERE id = ? FOR UPDATE;", accountID).Scan(&oldAlias)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAccountNotFound
+		}
 		return err
 	}
 
-	_, err = res.RowsAffected()
+	query := "UPDATE accounts SET alias = ? WHERE id = ?;"
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
 
-	return nil
+	_, err = stmt.ExecContext(ctx, alias, accountID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return ErrAliasAlreadyExists
+		}
+		return err
+	}
+
+	query = "INSERT INTO alias_history(account_id, old_alias, new_alias, changed_at) VALUES(?, ?, ?, ?);"
+	if _, err = tx.ExecContext(ctx, query, accountID, oldAlias.String, alias, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAliasHistory returns the alias changes of the account, newest first.
+func (r *repository) GetAliasHistory(ctx context.Context, accountID int) ([]domain.AliasChange, error) {
+	query := "SELECT old_alias, new_alias, changed_at FROM alias_history WHERE account_id = ? ORDER BY changed_at DESC, id DESC;"
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return []domain.AliasChange{}, err
+	}
+	defer rows.Close()
+
+	changes := []domain.AliasChange{}
+
+	for rows.Next() {
+		var change domain.AliasChange
+		if err = rows.Scan(&change.OldAlias, &change.NewAlias, &change.ChangedAt); err != nil {
+			return []domain.AliasChange{}, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []domain.AliasChange{}, err
+	}
+
+	return changes, nil
 }
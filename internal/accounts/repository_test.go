@@ -0,0 +1,48 @@
+package accounts
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryUpdateAliasSuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT alias FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"alias"}).AddRow("casa.perro.gato"))
+	mock.ExpectPrepare("UPDATE accounts SET alias").ExpectExec().WithArgs("mesa.silla.sol", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO alias_history").WithArgs(1, "casa.perro.gato", "mesa.silla.sol", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	err = repo.UpdateAlias(context.Background(), 1, "mesa.silla.sol")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryUpdateAliasTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT alias FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"alias"}).AddRow("casa.perro.gato"))
+	mock.ExpectPrepare("UPDATE accounts SET alias").ExpectExec().WithArgs("mesa.silla.sol", 1).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.UpdateAlias(context.Background(), 1, "mesa.silla.sol")
+	assert.Equal(t, ErrAliasAlreadyExists, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,11 +3,11 @@ package accounts
 import (
 	"context"
 	"errors"
-	"fmt"
 	"gitlab.com/leorodriguez/grupo-04/internal/auth"
+	"gitlab.com/leorodriguez/grupo-04/internal/cvu"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
-	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
 	"gitlab.com/leorodriguez/grupo-04/internal/users"
+	"gitlab.com/leorodriguez/grupo-04/pkg/jwks"
 	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
 	"math/rand"
 	"strings"
@@ -17,34 +17,45 @@ var (
 	ErrAccountNotFound    = errors.New("account not found")
 	ErrAliasAlreadyExists = errors.New("alias already exists")
 	ErrTokenExpired       = errors.New("expired token")
+	ErrInvalidToken       = errors.New("invalid token")
+)
+
+// Realm roles of the back office staff.
+const (
+	OperatorRole = "operator"
+	AdminRole    = "admin"
 )
 
 type Service interface {
 	Register(ctx context.Context, rq domain.RegisterRequest) (*users.UserDto, error)
 	GetAccountInfo(ctx context.Context, id int, token string) (domain.AccountInfo, error)
 	GetUserInfo(ctx context.Context, id int) (domain.UserInfo, error)
-	GetTransactionsLastFive(ctx context.Context, id int, token string) ([]domain.TransactionInfo, error)
 	IsAuthorized(ctx context.Context, id int, isUserID bool, token string) (bool, error)
+	IsAuthenticated(ctx context.Context, token string) (bool, error)
+	GetAuthID(ctx context.Context, token string) (string, error)
+	HasRole(ctx context.Context, token string, role string) (bool, error)
 	UpdateAccount(ctx context.Context, rq domain.RegisterRequest, id int) (*users.UserDto, error)
 	UpdateAlias(ctx context.Context, accountID int, alias string) error
+	CheckAliasAvailability(ctx context.Context, alias string) (domain.AliasAvailability, error)
+	GetAliasHistory(ctx context.Context, accountID int) ([]domain.AliasChange, error)
+	Lookup(ctx context.Context, cvu, alias string) (domain.AccountLookup, error)
 }
 
 type service struct {
-	auth                   auth.Auth
-	usersService           users.Service
-	accountsRepository     Repository
-	transactionsRepository transactions.Repository
-	aliasWords             []string
+	auth               auth.Auth
+	usersService       users.Service
+	accountsRepository Repository
+	aliasWords         []string
+	cvuGenerator       *cvu.Generator
 }
 
-func NewService(usersService users.Service, accountsRepository Repository, transactionsRepository transactions.Repository, auth auth.Auth,
-	aliasWords []string) Service {
+func NewService(usersService users.Service, accountsRepository Repository, auth auth.Auth, aliasWords []string, cvuGenerator *cvu.Generator) Service {
 	return &service{
-		usersService:           usersService,
-		accountsRepository:     accountsRepository,
-		transactionsRepository: transactionsRepository,
-		auth:                   auth,
-		aliasWords:             aliasWords,
+		usersService:       usersService,
+		accountsRepository: accountsRepository,
+		auth:               auth,
+		aliasWords:         aliasWords,
+		cvuGenerator:       cvuGenerator,
 	}
 }
 
@@ -60,20 +71,24 @@ func (s *service) Register(ctx context.Context, rq domain.RegisterRequest) (*use
 		Password: rq.Password,
 	}
 
-	userIDAuth, err := s.auth.Register(ctx, ru)
+	newCVU := s.getNewCVU(ctx)
+	alias, err := s.getNewAlias(ctx)
 	if err != nil {
 		logger.Error(err.Error())
 		return &users.UserDto{}, users.ErrInternal
 	}
 
-	cvu := s.getNewCVU(ctx)
-	alias := s.getNewAlias(ctx)
+	userIDAuth, err := s.auth.Register(ctx, ru)
+	if err != nil {
+		logger.Error(err.Error())
+		return &users.UserDto{}, users.ErrInternal
+	}
 
 	accountToSave := domain.AccountDto{
 		AuthID: userIDAuth,
 		DNI:    rq.DNI,
 		Phone:  rq.Phone,
-		CVU:    cvu,
+		CVU:    newCVU,
 		Alias:  alias,
 	}
 
@@ -121,7 +136,7 @@ func (s *service) GetUserInfo(ctx context.Context, userID int) (domain.UserInfo,
 	filters := domain.GetUserFilters{
 		AuthID: account.AuthID,
 	}
-	authUsers, err := s.auth.GetUsersByEmail(ctx, filters)
+	authUsers, err := s.auth.GetUsers(ctx, filters)
 	if err != nil {
 		return domain.UserInfo{}, err
 	}
@@ -131,9 +146,9 @@ func (s *service) GetUserInfo(ctx context.Context, userID int) (domain.UserInfo,
 	}
 
 	accountInfo := domain.UserInfo{
-		Name:     *authUsers[0].FirstName,
-		LastName: *authUsers[0].LastName,
-		Email:    *authUsers[0].Email,
+		Name:     authUsers[0].FirstName,
+		LastName: authUsers[0].LastName,
+		Email:    authUsers[0].Email,
 		DNI:      user.DNI,
 		Phone:    user.Phone,
 	}
@@ -144,12 +159,7 @@ func (s *service) GetUserInfo(ctx context.Context, userID int) (domain.UserInfo,
 func (s *service) IsAuthorized(ctx context.Context, id int, isUserID bool, token string) (bool, error) {
 	authID, err := s.auth.GetIDFromToken(ctx, token)
 	if err != nil {
-		switch err.Error() {
-		case "could not decode accessToken with custom claims: Token is expired":
-			return false, ErrTokenExpired
-		default:
-			return false, err
-		}
+		return false, tokenError(err)
 	}
 
 	isAuthorized, err := s.accountsRepository.IsAuthorized(ctx, id, isUserID, authID)
@@ -160,13 +170,49 @@ func (s *service) IsAuthorized(ctx context.Context, id int, isUserID bool, token
 	return isAuthorized, nil
 }
 
-func (s *service) GetTransactionsLastFive(ctx context.Context, id int, token string) ([]domain.TransactionInfo, error) {
-	trx, err := s.transactionsRepository.GetAllByIDLimit(ctx, id, 5)
+// IsAuthenticated reports whether the token belongs to a logged in user. Only
+// expired tokens are an error, any other unusable token is just not valid.
+func (s *service) IsAuthenticated(ctx context.Context, token string) (bool, error) {
+	authID, err := s.auth.GetIDFromToken(ctx, token)
+	if err != nil {
+		if err = tokenError(err); err == ErrTokenExpired {
+			return false, err
+		}
+		return false, nil
+	}
+
+	return authID != "", nil
+}
+
+// GetAuthID returns the identity provider ID of the user the token was
+// issued to.
+func (s *service) GetAuthID(ctx context.Context, token string) (string, error) {
+	authID, err := s.auth.GetIDFromToken(ctx, token)
 	if err != nil {
-		return []domain.TransactionInfo{}, err
+		return "", tokenError(err)
 	}
 
-	return trx, nil
+	return authID, nil
+}
+
+func (s *service) HasRole(ctx context.Context, token string, role string) (bool, error) {
+	hasRole, err := s.auth.HasRole(ctx, token, role)
+	if err != nil {
+		return false, tokenError(err)
+	}
+
+	return hasRole, nil
+}
+
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, jwks.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwks.ErrInvalidToken):
+		return ErrInvalidToken
+	default:
+		return err
+	}
 }
 
 func (s *service) UpdateAccount(ctx context.Context, rq domain.RegisterRequest, id int) (*users.UserDto, error) {
@@ -219,6 +265,11 @@ func (s *service) UpdateAccount(ctx context.Context, rq domain.RegisterRequest,
 }
 
 func (s *service) UpdateAlias(ctx context.Context, accountID int, alias string) error {
+	alias = NormalizeAlias(alias)
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+
 	exists := s.accountsRepository.AliasExist(ctx, alias)
 	if exists {
 		return ErrAliasAlreadyExists
@@ -232,36 +283,61 @@ func (s *service) UpdateAlias(ctx context.Context, accountID int, alias string)
 	return nil
 }
 
+func (s *service) CheckAliasAvailability(ctx context.Context, alias string) (domain.AliasAvailability, error) {
+	alias = NormalizeAlias(alias)
+	availability := domain.AliasAvailability{Alias: alias}
+
+	if err := ValidateAlias(alias); err != nil {
+		availability.Reason = err.Error()
+		return availability, nil
+	}
+
+	if exists := s.accountsRepository.AliasExist(ctx, alias); exists {
+		availability.Reason = ErrAliasAlreadyExists.Error()
+		return availability, nil
+	}
+
+	availability.Available = true
+	return availability, nil
+}
+
+func (s *service) GetAliasHistory(ctx context.Context, accountID int) ([]domain.AliasChange, error) {
+	return s.accountsRepository.GetAliasHistory(ctx, accountID)
+}
+
 func (s *service) getNewCVU(ctx context.Context) string {
-	var cvu string
+	var newCVU string
 	for true {
-		cvu = fmt.Sprint(rand.Int63n(1000000000000000000))
-		cvu += fmt.Sprint(rand.Int63n(10000))
+		newCVU = s.cvuGenerator.Generate()
 
-		if exists := s.accountsRepository.CVUExist(ctx, cvu); !exists {
+		if exists := s.accountsRepository.CVUExist(ctx, newCVU); !exists {
 			break
 		}
 	}
 
-	return cvu
+	return newCVU
 }
 
-func (s *service) getNewAlias(ctx context.Context) string {
-	var aliasList []string
-	var alias string
+// getNewAlias draws aliases from the words given by AliasWords until one is
+// free, giving up after maxAliasAttempts.
+func (s *service) getNewAlias(ctx context.Context) (string, error) {
+	aliasList := make([]string, aliasWords)
 	listLen := len(s.aliasWords)
 
-	for true {
-		for i := 0; i < 3; i++ {
-			y := rand.Intn(listLen)
-			aliasList = append(aliasList, s.aliasWords[y])
+	for attempt := 0; attempt < maxAliasAttempts; attempt++ {
+		for i := range aliasList {
+			aliasList[i] = s.aliasWords[rand.Intn(listLen)]
 		}
 
-		alias = strings.Join(aliasList, ".")
+		// very short words can make an alias under the minimum length
+		alias := strings.Join(aliasList, ".")
+		if ValidateAlias(alias) != nil {
+			continue
+		}
 		if exists := s.accountsRepository.AliasExist(ctx, alias); !exists {
-			break
+			return alias, nil
 		}
 	}
 
-	return alias
+	return "", ErrNoFreeAlias
 }
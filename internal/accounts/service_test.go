@@ -0,0 +1,18 @@
+package accounts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/pkg/jwks"
+)
+
+func TestTokenError(t *testing.T) {
+	other := errors.New("loading signing keys: connection refused")
+
+	assert.Equal(t, ErrTokenExpired, tokenError(jwks.ErrTokenExpired))
+	assert.Equal(t, ErrInvalidToken, tokenError(jwks.ErrTokenMalformed))
+	assert.Equal(t, ErrInvalidToken, tokenError(jwks.ErrWrongAudience))
+	assert.Equal(t, other, tokenError(other))
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var (
+	ErrAuthInvalidUserCredentials = errors.New("invalid user credentials")
+	ErrEmailNotVerified           = errors.New("400 Bad Request: invalid_grant: Account is not fully set up")
+	ErrInvalidRefreshToken        = errors.New("invalid refresh token")
+	ErrUserAlreadyExists          = errors.New("user already exists")
+	ErrUserNotFound               = errors.New("user not found")
+	ErrInvalidCode                = errors.New("invalid or expired code")
+	ErrUnsupported                = errors.New("not supported by the identity provider")
+)
+
+// Auth is the identity provider: it owns the users credentials and sessions.
+// Keycloak is one implementation, Memory another one.
+type Auth interface {
+	Register(ctx context.Context, rq domain.RegisterUser) (string, error)
+	Login(ctx context.Context, rq domain.LoginUser) (domain.LoginResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (domain.LoginResponse, error)
+	// Logout ends the session of the refresh token. A refresh token that is
+	// not of the user authID is invalid.
+	Logout(ctx context.Context, authID, refreshToken string) error
+	UserExists(ctx context.Context, email string) bool
+	GetUsers(ctx context.Context, filters domain.GetUserFilters) ([]domain.Identity, error)
+	SendVerifyEmail(ctx context.Context, userID string) error
+	// SendEmail sends the user an email to reset the password.
+	SendEmail(ctx context.Context, userID string) error
+	// VerifyEmail and ResetPassword complete the actions of the emails with
+	// their code. Providers whose emails link to their own pages return
+	// ErrUnsupported.
+	VerifyEmail(ctx context.Context, code string) error
+	ResetPassword(ctx context.Context, code string, password string) error
+	GetIDFromToken(ctx context.Context, accessToken string) (string, error)
+	HasRole(ctx context.Context, accessToken string, role string) (bool, error)
+	Update(ctx context.Context, rq domain.RegisterUser, authID string) error
+}
+
+// TokenVerifier checks access tokens without calling the provider, see jwks.Verifier.
+type TokenVerifier interface {
+	Verify(ctx context.Context, accessToken string) (jwt.MapClaims, error)
+}
+
+// subject returns the user ID of a valid token.
+func subject(ctx context.Context, verifier TokenVerifier, accessToken string) (string, error) {
+	claims, err := verifier.Verify(ctx, accessToken)
+	if err != nil {
+		return "", err
+	}
+
+	id, _ := claims["sub"].(string)
+	return id, nil
+}
+
+// hasRole reports whether a valid token grants the realm role.
+func hasRole(ctx context.Context, verifier TokenVerifier, accessToken string, role string) (bool, error) {
+	claims, err := verifier.Verify(ctx, accessToken)
+	if err != nil {
+		return false, err
+	}
+
+	realmAccess, _ := claims["realm_access"].(map[string]interface{})
+	roles, _ := realmAccess["roles"].([]interface{})
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
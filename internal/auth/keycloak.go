@@ -10,33 +10,16 @@ import (
 	"net/http"
 )
 
-var (
-	ErrAuthInvalidUserCredentials = errors.New("invalid user credentials")
-	ErrEmailNotVerified           = errors.New("400 Bad Request: invalid_grant: Account is not fully set up")
-)
-
-type Auth interface {
-	Register(ctx context.Context, rq domain.RegisterUser) (string, error)
-	Login(ctx context.Context, rq domain.LoginUser) (string, error)
-	Logout(ctx context.Context, token string) error
-	UserExists(ctx context.Context, email string) bool
-	GetUsersByEmail(ctx context.Context, filters domain.GetUserFilters) ([]*gocloak.User, error)
-	SendVerifyEmail(ctx context.Context, userID string) error
-	SendEmail(ctx context.Context, userID string) error
-	GetIDFromToken(ctx context.Context, accessToken string) (string, error)
-	Update(ctx context.Context, rq domain.RegisterUser, authID string) error
-}
-
 type Gocloak interface {
 	LoginAdmin(ctx context.Context, username string, password string, realm string) (*gocloak.JWT, error)
 	CreateUser(ctx context.Context, token string, realm string, user gocloak.User) (string, error)
 	Login(ctx context.Context, clientID string, clientSecret string, realm string, username string, password string) (*gocloak.JWT, error)
 	Logout(ctx context.Context, clientID string, clientSecret string, realm string, refreshToken string) error
+	RefreshToken(ctx context.Context, refreshToken string, clientID string, clientSecret string, realm string) (*gocloak.JWT, error)
 	GetUsers(ctx context.Context, token string, realm string, params gocloak.GetUsersParams) ([]*gocloak.User, error)
 	SendVerifyEmail(ctx context.Context, token string, userID string, realm string, params ...gocloak.SendVerificationMailParams) error
 	ExecuteActionsEmail(ctx context.Context, token string, realm string, params gocloak.ExecuteActionsEmail) error
 	GetUserInfo(ctx context.Context, accessToken string, realm string) (*gocloak.UserInfo, error)
-	DecodeAccessToken(ctx context.Context, accessToken string, realm string) (*jwt.Token, *jwt.MapClaims, error)
 	UpdateUser(ctx context.Context, token string, realm string, user gocloak.User) error
 	GetUserByID(ctx context.Context, accessToken string, realm string, userID string) (*gocloak.User, error)
 }
@@ -46,6 +29,7 @@ type KeycloakSettings struct {
 	ClientId     string
 	ClientSecret string
 	Realm        string
+	Verifier     TokenVerifier
 }
 
 type auth struct {
@@ -53,14 +37,17 @@ type auth struct {
 	clientId     string
 	clientSecret string
 	realm        string
+	verifier     TokenVerifier
 }
 
+// NewAuth returns the Keycloak implementation of Auth.
 func NewAuth(settings KeycloakSettings) Auth {
 	return &auth{
 		gocloak:      settings.GoCloak,
 		clientId:     settings.ClientId,
 		clientSecret: settings.ClientSecret,
 		realm:        settings.Realm,
+		verifier:     settings.Verifier,
 	}
 }
 
@@ -98,20 +85,12 @@ func (auth *auth) Update(ctx context.Context, rq domain.RegisterUser, authID str
 		return err
 	}
 
-	filters := domain.GetUserFilters{
-		AuthID: authID,
-	}
-	users, err := auth.GetUsersByEmail(ctx, filters)
+	user, err := auth.gocloak.GetUserByID(ctx, token, auth.realm, authID)
 	if err != nil {
 		logger.Error(err.Error())
 		return err
 	}
 
-	if len(users) == 0 {
-		return errors.New("")
-	}
-
-	user := users[0]
 	if rq.Name != "" {
 		user.FirstName = &rq.Name
 	}
@@ -132,7 +111,7 @@ func (auth *auth) Update(ctx context.Context, rq domain.RegisterUser, authID str
 	return nil
 }
 
-func (auth *auth) Login(ctx context.Context, rq domain.LoginUser) (string, error) {
+func (auth *auth) Login(ctx context.Context, rq domain.LoginUser) (domain.LoginResponse, error) {
 	jwt, err := auth.gocloak.Login(ctx, auth.clientId, auth.clientSecret, auth.realm, rq.Email, rq.Password)
 	if err != nil {
 		logger.Error(err.Error())
@@ -140,55 +119,93 @@ func (auth *auth) Login(ctx context.Context, rq domain.LoginUser) (string, error
 		if apiError, ok := err.(*gocloak.APIError); ok {
 			switch apiError.Code {
 			case http.StatusUnauthorized:
-				return "", ErrAuthInvalidUserCredentials
+				return domain.LoginResponse{}, ErrAuthInvalidUserCredentials
 			case http.StatusBadRequest:
-				return "", ErrEmailNotVerified
+				return domain.LoginResponse{}, ErrEmailNotVerified
 			default:
-				return "", errors.New("internal error")
+				return domain.LoginResponse{}, errors.New("internal error")
 			}
 		}
 
-		return "", err
+		return domain.LoginResponse{}, err
 	}
 
-	return jwt.AccessToken, nil
+	return loginResponse(jwt), nil
 }
 
-func (auth *auth) Logout(ctx context.Context, token string) error {
-	err := auth.gocloak.Logout(ctx, auth.clientId, auth.clientSecret, auth.realm, token)
+func (auth *auth) Refresh(ctx context.Context, refreshToken string) (domain.LoginResponse, error) {
+	jwt, err := auth.gocloak.RefreshToken(ctx, refreshToken, auth.clientId, auth.clientSecret, auth.realm)
 	if err != nil {
-		return err
+		logger.Error(err.Error())
+
+		return domain.LoginResponse{}, refreshError(err)
+	}
+
+	return loginResponse(jwt), nil
+}
+
+func (auth *auth) Logout(ctx context.Context, authID, refreshToken string) error {
+	// only the subject is read here, Keycloak checks the signature on logout
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(refreshToken, &claims); err != nil || claims.Subject != authID {
+		return ErrInvalidRefreshToken
+	}
+
+	err := auth.gocloak.Logout(ctx, auth.clientId, auth.clientSecret, auth.realm, refreshToken)
+	if err != nil {
+		logger.Error(err.Error())
+
+		return refreshError(err)
 	}
 
 	return nil
 }
 
+// refreshError tells apart refresh tokens Keycloak rejects, because they
+// expired, were revoked or are not tokens at all, from Keycloak failing.
+func refreshError(err error) error {
+	if apiError, ok := err.(*gocloak.APIError); ok && apiError.Code == http.StatusBadRequest {
+		return ErrInvalidRefreshToken
+	}
+
+	return err
+}
+
+func loginResponse(jwt *gocloak.JWT) domain.LoginResponse {
+	return domain.LoginResponse{
+		Token:            jwt.AccessToken,
+		ExpiresIn:        jwt.ExpiresIn,
+		RefreshToken:     jwt.RefreshToken,
+		RefreshExpiresIn: jwt.RefreshExpiresIn,
+	}
+}
+
 func (auth *auth) UserExists(ctx context.Context, email string) bool {
 	filters := domain.GetUserFilters{
 		Email: email,
 	}
-	users, _ := auth.GetUsersByEmail(ctx, filters)
+	users, _ := auth.GetUsers(ctx, filters)
 	return len(users) > 0
 }
 
 // TODO get by more params
-func (auth *auth) GetUsersByEmail(ctx context.Context, filters domain.GetUserFilters) ([]*gocloak.User, error) {
+func (auth *auth) GetUsers(ctx context.Context, filters domain.GetUserFilters) ([]domain.Identity, error) {
 	token, err := auth.loginAdmin(ctx)
 	if err != nil {
 		logger.Error(err.Error())
 
-		return []*gocloak.User{}, err
+		return []domain.Identity{}, err
 	}
 
 	params := gocloak.GetUsersParams{}
 	if filters.AuthID != "" {
-		users, err := auth.gocloak.GetUserByID(ctx, token, auth.realm, filters.AuthID)
+		user, err := auth.gocloak.GetUserByID(ctx, token, auth.realm, filters.AuthID)
 		if err != nil {
 			logger.Error(err.Error())
 
-			return []*gocloak.User{}, err
+			return []domain.Identity{}, err
 		}
-		return []*gocloak.User{users}, nil
+		return []domain.Identity{identity(user)}, nil
 	}
 
 	if filters.Email != "" {
@@ -198,10 +215,27 @@ func (auth *auth) GetUsersByEmail(ctx context.Context, filters domain.GetUserFil
 	if err != nil {
 		logger.Error(err.Error())
 
-		return []*gocloak.User{}, err
+		return []domain.Identity{}, err
 	}
 
-	return users, nil
+	identities := make([]domain.Identity, 0, len(users))
+	for _, user := range users {
+		identities = append(identities, identity(user))
+	}
+
+	return identities, nil
+}
+
+// identity keeps the gocloak pointers from leaking out of the adapter.
+func identity(user *gocloak.User) domain.Identity {
+	return domain.Identity{
+		ID:            gocloak.PString(user.ID),
+		FirstName:     gocloak.PString(user.FirstName),
+		LastName:      gocloak.PString(user.LastName),
+		Email:         gocloak.PString(user.Email),
+		Enabled:       gocloak.PBool(user.Enabled),
+		EmailVerified: gocloak.PBool(user.EmailVerified),
+	}
 }
 
 func (auth *auth) SendVerifyEmail(ctx context.Context, userID string) error {
@@ -246,19 +280,41 @@ func (auth *auth) SendEmail(ctx context.Context, userID string) error {
 	return nil
 }
 
+// VerifyEmail is done on the Keycloak pages the email links to.
+func (auth *auth) VerifyEmail(ctx context.Context, code string) error {
+	return ErrUnsupported
+}
+
+// ResetPassword is done on the Keycloak pages the email links to.
+func (auth *auth) ResetPassword(ctx context.Context, code string, password string) error {
+	return ErrUnsupported
+}
+
+// GetIDFromToken returns the subject of a valid token. Token errors are the
+// ones of the jwks package.
 func (auth *auth) GetIDFromToken(ctx context.Context, accessToken string) (string, error) {
-	token, _, err := auth.gocloak.DecodeAccessToken(ctx, accessToken, auth.realm)
+	id, err := subject(ctx, auth.verifier, accessToken)
 	if err != nil {
 		logger.Error(err.Error())
 
 		return "", err
 	}
 
-	id, ok := token.Claims.(jwt.MapClaims)["sub"].(string)
-	logger.Any(id, ok)
 	return id, nil
 }
 
+// HasRole reports whether the token grants the realm role.
+func (auth *auth) HasRole(ctx context.Context, accessToken string, role string) (bool, error) {
+	ok, err := hasRole(ctx, auth.verifier, accessToken, role)
+	if err != nil {
+		logger.Error(err.Error())
+
+		return false, err
+	}
+
+	return ok, nil
+}
+
 func (auth *auth) loginAdmin(ctx context.Context) (string, error) {
 	token, err := auth.gocloak.LoginAdmin(ctx, "admin1", "admin1", "realm-test")
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/Nerzal/gocloak/v12"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -35,6 +36,11 @@ func (g *GocloakMock) Logout(ctx context.Context, clientID string, clientSecret
 	return args.Error(0)
 }
 
+func (g *GocloakMock) RefreshToken(ctx context.Context, refreshToken string, clientID string, clientSecret string, realm string) (*gocloak.JWT, error) {
+	args := g.Called(refreshToken, clientID, clientSecret, realm)
+	return args.Get(0).(*gocloak.JWT), args.Error(1)
+}
+
 func (g *GocloakMock) GetUsers(ctx context.Context, token string, realm string, params gocloak.GetUsersParams) ([]*gocloak.User, error) {
 	args := g.Called(token, realm, params)
 	return args.Get(0).([]*gocloak.User), args.Error(1)
@@ -111,7 +117,7 @@ func TestLogin(t *testing.T) {
 		name          string
 		rq            domain.LoginUser
 		auth          Auth
-		tokenResponse string
+		tokenResponse domain.LoginResponse
 		wantError     bool
 	}{
 		{
@@ -124,7 +130,7 @@ func TestLogin(t *testing.T) {
 				gMock := new(GocloakMock)
 
 				gMock.On("Login", "clientID", "clientSecret", "realm-test", "email@c.com", "password").
-					Return(&gocloak.JWT{RefreshToken: "accessToken"}, nil)
+					Return(&gocloak.JWT{AccessToken: "accessToken", RefreshToken: "refreshToken"}, nil)
 
 				keycloakSettings := KeycloakSettings{
 					GoCloak:      gMock,
@@ -135,7 +141,7 @@ func TestLogin(t *testing.T) {
 				auth := NewAuth(keycloakSettings)
 				return auth
 			}(),
-			tokenResponse: "accessToken",
+			tokenResponse: domain.LoginResponse{Token: "accessToken", RefreshToken: "refreshToken"},
 			wantError:     false,
 		},
 	}
@@ -155,6 +161,21 @@ func TestLogin(t *testing.T) {
 }
 
 func TestLogout(t *testing.T) {
+	refreshToken := func(subject string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: subject}).SignedString([]byte("realm-secret"))
+		require.NoError(t, err)
+		return token
+	}
+	newAuth := func(gMock *GocloakMock) Auth {
+		keycloakSettings := KeycloakSettings{
+			GoCloak:      gMock,
+			ClientId:     "clientID",
+			ClientSecret: "clientSecret",
+			Realm:        "realm-test",
+		}
+		return NewAuth(keycloakSettings)
+	}
+
 	var tests = []struct {
 		name      string
 		token     string
@@ -163,24 +184,29 @@ func TestLogout(t *testing.T) {
 	}{
 		{
 			name:  "logout - ok",
-			token: "token",
+			token: refreshToken("user-1"),
 			auth: func() Auth {
 				gMock := new(GocloakMock)
 
-				gMock.On("Logout", "clientID", "clientSecret", "realm-test", "token").
+				gMock.On("Logout", "clientID", "clientSecret", "realm-test", refreshToken("user-1")).
 					Return(nil)
 
-				keycloakSettings := KeycloakSettings{
-					GoCloak:      gMock,
-					ClientId:     "clientID",
-					ClientSecret: "clientSecret",
-					Realm:        "realm-test",
-				}
-				auth := NewAuth(keycloakSettings)
-				return auth
+				return newAuth(gMock)
 			}(),
 			wantError: false,
 		},
+		{
+			name:      "logout - refresh token of another user",
+			token:     refreshToken("user-2"),
+			auth:      newAuth(new(GocloakMock)),
+			wantError: true,
+		},
+		{
+			name:      "logout - not a token",
+			token:     "token",
+			auth:      newAuth(new(GocloakMock)),
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -188,23 +214,23 @@ func TestLogout(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := tt.auth.Logout(context.Background(), tt.token)
+			err := tt.auth.Logout(context.Background(), "user-1", tt.token)
 
 			require.True(t, tt.wantError == (err != nil))
 		})
 	}
 }
 
-func TestGetUsersByEmail(t *testing.T) {
+func TestGetUsers(t *testing.T) {
 	var tests = []struct {
 		name      string
 		email     string
 		auth      Auth
-		usersOut  []*gocloak.User
+		usersOut  []domain.Identity
 		wantError bool
 	}{
 		{
-			name:  "GetUsersByEmail - ok",
+			name:  "GetUsers - ok",
 			email: "email@c.com",
 			auth: func() Auth {
 				gMock := new(GocloakMock)
@@ -214,7 +240,10 @@ func TestGetUsersByEmail(t *testing.T) {
 				params := gocloak.GetUsersParams{Email: gocloak.StringP("email@c.com")}
 				user := []*gocloak.User{
 					{
-						ID: gocloak.StringP("id1"),
+						ID:        gocloak.StringP("id1"),
+						FirstName: gocloak.StringP("name"),
+						Email:     gocloak.StringP("email@c.com"),
+						Enabled:   gocloak.BoolP(true),
 					},
 				}
 				gMock.On("GetUsers", "accessToken", "realm-test", params).
@@ -227,9 +256,12 @@ func TestGetUsersByEmail(t *testing.T) {
 				auth := NewAuth(keycloakSettings)
 				return auth
 			}(),
-			usersOut: []*gocloak.User{
+			usersOut: []domain.Identity{
 				{
-					ID: gocloak.StringP("id1"),
+					ID:        "id1",
+					FirstName: "name",
+					Email:     "email@c.com",
+					Enabled:   true,
 				},
 			},
 			wantError: false,
@@ -241,7 +273,7 @@ func TestGetUsersByEmail(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			users, err := tt.auth.GetUsersByEmail(context.Background(), tt.email)
+			users, err := tt.auth.GetUsers(context.Background(), domain.GetUserFilters{Email: tt.email})
 			if tt.wantError {
 				require.Error(t, err)
 			}
@@ -0,0 +1,465 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/pkg/jwks"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MemoryIssuer   = "memory"
+	memoryAudience = "account"
+	memoryKeyID    = "memory"
+
+	// Email actions, as given to the Notifier.
+	ActionVerifyEmail   = "verify_email"
+	ActionResetPassword = "reset_password"
+
+	defaultAccessTokenTTL  = 5 * time.Minute
+	defaultRefreshTokenTTL = 30 * time.Minute
+	defaultCodeTTL         = 24 * time.Hour
+)
+
+// hashCost is lowered by the tests, bcrypt is slow on purpose.
+var hashCost = bcrypt.DefaultCost
+
+// Notifier delivers the code of an email action to the user.
+type Notifier func(email, action, code string)
+
+type MemorySettings struct {
+	// Notifier defaults to logging the codes.
+	Notifier Notifier
+	// The TTLs default to the ones of a new Keycloak realm.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	CodeTTL         time.Duration
+	// Roles are the realm roles given to the user that registers with the
+	// email, since there is no admin console to grant them.
+	Roles map[string][]string
+}
+
+// Memory is an identity provider that keeps users, sessions and email codes in
+// memory, so the API can run and be tested without Keycloak. Everything is
+// lost on restart. Its access tokens are JWTs signed with a key generated on
+// start, checked the same way as the Keycloak ones.
+type Memory struct {
+	notify          Notifier
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	codeTTL         time.Duration
+	roles           map[string][]string
+	key             *rsa.PrivateKey
+	verifier        TokenVerifier
+	now             func() time.Time
+
+	mu       sync.Mutex
+	users    map[string]*memoryUser
+	sessions map[string]memorySession
+	codes    map[string]memoryCode
+}
+
+type memoryUser struct {
+	identity     domain.Identity
+	passwordHash []byte
+	roles        []string
+}
+
+type memorySession struct {
+	userID  string
+	expires time.Time
+}
+
+type memoryCode struct {
+	userID  string
+	action  string
+	expires time.Time
+}
+
+func NewMemory(settings MemorySettings) (*Memory, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Memory{
+		notify:          settings.Notifier,
+		accessTokenTTL:  settings.AccessTokenTTL,
+		refreshTokenTTL: settings.RefreshTokenTTL,
+		codeTTL:         settings.CodeTTL,
+		roles:           make(map[string][]string),
+		key:             key,
+		now:             time.Now,
+		users:           make(map[string]*memoryUser),
+		sessions:        make(map[string]memorySession),
+		codes:           make(map[string]memoryCode),
+	}
+	m.verifier = jwks.NewVerifier(jwks.VerifierSettings{
+		Keys:     jwks.StaticKeys{memoryKeyID: &key.PublicKey},
+		Issuer:   MemoryIssuer,
+		Audience: memoryAudience,
+		Now:      func() time.Time { return m.now() },
+	})
+	if m.notify == nil {
+		m.notify = logNotifier
+	}
+	if m.accessTokenTTL == 0 {
+		m.accessTokenTTL = defaultAccessTokenTTL
+	}
+	if m.refreshTokenTTL == 0 {
+		m.refreshTokenTTL = defaultRefreshTokenTTL
+	}
+	if m.codeTTL == 0 {
+		m.codeTTL = defaultCodeTTL
+	}
+	for email, roles := range settings.Roles {
+		email = normalizeEmail(email)
+		m.roles[email] = append(m.roles[email], roles...)
+	}
+
+	return m, nil
+}
+
+func logNotifier(email, action, code string) {
+	logger.Info("identity email", zap.String("email", email), zap.String("action", action), zap.String("code", code))
+}
+
+func (m *Memory) Register(ctx context.Context, rq domain.RegisterUser) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(rq.Password), hashCost)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.findByEmail(rq.Email) != nil {
+		return "", ErrUserAlreadyExists
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	m.users[id] = &memoryUser{
+		identity: domain.Identity{
+			ID:        id,
+			FirstName: rq.Name,
+			LastName:  rq.LastName,
+			Email:     normalizeEmail(rq.Email),
+			Enabled:   true,
+		},
+		passwordHash: hash,
+		roles:        append([]string(nil), m.roles[normalizeEmail(rq.Email)]...),
+	}
+
+	return id, nil
+}
+
+func (m *Memory) Login(ctx context.Context, rq domain.LoginUser) (domain.LoginResponse, error) {
+	m.mu.Lock()
+	user := m.findByEmail(rq.Email)
+	if user == nil || !user.identity.Enabled {
+		m.mu.Unlock()
+		return domain.LoginResponse{}, ErrAuthInvalidUserCredentials
+	}
+	userID, passwordHash := user.identity.ID, user.passwordHash
+	m.mu.Unlock()
+
+	// bcrypt is slow on purpose, other calls must not wait for it
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(rq.Password)); err != nil {
+		return domain.LoginResponse{}, ErrAuthInvalidUserCredentials
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the user may have changed while the password was compared
+	user, ok := m.users[userID]
+	if !ok || !user.identity.Enabled || !bytes.Equal(user.passwordHash, passwordHash) {
+		return domain.LoginResponse{}, ErrAuthInvalidUserCredentials
+	}
+
+	// only told once the password is right, like Keycloak does
+	if !user.identity.EmailVerified {
+		return domain.LoginResponse{}, ErrEmailNotVerified
+	}
+
+	return m.newSession(user)
+}
+
+// Refresh rotates the refresh token: the one given cannot be used again.
+func (m *Memory) Refresh(ctx context.Context, refreshToken string) (domain.LoginResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[refreshToken]
+	if !ok || !m.now().Before(session.expires) {
+		return domain.LoginResponse{}, ErrInvalidRefreshToken
+	}
+	delete(m.sessions, refreshToken)
+
+	user, ok := m.users[session.userID]
+	if !ok || !user.identity.Enabled {
+		return domain.LoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	return m.newSession(user)
+}
+
+func (m *Memory) Logout(ctx context.Context, authID, refreshToken string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[refreshToken]; !ok || session.userID != authID {
+		return ErrInvalidRefreshToken
+	}
+	delete(m.sessions, refreshToken)
+
+	return nil
+}
+
+func (m *Memory) UserExists(ctx context.Context, email string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.findByEmail(email) != nil
+}
+
+func (m *Memory) GetUsers(ctx context.Context, filters domain.GetUserFilters) ([]domain.Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if filters.AuthID != "" {
+		user, ok := m.users[filters.AuthID]
+		if !ok {
+			return []domain.Identity{}, ErrUserNotFound
+		}
+		return []domain.Identity{user.identity}, nil
+	}
+
+	identities := []domain.Identity{}
+	for _, user := range m.users {
+		if filters.Email == "" || user.identity.Email == normalizeEmail(filters.Email) {
+			identities = append(identities, user.identity)
+		}
+	}
+
+	return identities, nil
+}
+
+func (m *Memory) SendVerifyEmail(ctx context.Context, userID string) error {
+	return m.sendCode(userID, ActionVerifyEmail)
+}
+
+func (m *Memory) SendEmail(ctx context.Context, userID string) error {
+	return m.sendCode(userID, ActionResetPassword)
+}
+
+func (m *Memory) VerifyEmail(ctx context.Context, code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.useCode(code, ActionVerifyEmail)
+	if err != nil {
+		return err
+	}
+	user.identity.EmailVerified = true
+
+	return nil
+}
+
+// ResetPassword also ends every session of the user.
+func (m *Memory) ResetPassword(ctx context.Context, code string, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), hashCost)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.useCode(code, ActionResetPassword)
+	if err != nil {
+		return err
+	}
+	user.passwordHash = hash
+
+	for token, session := range m.sessions {
+		if session.userID == user.identity.ID {
+			delete(m.sessions, token)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) GetIDFromToken(ctx context.Context, accessToken string) (string, error) {
+	return subject(ctx, m.verifier, accessToken)
+}
+
+func (m *Memory) HasRole(ctx context.Context, accessToken string, role string) (bool, error) {
+	return hasRole(ctx, m.verifier, accessToken, role)
+}
+
+func (m *Memory) Update(ctx context.Context, rq domain.RegisterUser, authID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[authID]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if rq.Name != "" {
+		user.identity.FirstName = rq.Name
+	}
+	if rq.LastName != "" {
+		user.identity.LastName = rq.LastName
+	}
+	if rq.Email != "" {
+		if other := m.findByEmail(rq.Email); other != nil && other != user {
+			return ErrUserAlreadyExists
+		}
+		user.identity.Email = normalizeEmail(rq.Email)
+	}
+
+	return nil
+}
+
+// GrantRole gives the user a realm role, there is no admin console to do it.
+// It shows up in the tokens issued from then on.
+func (m *Memory) GrantRole(userID string, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	for _, r := range user.roles {
+		if r == role {
+			return nil
+		}
+	}
+	user.roles = append(user.roles, role)
+
+	return nil
+}
+
+// newSession issues the tokens of a new session. The caller holds the lock.
+func (m *Memory) newSession(user *memoryUser) (domain.LoginResponse, error) {
+	now := m.now()
+
+	roles := make([]interface{}, 0, len(user.roles))
+	for _, role := range user.roles {
+		roles = append(roles, role)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub":          user.identity.ID,
+		"iss":          MemoryIssuer,
+		"aud":          memoryAudience,
+		"iat":          now.Unix(),
+		"exp":          now.Add(m.accessTokenTTL).Unix(),
+		"email":        user.identity.Email,
+		"realm_access": map[string]interface{}{"roles": roles},
+	})
+	token.Header["kid"] = memoryKeyID
+
+	accessToken, err := token.SignedString(m.key)
+	if err != nil {
+		return domain.LoginResponse{}, err
+	}
+
+	refreshToken, err := randomID()
+	if err != nil {
+		return domain.LoginResponse{}, err
+	}
+	m.sessions[refreshToken] = memorySession{userID: user.identity.ID, expires: now.Add(m.refreshTokenTTL)}
+
+	return domain.LoginResponse{
+		Token:            accessToken,
+		ExpiresIn:        int(m.accessTokenTTL.Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int(m.refreshTokenTTL.Seconds()),
+	}, nil
+}
+
+func (m *Memory) sendCode(userID string, action string) error {
+	m.mu.Lock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		m.mu.Unlock()
+		return ErrUserNotFound
+	}
+
+	code, err := randomID()
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.codes[code] = memoryCode{userID: userID, action: action, expires: m.now().Add(m.codeTTL)}
+	email := user.identity.Email
+
+	// the notifier may be slow, do not hold everyone else meanwhile
+	m.mu.Unlock()
+	m.notify(email, action, code)
+
+	return nil
+}
+
+// useCode consumes the code of the action. The caller holds the lock.
+func (m *Memory) useCode(code string, action string) (*memoryUser, error) {
+	c, ok := m.codes[code]
+	if !ok || c.action != action {
+		return nil, ErrInvalidCode
+	}
+	delete(m.codes, code)
+
+	user, ok := m.users[c.userID]
+	if !ok || !m.now().Before(c.expires) {
+		return nil, ErrInvalidCode
+	}
+
+	return user, nil
+}
+
+// findByEmail returns nil if there is no such user. The caller holds the lock.
+func (m *Memory) findByEmail(email string) *memoryUser {
+	email = normalizeEmail(email)
+	for _, user := range m.users {
+		if user.identity.Email == email {
+			return user
+		}
+	}
+
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/pkg/jwks"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestMemory(t *testing.T) (*Memory, map[string]string) {
+	hashCost = bcrypt.MinCost
+
+	codes := make(map[string]string)
+	memory, err := NewMemory(MemorySettings{
+		Notifier: func(email, action, code string) { codes[action] = code },
+	})
+	require.NoError(t, err)
+
+	return memory, codes
+}
+
+func TestMemorySession(t *testing.T) {
+	ctx := context.Background()
+	memory, codes := newTestMemory(t)
+
+	id, err := memory.Register(ctx, domain.RegisterUser{Name: "Ana", LastName: "Diaz", Email: "Ana@mail.com", Password: "secret"})
+	require.NoError(t, err)
+	assert.True(t, memory.UserExists(ctx, "ana@mail.com"))
+
+	_, err = memory.Register(ctx, domain.RegisterUser{Email: "ana@mail.com", Password: "other"})
+	assert.Equal(t, ErrUserAlreadyExists, err)
+
+	_, err = memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "wrong"})
+	assert.Equal(t, ErrAuthInvalidUserCredentials, err)
+	_, err = memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "secret"})
+	assert.Equal(t, ErrEmailNotVerified, err)
+
+	require.NoError(t, memory.SendVerifyEmail(ctx, id))
+	assert.Equal(t, ErrInvalidCode, memory.VerifyEmail(ctx, "made-up"))
+	require.NoError(t, memory.VerifyEmail(ctx, codes[ActionVerifyEmail]))
+	// codes are single use
+	assert.Equal(t, ErrInvalidCode, memory.VerifyEmail(ctx, codes[ActionVerifyEmail]))
+
+	session, err := memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, 300, session.ExpiresIn)
+
+	authID, err := memory.GetIDFromToken(ctx, "Bearer "+session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, id, authID)
+
+	isAdmin, err := memory.HasRole(ctx, session.Token, "admin")
+	assert.NoError(t, err)
+	assert.False(t, isAdmin)
+
+	require.NoError(t, memory.GrantRole(id, "admin"))
+	renewed, err := memory.Refresh(ctx, session.RefreshToken)
+	require.NoError(t, err)
+	isAdmin, err = memory.HasRole(ctx, renewed.Token, "admin")
+	assert.NoError(t, err)
+	assert.True(t, isAdmin)
+
+	// refresh tokens are rotated
+	_, err = memory.Refresh(ctx, session.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	// only the owner of a session can end it
+	assert.Equal(t, ErrInvalidRefreshToken, memory.Logout(ctx, "someone-else", renewed.RefreshToken))
+	require.NoError(t, memory.Logout(ctx, id, renewed.RefreshToken))
+	_, err = memory.Refresh(ctx, renewed.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	memory, codes := newTestMemory(t)
+	now := time.Now()
+	memory.now = func() time.Time { return now }
+
+	id, err := memory.Register(ctx, domain.RegisterUser{Email: "ana@mail.com", Password: "secret"})
+	require.NoError(t, err)
+	require.NoError(t, memory.SendVerifyEmail(ctx, id))
+	require.NoError(t, memory.VerifyEmail(ctx, codes[ActionVerifyEmail]))
+
+	session, err := memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "secret"})
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = memory.GetIDFromToken(ctx, session.Token)
+	assert.ErrorIs(t, err, jwks.ErrTokenExpired)
+	_, err = memory.Refresh(ctx, session.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	require.NoError(t, memory.SendEmail(ctx, id))
+	now = now.Add(defaultCodeTTL)
+	assert.Equal(t, ErrInvalidCode, memory.ResetPassword(ctx, codes[ActionResetPassword], "new"))
+}
+
+func TestMemoryResetPassword(t *testing.T) {
+	ctx := context.Background()
+	memory, codes := newTestMemory(t)
+
+	id, err := memory.Register(ctx, domain.RegisterUser{Email: "ana@mail.com", Password: "secret"})
+	require.NoError(t, err)
+	require.NoError(t, memory.SendVerifyEmail(ctx, id))
+	require.NoError(t, memory.VerifyEmail(ctx, codes[ActionVerifyEmail]))
+	session, err := memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "secret"})
+	require.NoError(t, err)
+
+	require.NoError(t, memory.SendEmail(ctx, id))
+	// a verify email code does not reset passwords
+	assert.Equal(t, ErrInvalidCode, memory.ResetPassword(ctx, codes[ActionVerifyEmail], "new"))
+	require.NoError(t, memory.ResetPassword(ctx, codes[ActionResetPassword], "new"))
+
+	_, err = memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "secret"})
+	assert.Equal(t, ErrAuthInvalidUserCredentials, err)
+	_, err = memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "new"})
+	assert.NoError(t, err)
+
+	// the sessions from before the reset are over
+	_, err = memory.Refresh(ctx, session.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func TestMemorySeededRoles(t *testing.T) {
+	hashCost = bcrypt.MinCost
+	ctx := context.Background()
+	codes := make(map[string]string)
+	memory, err := NewMemory(MemorySettings{
+		Notifier: func(email, action, code string) { codes[action] = code },
+		Roles:    map[string][]string{" Admin@Mail.com": {"admin", "operator"}},
+	})
+	require.NoError(t, err)
+
+	for _, email := range []string{"admin@mail.com", "ana@mail.com"} {
+		id, err := memory.Register(ctx, domain.RegisterUser{Email: email, Password: "secret"})
+		require.NoError(t, err)
+		require.NoError(t, memory.SendVerifyEmail(ctx, id))
+		require.NoError(t, memory.VerifyEmail(ctx, codes[ActionVerifyEmail]))
+	}
+
+	admin, err := memory.Login(ctx, domain.LoginUser{Email: "admin@mail.com", Password: "secret"})
+	require.NoError(t, err)
+	isAdmin, err := memory.HasRole(ctx, admin.Token, "admin")
+	assert.NoError(t, err)
+	assert.True(t, isAdmin)
+	isOperator, err := memory.HasRole(ctx, admin.Token, "operator")
+	assert.NoError(t, err)
+	assert.True(t, isOperator)
+
+	user, err := memory.Login(ctx, domain.LoginUser{Email: "ana@mail.com", Password: "secret"})
+	require.NoError(t, err)
+	isAdmin, err = memory.HasRole(ctx, user.Token, "admin")
+	assert.NoError(t, err)
+	assert.False(t, isAdmin)
+}
@@ -3,17 +3,45 @@ package cards
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
 )
 
+const cardColumns = "id, account_id, last_four, holder_name, expiration_date, type, brand, nickname, is_default, verification_status, token"
+
+// Cards are soft deleted, so the deposits made with them keep their history.
+// Every query skips deleted cards. A card can only be active in one account:
+// its token is unique among the cards that are not deleted.
+
+// mysqlDuplicateEntry is the MySQL error number for unique index violations.
+const mysqlDuplicateEntry = 1062
+
 type Repository interface {
-	SaveCard(ctx context.Context, id int, card domain.CardDto) (int, error)
+	SaveCard(ctx context.Context, card domain.Card) (int, error)
 	GetAll(ctx context.Context, accountID int) ([]domain.Card, error)
 	GetByID(ctx context.Context, accountID, cardID int) (domain.Card, error)
-	Exists(ctx context.Context, pan string) (bool, error)
-	DeleteByCardID(ctx context.Context, cardID int) error
+	GetDefault(ctx context.Context, accountID int) (domain.Card, error)
+	Exists(ctx context.Context, token string) (bool, error)
+	Update(ctx context.Context, card domain.Card) error
+	DeleteByCardID(ctx context.Context, accountID, cardID int) error
+	GetVerification(ctx context.Context, cardID int) (domain.CardVerification, error)
+	// SaveVerification stores the verification and sets its status on the card.
+	// A card keeps the attempts of its earlier verifications.
+	SaveVerification(ctx context.Context, accountID int, verification domain.CardVerification) error
+	// Attempt locks the verification of the card while attempt settles it, and
+	// stores its attempts and status, so concurrent attempts are counted one
+	// after the other.
+	Attempt(ctx context.Context, accountID, cardID int, attempt AttemptFunc) (domain.CardVerification, error)
 }
 
+// AttemptFunc settles one attempt at a card verification, whose status is the
+// one of the card, and returns it with its attempts and status updated.
+type AttemptFunc func(verification domain.CardVerification) domain.CardVerification
+
 type repository struct {
 	db *sql.DB
 }
@@ -22,15 +50,20 @@ func NewRepository(db *sql.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) SaveCard(ctx context.Context, id int, card domain.CardDto) (int, error) {
-	query := "INSERT INTO cards (account_id, pan, holder_name, expiration_date, cid, type) VALUES (?, ?, ?, ?, ?, ?);"
+func (r *repository) SaveCard(ctx context.Context, card domain.Card) (int, error) {
+	query := "INSERT INTO cards (account_id, last_four, holder_name, expiration_date, type, brand, verification_status, token) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?);"
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := stmt.Exec(&id, &card.PAN, &card.HolderName, &card.ExpirationDate, &card.CID, &card.Type)
+	res, err := stmt.Exec(&card.AccountID, &card.LastFour, &card.HolderName, &card.ExpirationDate, &card.Type, &card.Brand, &card.VerificationStatus, &card.Token)
 	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return 0, ErrCardAlreadyAssociated
+		}
 		return 0, err
 	}
 
@@ -43,7 +76,7 @@ func (r *repository) SaveCard(ctx context.Context, id int, card domain.CardDto)
 }
 
 func (r *repository) GetAll(ctx context.Context, accountID int) ([]domain.Card, error) {
-	query := "SELECT * FROM cards WHERE account_id = ?;"
+	query := "SELECT " + cardColumns + " FROM cards WHERE account_id = ? AND deleted_at IS NULL;"
 	rows, err := r.db.Query(query, accountID)
 	if err != nil {
 		return []domain.Card{}, err
@@ -52,8 +85,7 @@ func (r *repository) GetAll(ctx context.Context, accountID int) ([]domain.Card,
 	var cards []domain.Card
 
 	for rows.Next() {
-		card := domain.Card{}
-		err = rows.Scan(&card.ID, &card.AccountID, &card.PAN, &card.HolderName, &card.ExpirationDate, &card.CID, &card.Type)
+		card, err := scanCard(rows)
 		if err != nil {
 			return []domain.Card{}, err
 		}
@@ -65,15 +97,24 @@ func (r *repository) GetAll(ctx context.Context, accountID int) ([]domain.Card,
 }
 
 func (r *repository) GetByID(ctx context.Context, accountID, cardID int) (domain.Card, error) {
-	query := "SELECT * FROM cards WHERE id = ? and account_id = ?;"
-	rows := r.db.QueryRow(query, cardID, accountID)
+	query := "SELECT " + cardColumns + " FROM cards WHERE id = ? and account_id = ? AND deleted_at IS NULL;"
+	card, err := scanCard(r.db.QueryRow(query, cardID, accountID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Card{}, ErrCardNotFound
+		}
+		return domain.Card{}, err
+	}
 
-	var card domain.Card
+	return card, nil
+}
 
-	err := rows.Scan(&card.ID, &card.AccountID, &card.PAN, &card.HolderName, &card.ExpirationDate, &card.CID, &card.Type)
+func (r *repository) GetDefault(ctx context.Context, accountID int) (domain.Card, error) {
+	query := "SELECT " + cardColumns + " FROM cards WHERE account_id = ? AND is_default AND deleted_at IS NULL;"
+	card, err := scanCard(r.db.QueryRowContext(ctx, query, accountID))
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return domain.Card{}, ErrCardNotFound
+		if err == sql.ErrNoRows {
+			return domain.Card{}, ErrNoDefaultCard
 		}
 		return domain.Card{}, err
 	}
@@ -81,9 +122,9 @@ func (r *repository) GetByID(ctx context.Context, accountID, cardID int) (domain
 	return card, nil
 }
 
-func (r *repository) Exists(ctx context.Context, pan string) (bool, error) {
-	query := "SELECT id FROM cards WHERE pan = ?;"
-	rows := r.db.QueryRow(query, pan)
+func (r *repository) Exists(ctx context.Context, token string) (bool, error) {
+	query := "SELECT id FROM cards WHERE token = ? AND deleted_at IS NULL;"
+	rows := r.db.QueryRow(query, token)
 
 	var id int
 
@@ -99,14 +140,56 @@ func (r *repository) Exists(ctx context.Context, pan string) (bool, error) {
 	return id != 0, nil
 }
 
-func (r *repository) DeleteByCardID(ctx context.Context, cardID int) error {
-	query := "DELETE FROM cards WHERE id=?;"
+// Update saves the holder name, nickname and default flag of the card. Making
+// it the default takes the flag from the other cards of the account.
+func (r *repository) Update(ctx context.Context, card domain.Card) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if card.Default {
+		query := "UPDATE cards SET is_default = FALSE WHERE account_id = ? AND id <> ?;"
+		if _, err = tx.ExecContext(ctx, query, card.AccountID, card.ID); err != nil {
+			return err
+		}
+	}
+
+	query := "UPDATE cards SET holder_name = ?, nickname = ?, is_default = ? WHERE id = ? AND account_id = ? AND deleted_at IS NULL;"
+	res, err := tx.ExecContext(ctx, query, card.HolderName, card.Nickname, card.Default, card.ID, card.AccountID)
+	if err != nil {
+		return err
+	}
+
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// MySQL does not count rows left as they were, so check the card is there
+	if affect < 1 {
+		var id int
+		query = "SELECT id FROM cards WHERE id = ? AND account_id = ? AND deleted_at IS NULL;"
+		if err = tx.QueryRowContext(ctx, query, card.ID, card.AccountID).Scan(&id); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrCardNotFound
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) DeleteByCardID(ctx context.Context, accountID, cardID int) error {
+	query := "UPDATE cards SET deleted_at = ?, is_default = FALSE WHERE id = ? AND account_id = ? AND deleted_at IS NULL;"
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(cardID)
+	res, err := stmt.Exec(time.Now().UTC(), cardID, accountID)
 	if err != nil {
 		return err
 	}
@@ -122,3 +205,99 @@ func (r *repository) DeleteByCardID(ctx context.Context, cardID int) error {
 
 	return nil
 }
+
+func (r *repository) GetVerification(ctx context.Context, cardID int) (domain.CardVerification, error) {
+	query := "SELECT card_id, first_amount, second_amount, first_reference, second_reference, attempts, created_at " +
+		"FROM card_verifications WHERE card_id = ?;"
+
+	verification := domain.CardVerification{Amounts: make([]decimal.Decimal, 2), References: make([]string, 2)}
+	err := r.db.QueryRowContext(ctx, query, cardID).Scan(&verification.CardID, &verification.Amounts[0], &verification.Amounts[1],
+		&verification.References[0], &verification.References[1], &verification.Attempts, &verification.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.CardVerification{}, ErrVerificationNotStarted
+		}
+		return domain.CardVerification{}, err
+	}
+
+	return verification, nil
+}
+
+func (r *repository) SaveVerification(ctx context.Context, accountID int, verification domain.CardVerification) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO card_verifications(card_id, first_amount, second_amount, first_reference, second_reference, attempts, created_at) " +
+		"VALUES(?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE first_amount = VALUES(first_amount), second_amount = VALUES(second_amount), " +
+		"first_reference = VALUES(first_reference), second_reference = VALUES(second_reference), created_at = VALUES(created_at);"
+	_, err = tx.ExecContext(ctx, query, verification.CardID, verification.Amounts[0], verification.Amounts[1],
+		verification.References[0], verification.References[1], verification.Attempts, verification.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = "UPDATE cards SET verification_status = ? WHERE id = ? AND account_id = ?;"
+	if _, err = tx.ExecContext(ctx, query, verification.Status, verification.CardID, accountID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) Attempt(ctx context.Context, accountID, cardID int, attempt AttemptFunc) (domain.CardVerification, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.CardVerification{}, err
+	}
+	defer tx.Rollback()
+
+	query := "SELECT v.card_id, v.first_amount, v.second_amount, v.first_reference, v.second_reference, v.attempts, v.created_at, " +
+		"c.verification_status FROM card_verifications v JOIN cards c ON c.id = v.card_id " +
+		"WHERE v.card_id = ? AND c.account_id = ? AND c.deleted_at IS NULL FOR UPDATE;"
+
+	verification := domain.CardVerification{Amounts: make([]decimal.Decimal, 2), References: make([]string, 2)}
+	err = tx.QueryRowContext(ctx, query, cardID, accountID).Scan(&verification.CardID, &verification.Amounts[0], &verification.Amounts[1],
+		&verification.References[0], &verification.References[1], &verification.Attempts, &verification.CreatedAt, &verification.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.CardVerification{}, ErrVerificationNotStarted
+		}
+		return domain.CardVerification{}, err
+	}
+
+	verification = attempt(verification)
+
+	query = "UPDATE card_verifications SET attempts = ? WHERE card_id = ?;"
+	if _, err = tx.ExecContext(ctx, query, verification.Attempts, cardID); err != nil {
+		return domain.CardVerification{}, err
+	}
+
+	query = "UPDATE cards SET verification_status = ? WHERE id = ? AND account_id = ?;"
+	if _, err = tx.ExecContext(ctx, query, verification.Status, cardID, accountID); err != nil {
+		return domain.CardVerification{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.CardVerification{}, err
+	}
+
+	return verification, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCard(row scanner) (domain.Card, error) {
+	var card domain.Card
+	err := row.Scan(&card.ID, &card.AccountID, &card.LastFour, &card.HolderName, &card.ExpirationDate, &card.Type, &card.Brand,
+		&card.Nickname, &card.Default, &card.VerificationStatus, &card.Token)
+	if err != nil {
+		return domain.Card{}, err
+	}
+
+	return card, nil
+}
@@ -2,10 +2,13 @@ package cards
 
 import (
 	"context"
+	"database/sql"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
 )
@@ -15,42 +18,74 @@ func TestRepositorySaveSuccesfully(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
+	card := domain.Card{
+		AccountID:      1,
+		LastFour:       "3704",
+		HolderName:     "Marco Suarez",
+		ExpirationDate: "2022-12-31",
+		Type:           TypeCredit,
+		Brand:          BrandVisa,
+		Token:          "tok_1",
+
+		VerificationStatus: domain.CardVerificationPending,
+	}
+
 	mock.ExpectPrepare("INSERT INTO cards ")
-	mock.ExpectExec("INSERT INTO cards").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO cards").
+		WithArgs(1, "3704", card.HolderName, card.ExpirationDate, card.Type, card.Brand, domain.CardVerificationPending, card.Token).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	repo := NewRepository(db)
-	card := domain.CardDto{
-		PAN:            "8543345689903456",
-		HolderName:     "Marco Suarez",
-		ExpirationDate: "2022-12-10",
-		CID:            "567",
-	}
 
-	us, err := repo.SaveCard(context.Background(), 1, card)
+	us, err := repo.SaveCard(context.Background(), card)
 	assert.NoError(t, err)
 	assert.NotZero(t, us)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestRepositorySaveActiveToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	card := domain.Card{AccountID: 1, LastFour: "3704", Token: "tok_1", VerificationStatus: domain.CardVerificationPending}
+
+	mock.ExpectPrepare("INSERT INTO cards ")
+	mock.ExpectExec("INSERT INTO cards").
+		WillReturnError(&mysql.MySQLError{Number: mysqlDuplicateEntry, Message: "Duplicate entry 'tok_1' for key 'cards_active_token_idx'"})
+
+	repo := NewRepository(db)
+
+	_, err = repo.SaveCard(context.Background(), card)
+	assert.Equal(t, ErrCardAlreadyAssociated, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRepositoryGetAllSuccesfully(t *testing.T) {
 	input := []domain.Card{
 		{
 			ID:             1,
 			AccountID:      1,
-			PAN:            "123456",
+			LastFour:       "3456",
 			HolderName:     "Miguel",
-			ExpirationDate: "2022-10-10",
-			CID:            "123",
-			Type:           "Visa",
+			ExpirationDate: "2022-10-31",
+			Type:           TypeCredit,
+			Brand:          BrandVisa,
+			Token:          "tok_1",
+
+			VerificationStatus: domain.CardVerificationVerified,
 		},
 		{
 			ID:             2,
 			AccountID:      1,
-			PAN:            "987643",
+			LastFour:       "7643",
 			HolderName:     "Marcos",
-			ExpirationDate: "2022-02-10",
-			CID:            "456",
-			Type:           "MasterCard",
+			ExpirationDate: "2022-02-28",
+			Type:           TypeDebit,
+			Brand:          BrandMastercard,
+			Nickname:       "Sueldo",
+			Default:        true,
+			Token:          "tok_2",
 		},
 	}
 
@@ -59,16 +94,18 @@ func TestRepositoryGetAllSuccesfully(t *testing.T) {
 	defer db.Close()
 	ctx := context.TODO()
 
-	columns := []string{"card_id", "account_id", "pan", "holder_name", "expiration_date", "cvv", "type"}
+	columns := []string{"id", "account_id", "last_four", "holder_name", "expiration_date", "type", "brand", "nickname", "is_default", "verification_status", "token"}
 	rows := sqlmock.NewRows(columns)
-	rows.AddRow(input[0].ID, input[0].AccountID, input[0].PAN, input[0].HolderName, input[0].ExpirationDate, input[0].CID, input[0].Type).AddRow(input[1].ID, input[1].AccountID, input[1].PAN, input[1].HolderName, input[1].ExpirationDate, input[1].CID, input[1].Type)
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM cards WHERE account_id = ?;")).WithArgs(1).WillReturnRows(rows)
+	for _, card := range input {
+		rows.AddRow(card.ID, card.AccountID, card.LastFour, card.HolderName, card.ExpirationDate, card.Type, card.Brand, card.Nickname, card.Default, card.VerificationStatus, card.Token)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + cardColumns + " FROM cards WHERE account_id = ? AND deleted_at IS NULL;")).WithArgs(1).WillReturnRows(rows)
 
 	repo := NewRepository(db)
 
 	cards, err := repo.GetAll(ctx, 1)
 	assert.NoError(t, err)
-	assert.NotZero(t, cards)
+	assert.Equal(t, input, cards)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -76,11 +113,12 @@ func TestRepositoryGetByIDSuccesfully(t *testing.T) {
 	input := domain.Card{
 		ID:             1,
 		AccountID:      1,
-		PAN:            "123456",
+		LastFour:       "3704",
 		HolderName:     "Miguel",
-		ExpirationDate: "2022-10-10",
-		CID:            "123",
-		Type:           "Visa",
+		ExpirationDate: "2022-10-31",
+		Type:           TypeCredit,
+		Brand:          BrandVisa,
+		Token:          "tok_1",
 	}
 
 	db, mock, err := sqlmock.New()
@@ -88,56 +126,152 @@ func TestRepositoryGetByIDSuccesfully(t *testing.T) {
 	defer db.Close()
 	ctx := context.TODO()
 
-	columns := []string{"card_id", "account_id", "pan", "holder_name", "expiration_date", "cvv", "type"}
+	columns := []string{"id", "account_id", "last_four", "holder_name", "expiration_date", "type", "brand", "nickname", "is_default", "verification_status", "token"}
 	rows := sqlmock.NewRows(columns)
-	rows.AddRow(input.ID, input.AccountID, input.PAN, input.HolderName, input.ExpirationDate, input.CID, input.Type)
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM cards WHERE id = ? and account_id = ?;")).WithArgs(1, 1).WillReturnRows(rows)
+	rows.AddRow(input.ID, input.AccountID, input.LastFour, input.HolderName, input.ExpirationDate, input.Type, input.Brand, input.Nickname, input.Default, input.VerificationStatus, input.Token)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT "+cardColumns+" FROM cards WHERE id = ? and account_id = ? AND deleted_at IS NULL;")).WithArgs(1, 1).WillReturnRows(rows)
 
 	repo := NewRepository(db)
 	card, err := repo.GetByID(ctx, 1, 1)
 	assert.NoError(t, err)
-	assert.NotZero(t, card)
+	assert.Equal(t, input, card)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestRepositoryExistsSuccesfully(t *testing.T) {
-	input := domain.Card{
-		ID:             1,
-		AccountID:      1,
-		PAN:            "123456",
-		HolderName:     "Miguel",
-		ExpirationDate: "2022-10-10",
-		CID:            "123",
-		Type:           "Visa",
-	}
-
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 	ctx := context.TODO()
 
-	columns := []string{"card_id"}
+	columns := []string{"id"}
 	rows := sqlmock.NewRows(columns)
-	rows.AddRow(input.ID)
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM cards WHERE pan = ?;")).WithArgs("123456").WillReturnRows(rows)
+	rows.AddRow(1)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM cards WHERE token = ? AND deleted_at IS NULL;")).WithArgs("tok_1").WillReturnRows(rows)
 
 	repo := NewRepository(db)
-	result, err := repo.Exists(ctx, "123456")
+	result, err := repo.Exists(ctx, "tok_1")
 	assert.NoError(t, err)
 	assert.NotZero(t, result)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestRepositoryUpdateDefault(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	ctx := context.TODO()
+
+	card := domain.Card{ID: 2, AccountID: 1, HolderName: "Miguel", Nickname: "Sueldo", Default: true}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE cards SET is_default = FALSE WHERE account_id = ? AND id <> ?;")).
+		WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE cards SET holder_name = ?, nickname = ?, is_default = ? WHERE id = ? AND account_id = ? AND deleted_at IS NULL;")).
+		WithArgs("Miguel", "Sueldo", true, 2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	err = repo.Update(ctx, card)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryUpdateOtherAccountCard(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	ctx := context.TODO()
+
+	card := domain.Card{ID: 2, AccountID: 1, HolderName: "Miguel"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE cards SET holder_name").WithArgs("Miguel", "", false, 2, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM cards WHERE id = ? AND account_id = ? AND deleted_at IS NULL;")).
+		WithArgs(2, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.Update(ctx, card)
+	assert.Equal(t, ErrCardNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRepositoryDeleteSuccesfully(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 	ctx := context.TODO()
 
-	mock.ExpectPrepare(regexp.QuoteMeta("DELETE FROM cards WHERE id=?;")).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(regexp.QuoteMeta("UPDATE cards SET deleted_at = ?, is_default = FALSE WHERE id = ? AND account_id = ? AND deleted_at IS NULL;")).
+		ExpectExec().WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
 
 	repo := NewRepository(db)
-	err = repo.DeleteByCardID(ctx, 1)
+	err = repo.DeleteByCardID(ctx, 1, 1)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestRepositoryDeleteOtherAccountCard(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	ctx := context.TODO()
+
+	mock.ExpectPrepare("UPDATE cards SET deleted_at").ExpectExec().WithArgs(sqlmock.AnyArg(), 1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewRepository(db)
+	err = repo.DeleteByCardID(ctx, 2, 1)
+	assert.Equal(t, ErrCardNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryAttemptLocksTheVerification(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	createdAt := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM card_verifications v JOIN cards c ON c.id = v.card_id WHERE v.card_id = ? AND c.account_id = ? AND c.deleted_at IS NULL FOR UPDATE;")).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"card_id", "first_amount", "second_amount", "first_reference", "second_reference", "attempts", "created_at", "verification_status"}).
+			AddRow(2, "0.17", "0.42", "ref-1", "ref-2", 2, createdAt, domain.CardVerificationPending))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE card_verifications SET attempts = ? WHERE card_id = ?;")).WithArgs(3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE cards SET verification_status = ? WHERE id = ? AND account_id = ?;")).
+		WithArgs(domain.CardVerificationLocked, 2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+
+	verification, err := repo.Attempt(context.Background(), 1, 2, func(verification domain.CardVerification) domain.CardVerification {
+		verification.Attempts++
+		verification.Status = domain.CardVerificationLocked
+		return verification
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, verification.Attempts)
+	assert.Equal(t, "ref-2", verification.References[1])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryAttemptNotStarted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM card_verifications").WithArgs(2, 1).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+
+	_, err = repo.Attempt(context.Background(), 1, 2, func(verification domain.CardVerification) domain.CardVerification {
+		t.Fatal("attempt called without a verification")
+		return verification
+	})
+	assert.Equal(t, ErrVerificationNotStarted, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
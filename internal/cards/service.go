@@ -3,33 +3,63 @@ package cards
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/processor"
+	"gitlab.com/leorodriguez/grupo-04/internal/vault"
 )
 
 var (
 	ErrCardAlreadyAssociated = errors.New("card already associated to another account")
 	ErrCardNotFound          = errors.New("card not found")
+	ErrNoDefaultCard         = errors.New("account has no default card")
 )
 
+const maxNicknameLength = 30
+
 type Service interface {
 	Save(ctx context.Context, id int, card domain.CardDto) error
 	GetAll(ctx context.Context, accountID int) ([]domain.Card, error)
 	GetByCardID(ctx context.Context, accountID, cardID int) (domain.Card, error)
-	DeleteByCardID(ctx context.Context, cardID int) error
+	Update(ctx context.Context, accountID, cardID int, rq domain.CardUpdate) (domain.Card, error)
+	DeleteByCardID(ctx context.Context, accountID, cardID int) error
+	StartVerification(ctx context.Context, accountID, cardID int) (domain.CardVerificationResult, error)
+	Verify(ctx context.Context, accountID, cardID int, rq domain.CardVerifyRequest) (domain.CardVerificationResult, error)
 }
 
 type service struct {
 	cardsRepository Repository
+	tokenizer       vault.Tokenizer
+	processor       processor.Processor
+	now             func() time.Time
 }
 
-func NewService(cardsRepository Repository) Service {
+func NewService(cardsRepository Repository, tokenizer vault.Tokenizer, processor processor.Processor) Service {
 	return &service{
 		cardsRepository: cardsRepository,
+		tokenizer:       tokenizer,
+		processor:       processor,
+		now:             time.Now,
 	}
 }
 
 func (s *service) Save(ctx context.Context, id int, card domain.CardDto) error {
-	exists, err := s.cardsRepository.Exists(ctx, card.PAN)
+	card, err := Validate(card, s.now().UTC())
+	if err != nil {
+		return err
+	}
+
+	// from here on the card is only known by its token
+	token, err := s.tokenizer.Tokenize(ctx, card.PAN)
+	if err != nil {
+		return err
+	}
+
+	exists, err := s.cardsRepository.Exists(ctx, token.Value)
 	if err != nil {
 		return err
 	}
@@ -38,7 +68,17 @@ func (s *service) Save(ctx context.Context, id int, card domain.CardDto) error {
 		return ErrCardAlreadyAssociated
 	}
 
-	id, err = s.cardsRepository.SaveCard(ctx, id, card)
+	id, err = s.cardsRepository.SaveCard(ctx, domain.Card{
+		AccountID:      id,
+		LastFour:       token.LastFour,
+		HolderName:     card.HolderName,
+		ExpirationDate: card.ExpirationDate,
+		Type:           card.Type,
+		Brand:          card.Brand,
+		Token:          token.Value,
+		// new cards must be verified before they can fund deposits
+		VerificationStatus: domain.CardVerificationPending,
+	})
 	if err != nil {
 		return err
 	}
@@ -69,11 +109,56 @@ func (s *service) GetByCardID(ctx context.Context, accountID, cardID int) (domai
 	return cards, nil
 }
 
-func (s *service) DeleteByCardID(ctx context.Context, cardID int) error {
-	err := s.cardsRepository.DeleteByCardID(ctx, cardID)
+func (s *service) Update(ctx context.Context, accountID, cardID int, rq domain.CardUpdate) (domain.Card, error) {
+	verr := &ValidationError{}
+	if rq.HolderName != nil && strings.TrimSpace(*rq.HolderName) == "" {
+		verr.add("holder_name", "is required")
+	}
+	if rq.Nickname != nil && utf8.RuneCountInString(strings.TrimSpace(*rq.Nickname)) > maxNicknameLength {
+		verr.add("nickname", fmt.Sprintf("must have at most %d characters", maxNicknameLength))
+	}
+	if len(verr.Fields) > 0 {
+		return domain.Card{}, verr
+	}
+
+	card, err := s.cardsRepository.GetByID(ctx, accountID, cardID)
+	if err != nil {
+		return domain.Card{}, err
+	}
+
+	if rq.HolderName != nil {
+		card.HolderName = strings.TrimSpace(*rq.HolderName)
+	}
+	if rq.Nickname != nil {
+		card.Nickname = strings.TrimSpace(*rq.Nickname)
+	}
+	if rq.Default != nil {
+		card.Default = *rq.Default
+	}
+
+	if err = s.cardsRepository.Update(ctx, card); err != nil {
+		return domain.Card{}, err
+	}
+
+	return card, nil
+}
+
+// DeleteByCardID soft deletes the card, only if it belongs to the account.
+func (s *service) DeleteByCardID(ctx context.Context, accountID, cardID int) error {
+	err := s.cardsRepository.DeleteByCardID(ctx, accountID, cardID)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// MaskPAN hides all but the last four digits of a card number. It can also be
+// given just the last four digits.
+func MaskPAN(pan string) string {
+	if len(pan) > 4 {
+		pan = pan[len(pan)-4:]
+	}
+
+	return "**** " + pan
+}
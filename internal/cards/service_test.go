@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/vault"
 )
 
 type repositoryMock struct {
@@ -24,67 +26,143 @@ func (r *repositoryMock) GetByID(ctx context.Context, accountID, cardID int) (do
 	return args.Get(0).(domain.Card), args.Error(1)
 }
 
-func (r *repositoryMock) Exists(ctx context.Context, pan string) (bool, error) {
-	args := r.Called(ctx, pan)
+func (r *repositoryMock) Exists(ctx context.Context, token string) (bool, error) {
+	args := r.Called(ctx, token)
 	return args.Bool(0), args.Error(1)
 }
 
-func (r *repositoryMock) SaveCard(ctx context.Context, id int, card domain.CardDto) (int, error) {
-	args := r.Called(ctx, id, card)
+func (r *repositoryMock) SaveCard(ctx context.Context, card domain.Card) (int, error) {
+	args := r.Called(ctx, card)
 	return args.Int(0), args.Error(1)
 }
 
-func (r *repositoryMock) DeleteByCardID(ctx context.Context, cardID int) error {
+func (r *repositoryMock) GetDefault(ctx context.Context, accountID int) (domain.Card, error) {
+	args := r.Called(ctx, accountID)
+	return args.Get(0).(domain.Card), args.Error(1)
+}
+
+func (r *repositoryMock) Update(ctx context.Context, card domain.Card) error {
+	args := r.Called(ctx, card)
+	return args.Error(0)
+}
+
+func (r *repositoryMock) DeleteByCardID(ctx context.Context, accountID, cardID int) error {
+	args := r.Called(ctx, accountID, cardID)
+	return args.Error(0)
+}
+
+func (r *repositoryMock) GetVerification(ctx context.Context, cardID int) (domain.CardVerification, error) {
 	args := r.Called(ctx, cardID)
+	return args.Get(0).(domain.CardVerification), args.Error(1)
+}
+
+func (r *repositoryMock) SaveVerification(ctx context.Context, accountID int, verification domain.CardVerification) error {
+	args := r.Called(ctx, accountID, verification)
 	return args.Error(0)
 }
 
+// Attempt settles the verification the mock returns with attempt.
+func (r *repositoryMock) Attempt(ctx context.Context, accountID, cardID int, attempt AttemptFunc) (domain.CardVerification, error) {
+	args := r.Called(ctx, accountID, cardID)
+	if err := args.Error(1); err != nil {
+		return domain.CardVerification{}, err
+	}
+	return attempt(args.Get(0).(domain.CardVerification)), nil
+}
+
+type tokenizerMock struct {
+	mock.Mock
+}
+
+func (t *tokenizerMock) Tokenize(ctx context.Context, pan string) (vault.Token, error) {
+	args := t.Called(ctx, pan)
+	return args.Get(0).(vault.Token), args.Error(1)
+}
+
 func Test_service_Save(t *testing.T) {
 	var ctx = context.Background()
+	now := time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)
 	card := domain.CardDto{
-		PAN: "123456",
+		PAN:            "4111 1111 1111 1111",
+		HolderName:     "Miguel",
+		ExpirationDate: "12/25",
+		CID:            "123",
+		Type:           "Credit",
+	}
+	token := vault.Token{Value: "tok_1", LastFour: "1111"}
+	saved := domain.Card{
+		AccountID:      1,
+		LastFour:       "1111",
+		HolderName:     "Miguel",
+		ExpirationDate: "2025-12-31",
+		Type:           TypeCredit,
+		Brand:          BrandVisa,
+		Token:          token.Value,
+
+		VerificationStatus: domain.CardVerificationPending,
 	}
 	id := 1
 	testCases := []struct {
 		name          string
+		card          domain.CardDto
+		tokenizerMock func(m *mock.Mock)
 		repoMock      func(m *mock.Mock)
 		expectedError error
 	}{
+		{
+			name:     "Invalid card",
+			card:     domain.CardDto{PAN: "4111111111111112", HolderName: "Miguel", ExpirationDate: "04/23", CID: "12", Type: "prepaid"},
+			repoMock: func(m *mock.Mock) {},
+			expectedError: &ValidationError{Fields: []FieldError{
+				{Field: "pan", Message: "invalid card number"},
+				{Field: "expiration_date", Message: "card is expired"},
+				{Field: "cvv", Message: "must have 3 digits"},
+				{Field: "type", Message: "must be credit or debit"},
+			}},
+		},
+		{
+			name: "Tokenize error",
+			tokenizerMock: func(m *mock.Mock) {
+				m.On("Tokenize", ctx, "4111111111111111").Return(vault.Token{}, errors.New("error"))
+			},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: errors.New("error"),
+		},
 		{
 			name: "Error exists card",
 			repoMock: func(m *mock.Mock) {
-				m.On("Exists", ctx, card.PAN).Return(false, errors.New("error"))
+				m.On("Exists", ctx, token.Value).Return(false, errors.New("error"))
 			},
 			expectedError: errors.New("error"),
 		},
 		{
 			name: "Card exists true",
 			repoMock: func(m *mock.Mock) {
-				m.On("Exists", ctx, card.PAN).Return(true, nil)
+				m.On("Exists", ctx, token.Value).Return(true, nil)
 			},
 			expectedError: errors.New("card already associated to another account"),
 		},
 		{
 			name: "Card save error",
 			repoMock: func(m *mock.Mock) {
-				m.On("Exists", ctx, card.PAN).Return(false, nil)
-				m.On("SaveCard", ctx, id, card).Return(0, errors.New("error save card"))
+				m.On("Exists", ctx, token.Value).Return(false, nil)
+				m.On("SaveCard", ctx, saved).Return(0, errors.New("error save card"))
 			},
 			expectedError: errors.New("error save card"),
 		},
 		{
 			name: "Card save cero",
 			repoMock: func(m *mock.Mock) {
-				m.On("Exists", ctx, card.PAN).Return(false, nil)
-				m.On("SaveCard", ctx, id, card).Return(0, nil)
+				m.On("Exists", ctx, token.Value).Return(false, nil)
+				m.On("SaveCard", ctx, saved).Return(0, nil)
 			},
 			expectedError: nil,
 		},
 		{
 			name: "Card save successfully",
 			repoMock: func(m *mock.Mock) {
-				m.On("Exists", ctx, card.PAN).Return(false, nil)
-				m.On("SaveCard", ctx, id, card).Return(1, nil)
+				m.On("Exists", ctx, token.Value).Return(false, nil)
+				m.On("SaveCard", ctx, saved).Return(1, nil)
 			},
 			expectedError: nil,
 		},
@@ -93,10 +171,21 @@ func Test_service_Save(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			repoMock := new(repositoryMock)
 			testCase.repoMock(&repoMock.Mock)
+			tokenizer := new(tokenizerMock)
+			if testCase.tokenizerMock != nil {
+				testCase.tokenizerMock(&tokenizer.Mock)
+			} else {
+				tokenizer.On("Tokenize", ctx, "4111111111111111").Return(token, nil)
+			}
+
+			cardsService := &service{cardsRepository: repoMock, tokenizer: tokenizer, now: func() time.Time { return now }}
 
-			cardsService := NewService(repoMock)
+			rq := card
+			if testCase.card.PAN != "" {
+				rq = testCase.card
+			}
 
-			err := cardsService.Save(ctx, id, card)
+			err := cardsService.Save(ctx, id, rq)
 
 			assert.Equal(t, testCase.expectedError, err)
 
@@ -129,19 +218,17 @@ func Test_service_GetAll(t *testing.T) {
 					{
 						ID:             1,
 						AccountID:      1,
-						PAN:            "12345",
+						LastFour:       "2345",
 						HolderName:     "Miguel",
 						ExpirationDate: "2025-10-10",
-						CID:            "124",
 						Type:           "Master Card",
 					},
 					{
 						ID:             2,
 						AccountID:      1,
-						PAN:            "323432",
+						LastFour:       "3432",
 						HolderName:     "Miguel",
 						ExpirationDate: "2025-10-10",
-						CID:            "143",
 						Type:           "Visa",
 					},
 				}, nil)
@@ -151,19 +238,17 @@ func Test_service_GetAll(t *testing.T) {
 				{
 					ID:             1,
 					AccountID:      1,
-					PAN:            "12345",
+					LastFour:       "2345",
 					HolderName:     "Miguel",
 					ExpirationDate: "2025-10-10",
-					CID:            "124",
 					Type:           "Master Card",
 				},
 				{
 					ID:             2,
 					AccountID:      1,
-					PAN:            "323432",
+					LastFour:       "3432",
 					HolderName:     "Miguel",
 					ExpirationDate: "2025-10-10",
-					CID:            "143",
 					Type:           "Visa",
 				},
 			},
@@ -174,7 +259,7 @@ func Test_service_GetAll(t *testing.T) {
 			repoMock := new(repositoryMock)
 			testCase.repoMock(&repoMock.Mock)
 
-			cardsService := NewService(repoMock)
+			cardsService := NewService(repoMock, nil, nil)
 
 			cards, err := cardsService.GetAll(ctx, accountID)
 
@@ -209,10 +294,9 @@ func Test_service_GetByCardID(t *testing.T) {
 				m.On("GetByID", ctx, accountID, cardID).Return(domain.Card{
 					ID:             1,
 					AccountID:      1,
-					PAN:            "12345",
+					LastFour:       "2345",
 					HolderName:     "Miguel",
 					ExpirationDate: "2025-10-10",
-					CID:            "124",
 					Type:           "Master Card",
 				}, nil)
 			},
@@ -220,10 +304,9 @@ func Test_service_GetByCardID(t *testing.T) {
 			expectedResult: domain.Card{
 				ID:             1,
 				AccountID:      1,
-				PAN:            "12345",
+				LastFour:       "2345",
 				HolderName:     "Miguel",
 				ExpirationDate: "2025-10-10",
-				CID:            "124",
 				Type:           "Master Card",
 			},
 		},
@@ -233,7 +316,7 @@ func Test_service_GetByCardID(t *testing.T) {
 			repoMock := new(repositoryMock)
 			testCase.repoMock(&repoMock.Mock)
 
-			cardsService := NewService(repoMock)
+			cardsService := NewService(repoMock, nil, nil)
 
 			card, err := cardsService.GetByCardID(ctx, accountID, cardID)
 
@@ -245,6 +328,7 @@ func Test_service_GetByCardID(t *testing.T) {
 
 func Test_service_DeleteByCardID(t *testing.T) {
 	var ctx = context.Background()
+	accountID := 1
 	cardID := 1
 	testCases := []struct {
 		name          string
@@ -255,14 +339,14 @@ func Test_service_DeleteByCardID(t *testing.T) {
 		{
 			name: "Error delete by id card repository",
 			repoMock: func(m *mock.Mock) {
-				m.On("DeleteByCardID", ctx, cardID).Return(errors.New("error"))
+				m.On("DeleteByCardID", ctx, accountID, cardID).Return(errors.New("error"))
 			},
 			expectedError: errors.New("error"),
 		},
 		{
 			name: "Delete by id card successfully",
 			repoMock: func(m *mock.Mock) {
-				m.On("DeleteByCardID", ctx, cardID).Return(nil)
+				m.On("DeleteByCardID", ctx, accountID, cardID).Return(nil)
 			},
 		},
 
@@ -273,11 +357,72 @@ func Test_service_DeleteByCardID(t *testing.T) {
 			repoMock := new(repositoryMock)
 			testCase.repoMock(&repoMock.Mock)
 
-			cardsService := NewService(repoMock)
+			cardsService := NewService(repoMock, nil, nil)
+
+			err := cardsService.DeleteByCardID(ctx, accountID, cardID)
+
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
+func Test_service_Update(t *testing.T) {
+	var ctx = context.Background()
+	card := domain.Card{ID: 2, AccountID: 1, LastFour: "3704", HolderName: "Miguel", Type: TypeCredit, Brand: BrandVisa}
+	blank := "  "
+	nickname := " Sueldo "
+	longNickname := "La tarjeta de la cuenta del sueldo"
+	isDefault := true
+	testCases := []struct {
+		name           string
+		rq             domain.CardUpdate
+		repoMock       func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.Card
+	}{
+		{
+			name:     "Invalid fields",
+			rq:       domain.CardUpdate{HolderName: &blank, Nickname: &longNickname},
+			repoMock: func(m *mock.Mock) {},
+			expectedError: &ValidationError{Fields: []FieldError{
+				{Field: "holder_name", Message: "is required"},
+				{Field: "nickname", Message: "must have at most 30 characters"},
+			}},
+		},
+		{
+			name: "Card of another account",
+			rq:   domain.CardUpdate{Default: &isDefault},
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(domain.Card{}, ErrCardNotFound)
+			},
+			expectedError: ErrCardNotFound,
+		},
+		{
+			name: "Nickname and default",
+			rq:   domain.CardUpdate{Nickname: &nickname, Default: &isDefault},
+			repoMock: func(m *mock.Mock) {
+				updated := card
+				updated.Nickname = "Sueldo"
+				updated.Default = true
+				m.On("GetByID", ctx, 1, 2).Return(card, nil)
+				m.On("Update", ctx, updated).Return(nil)
+			},
+			expectedResult: domain.Card{ID: 2, AccountID: 1, LastFour: "3704", HolderName: "Miguel", Type: TypeCredit, Brand: BrandVisa,
+				Nickname: "Sueldo", Default: true},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+
+			cardsService := NewService(repoMock, nil, nil)
 
-			err := cardsService.DeleteByCardID(ctx, cardID)
+			updated, err := cardsService.Update(ctx, 1, 2, testCase.rq)
 
 			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, updated)
+			repoMock.AssertExpectations(t)
 		})
 	}
 }
@@ -0,0 +1,194 @@
+package cards
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+const (
+	BrandVisa       = "visa"
+	BrandMastercard = "mastercard"
+	BrandAmex       = "amex"
+	BrandCabal      = "cabal"
+	BrandNaranja    = "naranja"
+
+	TypeCredit = "credit"
+	TypeDebit  = "debit"
+)
+
+// expirationLayouts are the accepted formats of expiration_date. Cards are
+// valid until the end of the month they expire in.
+var expirationLayouts = []string{"01/06", "01/2006", "2006-01", "2006-01-02", time.RFC3339}
+
+// brandRange is a range of BINs, compared against the first len(From) digits
+// of the card number.
+type brandRange struct {
+	brand    string
+	from, to string
+	lengths  []int
+}
+
+// brandRanges are checked in order, so the narrower local ranges go before the
+// international ones they could overlap with.
+var brandRanges = []brandRange{
+	{brand: BrandNaranja, from: "589562", to: "589562", lengths: []int{16}},
+	{brand: BrandCabal, from: "589657", to: "589657", lengths: []int{16}},
+	{brand: BrandCabal, from: "603522", to: "603522", lengths: []int{16}},
+	{brand: BrandCabal, from: "6042", to: "6043", lengths: []int{16}},
+	{brand: BrandAmex, from: "34", to: "34", lengths: []int{15}},
+	{brand: BrandAmex, from: "37", to: "37", lengths: []int{15}},
+	{brand: BrandMastercard, from: "51", to: "55", lengths: []int{16}},
+	{brand: BrandMastercard, from: "2221", to: "2720", lengths: []int{16}},
+	{brand: BrandVisa, from: "4", to: "4", lengths: []int{13, 16, 19}},
+}
+
+// FieldError describes why a field of a card is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a card.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
+
+	return "invalid card: " + strings.Join(fields, ", ")
+}
+
+func (e *ValidationError) add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Validate checks the card against now and returns it normalized: digits only
+// in the PAN, the detected brand, the type in lowercase and the expiration date
+// as the last day of its month. Errors are a *ValidationError.
+func Validate(card domain.CardDto, now time.Time) (domain.CardDto, error) {
+	verr := &ValidationError{}
+
+	card.PAN = strings.NewReplacer(" ", "", "-", "").Replace(card.PAN)
+	switch {
+	case !isDigits(card.PAN):
+		verr.add("pan", "must contain only digits")
+	case !luhn(card.PAN):
+		verr.add("pan", "invalid card number")
+	default:
+		brand, ok := DetectBrand(card.PAN)
+		if !ok {
+			verr.add("pan", "unsupported card brand")
+		}
+		card.Brand = brand
+	}
+
+	if strings.TrimSpace(card.HolderName) == "" {
+		verr.add("holder_name", "is required")
+	}
+
+	expiration, err := parseExpiration(card.ExpirationDate)
+	if err != nil {
+		verr.add("expiration_date", "must be MM/YY, MM/YYYY or YYYY-MM-DD")
+	} else if expiration.Before(now) {
+		verr.add("expiration_date", "card is expired")
+	} else {
+		card.ExpirationDate = expiration.Format("2006-01-02")
+	}
+
+	cvvLength := 3
+	if card.Brand == BrandAmex {
+		cvvLength = 4
+	}
+	if len(card.CID) != cvvLength || !isDigits(card.CID) {
+		verr.add("cvv", fmt.Sprintf("must have %d digits", cvvLength))
+	}
+
+	card.Type = strings.ToLower(strings.TrimSpace(card.Type))
+	if card.Type != TypeCredit && card.Type != TypeDebit {
+		verr.add("type", "must be credit or debit")
+	}
+
+	if len(verr.Fields) > 0 {
+		return domain.CardDto{}, verr
+	}
+
+	return card, nil
+}
+
+// DetectBrand returns the brand of the card number from its BIN and length.
+func DetectBrand(pan string) (string, bool) {
+	for _, r := range brandRanges {
+		if len(pan) < len(r.from) {
+			continue
+		}
+		prefix := pan[:len(r.from)]
+		if prefix < r.from || prefix > r.to {
+			continue
+		}
+		for _, l := range r.lengths {
+			if len(pan) == l {
+				return r.brand, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// luhn checks the mod 10 checksum of a card number.
+func luhn(pan string) bool {
+	if len(pan) < 12 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(pan) - 1; i >= 0; i-- {
+		d := int(pan[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// parseExpiration returns the last instant of the month the card expires in.
+func parseExpiration(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range expirationLayouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		firstOfMonth := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return firstOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid expiration date %q", value)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
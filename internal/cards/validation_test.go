@@ -0,0 +1,68 @@
+package cards
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+func TestDetectBrand(t *testing.T) {
+	testCases := []struct {
+		pan   string
+		brand string
+		ok    bool
+	}{
+		{pan: "4111111111111111", brand: BrandVisa, ok: true},
+		{pan: "5500000000000004", brand: BrandMastercard, ok: true},
+		{pan: "2221000000000009", brand: BrandMastercard, ok: true},
+		{pan: "378282246310005", brand: BrandAmex, ok: true},
+		{pan: "5896570000000008", brand: BrandCabal, ok: true},
+		{pan: "6042010000000002", brand: BrandCabal, ok: true},
+		{pan: "5895620000000002", brand: BrandNaranja, ok: true},
+		// amex BIN with the length of other brands
+		{pan: "3782822463100051"},
+		{pan: "6011111111111117"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.pan, func(t *testing.T) {
+			brand, ok := DetectBrand(testCase.pan)
+			assert.Equal(t, testCase.ok, ok)
+			assert.Equal(t, testCase.brand, brand)
+		})
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	assert.True(t, luhn("4111111111111111"))
+	assert.True(t, luhn("378282246310005"))
+	assert.False(t, luhn("4111111111111112"))
+	assert.False(t, luhn("0000"))
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2023, 5, 10, 15, 0, 0, 0, time.UTC)
+	valid := domain.CardDto{PAN: "3782-822463-10005", HolderName: "Miguel", ExpirationDate: "05/2023", CID: "1234", Type: "debit"}
+
+	card, err := Validate(valid, now)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.CardDto{PAN: "378282246310005", HolderName: "Miguel", ExpirationDate: "2023-05-31", CID: "1234",
+		Type: TypeDebit, Brand: BrandAmex}, card)
+
+	// any accepted layout of the same month gives the same date
+	for _, expiration := range []string{"05/23", "2023-05", "2023-05-01", "2023-05-01T00:00:00Z"} {
+		valid.ExpirationDate = expiration
+		card, err = Validate(valid, now)
+		assert.Nil(t, err)
+		assert.Equal(t, "2023-05-31", card.ExpirationDate)
+	}
+
+	_, err = Validate(domain.CardDto{PAN: "4111 1111 1111 111a", ExpirationDate: "04/23", CID: "123", Type: "credit"}, now)
+	assert.Equal(t, &ValidationError{Fields: []FieldError{
+		{Field: "pan", Message: "must contain only digits"},
+		{Field: "holder_name", Message: "is required"},
+		{Field: "expiration_date", Message: "card is expired"},
+	}}, err)
+	assert.EqualError(t, err, "invalid card: pan: must contain only digits, holder_name: is required, expiration_date: card is expired")
+}
@@ -0,0 +1,197 @@
+package cards
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+)
+
+var (
+	ErrVerificationNotStarted = errors.New("card verification not started")
+	ErrCardNotVerified        = errors.New("card is not verified")
+	ErrCardAlreadyVerified    = errors.New("card is already verified")
+	ErrCardLocked             = errors.New("card is locked after too many verification attempts")
+	ErrAmountsMismatch        = errors.New("verification amounts do not match")
+)
+
+const (
+	verificationCharges  = 2
+	maxVerifyAttempts    = 3
+	maxVerificationCents = 99
+)
+
+// StartVerification charges the card two small random amounts that the user
+// has to confirm with Verify. Starting again replaces the previous charges,
+// but not the attempts already made.
+func (s *service) StartVerification(ctx context.Context, accountID, cardID int) (domain.CardVerificationResult, error) {
+	card, err := s.cardsRepository.GetByID(ctx, accountID, cardID)
+	if err != nil {
+		return domain.CardVerificationResult{}, err
+	}
+
+	switch card.VerificationStatus {
+	case domain.CardVerificationVerified:
+		return domain.CardVerificationResult{}, ErrCardAlreadyVerified
+	case domain.CardVerificationLocked:
+		return domain.CardVerificationResult{}, ErrCardLocked
+	}
+
+	previous, err := s.cardsRepository.GetVerification(ctx, cardID)
+	if err != nil && err != ErrVerificationNotStarted {
+		return domain.CardVerificationResult{}, err
+	}
+	if previous.Attempts >= maxVerifyAttempts {
+		return domain.CardVerificationResult{}, ErrCardLocked
+	}
+	if err == nil {
+		s.refund(ctx, previous)
+	}
+
+	verification := domain.CardVerification{
+		CardID:     cardID,
+		Amounts:    make([]decimal.Decimal, verificationCharges),
+		References: make([]string, verificationCharges),
+		Attempts:   previous.Attempts,
+		Status:     domain.CardVerificationPending,
+		CreatedAt:  s.now().UTC(),
+	}
+
+	cents, err := randomCents()
+	if err != nil {
+		return domain.CardVerificationResult{}, err
+	}
+
+	for i := range verification.Amounts {
+		verification.Amounts[i] = decimal.New(cents[i], -2)
+		verification.References[i], err = s.processor.Charge(ctx, card, verification.Amounts[i])
+		if err != nil {
+			s.refund(ctx, verification)
+			verification.Status = domain.CardVerificationFailed
+			if saveErr := s.cardsRepository.SaveVerification(ctx, accountID, verification); saveErr != nil {
+				logger.Error(fmt.Sprintf("saving failed verification of card %d: %s", cardID, saveErr.Error()))
+			}
+			return domain.CardVerificationResult{}, err
+		}
+	}
+
+	if err = s.cardsRepository.SaveVerification(ctx, accountID, verification); err != nil {
+		s.refund(ctx, verification)
+		return domain.CardVerificationResult{}, err
+	}
+
+	return domain.CardVerificationResult{Status: verification.Status, AttemptsLeft: maxVerifyAttempts - verification.Attempts}, nil
+}
+
+// Verify checks the amounts the user saw charged to the card, in any order.
+// After maxVerifyAttempts wrong answers the card is locked.
+func (s *service) Verify(ctx context.Context, accountID, cardID int, rq domain.CardVerifyRequest) (domain.CardVerificationResult, error) {
+	if len(rq.Amounts) != verificationCharges {
+		return domain.CardVerificationResult{}, &ValidationError{Fields: []FieldError{
+			{Field: "amounts", Message: fmt.Sprintf("must have %d amounts", verificationCharges)},
+		}}
+	}
+
+	card, err := s.cardsRepository.GetByID(ctx, accountID, cardID)
+	if err != nil {
+		return domain.CardVerificationResult{}, err
+	}
+
+	switch card.VerificationStatus {
+	case domain.CardVerificationVerified:
+		return domain.CardVerificationResult{}, ErrCardAlreadyVerified
+	case domain.CardVerificationLocked:
+		return domain.CardVerificationResult{}, ErrCardLocked
+	case domain.CardVerificationFailed:
+		return domain.CardVerificationResult{}, ErrVerificationNotStarted
+	}
+
+	var result error
+	var settled bool
+	verification, err := s.cardsRepository.Attempt(ctx, accountID, cardID, func(verification domain.CardVerification) domain.CardVerification {
+		// a concurrent attempt may have settled it since the card was read
+		switch verification.Status {
+		case domain.CardVerificationVerified:
+			result = ErrCardAlreadyVerified
+			return verification
+		case domain.CardVerificationLocked:
+			result = ErrCardLocked
+			return verification
+		case domain.CardVerificationFailed:
+			result = ErrVerificationNotStarted
+			return verification
+		}
+
+		verification.Attempts++
+		switch {
+		case amountsMatch(verification.Amounts, rq.Amounts):
+			verification.Status = domain.CardVerificationVerified
+		case verification.Attempts >= maxVerifyAttempts:
+			verification.Status = domain.CardVerificationLocked
+			result = ErrCardLocked
+		default:
+			result = ErrAmountsMismatch
+		}
+		settled = verification.Status != domain.CardVerificationPending
+		return verification
+	})
+	if err != nil {
+		return domain.CardVerificationResult{}, err
+	}
+
+	if !settled && verification.Status != domain.CardVerificationPending {
+		return domain.CardVerificationResult{}, result
+	}
+
+	// the charges were only a proof, give them back once it is settled
+	if settled {
+		s.refund(ctx, verification)
+	}
+
+	attemptsLeft := maxVerifyAttempts - verification.Attempts
+	if verification.Status != domain.CardVerificationPending {
+		attemptsLeft = 0
+	}
+
+	return domain.CardVerificationResult{Status: verification.Status, AttemptsLeft: attemptsLeft}, result
+}
+
+func (s *service) refund(ctx context.Context, verification domain.CardVerification) {
+	for _, reference := range verification.References {
+		if reference == "" {
+			continue
+		}
+		if err := s.processor.Refund(ctx, reference); err != nil {
+			logger.Error(fmt.Sprintf("refunding verification charge %s: %s", reference, err.Error()))
+		}
+	}
+}
+
+func amountsMatch(expected, got []decimal.Decimal) bool {
+	return (expected[0].Equal(got[0]) && expected[1].Equal(got[1])) ||
+		(expected[0].Equal(got[1]) && expected[1].Equal(got[0]))
+}
+
+// randomCents returns two different amounts between 1 and 99 cents, so the
+// user cannot confirm them by guessing the same value twice.
+func randomCents() ([]int64, error) {
+	cents := make([]int64, 0, verificationCharges)
+	for len(cents) < verificationCharges {
+		n, err := rand.Int(rand.Reader, big.NewInt(maxVerificationCents))
+		if err != nil {
+			return nil, err
+		}
+		c := n.Int64() + 1
+		if len(cents) > 0 && cents[0] == c {
+			continue
+		}
+		cents = append(cents, c)
+	}
+
+	return cents, nil
+}
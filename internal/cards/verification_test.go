@@ -0,0 +1,236 @@
+package cards
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/processor"
+)
+
+type processorMock struct {
+	mock.Mock
+}
+
+func (p *processorMock) Charge(ctx context.Context, card domain.Card, amount decimal.Decimal) (string, error) {
+	args := p.Called(ctx, card, amount)
+	return args.String(0), args.Error(1)
+}
+
+func (p *processorMock) Refund(ctx context.Context, reference string) error {
+	args := p.Called(ctx, reference)
+	return args.Error(0)
+}
+
+func Test_service_StartVerification(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	pending := domain.Card{ID: 2, AccountID: 1, Token: "tok_1", VerificationStatus: domain.CardVerificationPending}
+	isMicroCharge := mock.MatchedBy(func(amount decimal.Decimal) bool {
+		return amount.IsPositive() && amount.LessThan(decimal.NewFromInt(1))
+	})
+
+	testCases := []struct {
+		name           string
+		repoMock       func(m *mock.Mock)
+		processorMock  func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.CardVerificationResult
+	}{
+		{
+			name: "Already verified",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(domain.Card{ID: 2, VerificationStatus: domain.CardVerificationVerified}, nil)
+			},
+			processorMock: func(m *mock.Mock) {},
+			expectedError: ErrCardAlreadyVerified,
+		},
+		{
+			name: "Declined charge fails the verification",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("GetVerification", ctx, 2).Return(domain.CardVerification{}, ErrVerificationNotStarted)
+				m.On("SaveVerification", ctx, 1, mock.MatchedBy(func(v domain.CardVerification) bool {
+					return v.Status == domain.CardVerificationFailed
+				})).Return(nil)
+			},
+			processorMock: func(m *mock.Mock) {
+				m.On("Charge", ctx, pending, isMicroCharge).Return("", processor.ErrPaymentDeclined)
+			},
+			expectedError: processor.ErrPaymentDeclined,
+		},
+		{
+			name: "Restart refunds the previous charges",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("GetVerification", ctx, 2).Return(domain.CardVerification{CardID: 2, References: []string{"ref-1", "ref-2"}}, nil)
+				m.On("SaveVerification", ctx, 1, mock.MatchedBy(func(v domain.CardVerification) bool {
+					return v.Status == domain.CardVerificationPending && v.Attempts == 0 && v.CreatedAt.Equal(now) &&
+						!v.Amounts[0].Equal(v.Amounts[1]) && v.References[0] == "ref-3" && v.References[1] == "ref-4"
+				})).Return(nil)
+			},
+			processorMock: func(m *mock.Mock) {
+				m.On("Refund", ctx, "ref-1").Return(nil)
+				m.On("Refund", ctx, "ref-2").Return(nil)
+				m.On("Charge", ctx, pending, isMicroCharge).Return("ref-3", nil).Once()
+				m.On("Charge", ctx, pending, isMicroCharge).Return("ref-4", nil).Once()
+			},
+			expectedResult: domain.CardVerificationResult{Status: domain.CardVerificationPending, AttemptsLeft: maxVerifyAttempts},
+		},
+		{
+			name: "Restart keeps the attempts",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("GetVerification", ctx, 2).Return(domain.CardVerification{CardID: 2, References: []string{"ref-1", "ref-2"}, Attempts: 2}, nil)
+				m.On("SaveVerification", ctx, 1, mock.MatchedBy(func(v domain.CardVerification) bool {
+					return v.Status == domain.CardVerificationPending && v.Attempts == 2
+				})).Return(nil)
+			},
+			processorMock: func(m *mock.Mock) {
+				m.On("Refund", ctx, "ref-1").Return(nil)
+				m.On("Refund", ctx, "ref-2").Return(nil)
+				m.On("Charge", ctx, pending, isMicroCharge).Return("ref-3", nil).Once()
+				m.On("Charge", ctx, pending, isMicroCharge).Return("ref-4", nil).Once()
+			},
+			expectedResult: domain.CardVerificationResult{Status: domain.CardVerificationPending, AttemptsLeft: 1},
+		},
+		{
+			name: "Restart after every attempt was used",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("GetVerification", ctx, 2).Return(domain.CardVerification{CardID: 2, References: []string{"ref-1", "ref-2"}, Attempts: maxVerifyAttempts}, nil)
+			},
+			processorMock: func(m *mock.Mock) {},
+			expectedError: ErrCardLocked,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+			processor := new(processorMock)
+			testCase.processorMock(&processor.Mock)
+
+			s := &service{cardsRepository: repoMock, processor: processor, now: func() time.Time { return now }}
+
+			result, err := s.StartVerification(ctx, 1, 2)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, result)
+			repoMock.AssertExpectations(t)
+			processor.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_service_Verify(t *testing.T) {
+	ctx := context.Background()
+	pending := domain.Card{ID: 2, AccountID: 1, VerificationStatus: domain.CardVerificationPending}
+	verification := domain.CardVerification{
+		CardID:     2,
+		Amounts:    []decimal.Decimal{decimal.RequireFromString("0.17"), decimal.RequireFromString("0.42")},
+		References: []string{"ref-1", "ref-2"},
+	}
+	withAttempts := func(attempts int, status string) domain.CardVerification {
+		v := verification
+		v.Attempts = attempts
+		v.Status = status
+		return v
+	}
+	wrong := domain.CardVerifyRequest{Amounts: []decimal.Decimal{decimal.RequireFromString("0.17"), decimal.RequireFromString("0.24")}}
+
+	testCases := []struct {
+		name           string
+		rq             domain.CardVerifyRequest
+		repoMock       func(m *mock.Mock)
+		processorMock  func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.CardVerificationResult
+	}{
+		{
+			name:     "One amount",
+			rq:       domain.CardVerifyRequest{Amounts: []decimal.Decimal{decimal.RequireFromString("0.17")}},
+			repoMock: func(m *mock.Mock) {},
+			expectedError: &ValidationError{Fields: []FieldError{
+				{Field: "amounts", Message: "must have 2 amounts"},
+			}},
+		},
+		{
+			name: "Locked card",
+			rq:   wrong,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(domain.Card{ID: 2, VerificationStatus: domain.CardVerificationLocked}, nil)
+			},
+			expectedError: ErrCardLocked,
+		},
+		{
+			name: "Wrong amounts",
+			rq:   wrong,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("Attempt", ctx, 1, 2).Return(withAttempts(0, domain.CardVerificationPending), nil)
+			},
+			expectedError:  ErrAmountsMismatch,
+			expectedResult: domain.CardVerificationResult{Status: domain.CardVerificationPending, AttemptsLeft: 2},
+		},
+		{
+			name: "Last attempt locks the card",
+			rq:   wrong,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("Attempt", ctx, 1, 2).Return(withAttempts(2, domain.CardVerificationPending), nil)
+			},
+			processorMock: func(m *mock.Mock) {
+				m.On("Refund", ctx, "ref-1").Return(nil)
+				m.On("Refund", ctx, "ref-2").Return(nil)
+			},
+			expectedError:  ErrCardLocked,
+			expectedResult: domain.CardVerificationResult{Status: domain.CardVerificationLocked},
+		},
+		{
+			name: "Verified in any order",
+			rq:   domain.CardVerifyRequest{Amounts: []decimal.Decimal{decimal.RequireFromString("0.42"), decimal.RequireFromString("0.170")}},
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("Attempt", ctx, 1, 2).Return(withAttempts(0, domain.CardVerificationPending), nil)
+			},
+			processorMock: func(m *mock.Mock) {
+				m.On("Refund", ctx, "ref-1").Return(nil)
+				m.On("Refund", ctx, "ref-2").Return(nil)
+			},
+			expectedResult: domain.CardVerificationResult{Status: domain.CardVerificationVerified},
+		},
+		{
+			name: "Locked by a concurrent attempt",
+			rq:   wrong,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 2).Return(pending, nil)
+				m.On("Attempt", ctx, 1, 2).Return(withAttempts(maxVerifyAttempts, domain.CardVerificationLocked), nil)
+			},
+			expectedError: ErrCardLocked,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+			processor := new(processorMock)
+			if testCase.processorMock != nil {
+				testCase.processorMock(&processor.Mock)
+			}
+
+			s := &service{cardsRepository: repoMock, processor: processor, now: time.Now}
+
+			result, err := s.Verify(ctx, 1, 2, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, result)
+			repoMock.AssertExpectations(t)
+			processor.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package contacts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+const mysqlDuplicateEntry = 1062
+
+type Repository interface {
+	Save(ctx context.Context, contact domain.Contact) (int, error)
+	GetAll(ctx context.Context, accountID int) ([]domain.Contact, error)
+	GetByID(ctx context.Context, accountID, id int) (domain.Contact, error)
+	Rename(ctx context.Context, accountID, id int, nickname string) error
+	Delete(ctx context.Context, accountID, id int) error
+	GetRecentDestinations(ctx context.Context, accountID int, since time.Time) ([]domain.RecentDestination, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Save(ctx context.Context, contact domain.Contact) (int, error) {
+	query := "INSERT INTO contacts(account_id, destination_account_id, nickname, created_at) VALUES(?, ?, ?, ?);"
+	res, err := r.db.ExecContext(ctx, query, contact.AccountID, contact.DestinationAccountID, contact.Nickname, contact.CreatedAt)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return 0, ErrContactAlreadyExists
+		}
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetAll returns the contacts of the account sorted by nickname.
+func (r *repository) GetAll(ctx context.Context, accountID int) ([]domain.Contact, error) {
+	query := "SELECT c.id, c.account_id, c.destination_account_id, c.nickname, a.cvu, a.alias, c.created_at FROM contacts c " +
+		"JOIN accounts a ON a.id = c.destination_account_id WHERE c.account_id = ? ORDER BY c.nickname, c.id;"
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return []domain.Contact{}, err
+	}
+	defer rows.Close()
+
+	var contacts []domain.Contact
+
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return []domain.Contact{}, err
+		}
+
+		contacts = append(contacts, contact)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []domain.Contact{}, err
+	}
+
+	return contacts, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, accountID, id int) (domain.Contact, error) {
+	query := "SELECT c.id, c.account_id, c.destination_account_id, c.nickname, a.cvu, a.alias, c.created_at FROM contacts c " +
+		"JOIN accounts a ON a.id = c.destination_account_id WHERE c.id = ? AND c.account_id = ?;"
+	contact, err := scanContact(r.db.QueryRowContext(ctx, query, id, accountID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Contact{}, ErrContactNotFound
+		}
+		return domain.Contact{}, err
+	}
+
+	return contact, nil
+}
+
+func (r *repository) Rename(ctx context.Context, accountID, id int, nickname string) error {
+	query := "UPDATE contacts SET nickname = ? WHERE id = ? AND account_id = ?;"
+	_, err := r.db.ExecContext(ctx, query, nickname, id, accountID)
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, accountID, id int) error {
+	query := "DELETE FROM contacts WHERE id = ? AND account_id = ?;"
+	res, err := r.db.ExecContext(ctx, query, id, accountID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected < 1 {
+		return ErrContactNotFound
+	}
+
+	return nil
+}
+
+// GetRecentDestinations returns the accounts the account transferred to since
+// the given time, with how many transfers each got and when the last one was.
+func (r *repository) GetRecentDestinations(ctx context.Context, accountID int, since time.Time) ([]domain.RecentDestination, error) {
+	query := "SELECT t.destination_cvu, COALESCE(a.alias, ''), COALESCE(c.nickname, ''), COUNT(*), MAX(t.date_time) FROM transactions t " +
+		"LEFT JOIN accounts a ON a.cvu = t.destination_cvu " +
+		"LEFT JOIN contacts c ON c.account_id = t.account_id AND c.destination_account_id = a.id " +
+		"WHERE t.account_id = ? AND t.type = ? AND t.date_time >= ? " +
+		"GROUP BY t.destination_cvu, a.alias, c.nickname;"
+	rows, err := r.db.QueryContext(ctx, query, accountID, domain.TransactionTypeTransferOut, since)
+	if err != nil {
+		return []domain.RecentDestination{}, err
+	}
+	defer rows.Close()
+
+	var destinations []domain.RecentDestination
+
+	for rows.Next() {
+		var destination domain.RecentDestination
+		err = rows.Scan(&destination.CVU, &destination.Alias, &destination.Nickname, &destination.Transfers, &destination.LastTransferAt)
+		if err != nil {
+			return []domain.RecentDestination{}, err
+		}
+
+		destinations = append(destinations, destination)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []domain.RecentDestination{}, err
+	}
+
+	return destinations, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanContact(row scanner) (domain.Contact, error) {
+	var contact domain.Contact
+	var alias sql.NullString
+	err := row.Scan(&contact.ID, &contact.AccountID, &contact.DestinationAccountID, &contact.Nickname, &contact.CVU, &alias, &contact.CreatedAt)
+	if err != nil {
+		return domain.Contact{}, err
+	}
+	contact.Alias = alias.String
+
+	return contact, nil
+}
@@ -0,0 +1,161 @@
+package contacts
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var (
+	ErrContactNotFound      = errors.New("contact not found")
+	ErrContactAlreadyExists = errors.New("destination is already a contact")
+	ErrInvalidNickname      = errors.New("nickname must have between 1 and 30 characters")
+	ErrSelfContact          = errors.New("cannot save the own account as a contact")
+)
+
+const (
+	maxNicknameLength = 30
+	// only transfers made in this window count for recent destinations
+	recentDestinationsWindow = 180 * 24 * time.Hour
+	maxRecentDestinations    = 10
+)
+
+type Service interface {
+	Create(ctx context.Context, accountID int, rq domain.ContactRequest) (domain.Contact, error)
+	GetAll(ctx context.Context, accountID int) ([]domain.Contact, error)
+	Rename(ctx context.Context, accountID, id int, rq domain.RenameContactRequest) (domain.Contact, error)
+	Delete(ctx context.Context, accountID, id int) error
+	GetRecentDestinations(ctx context.Context, accountID int) ([]domain.RecentDestination, error)
+}
+
+type service struct {
+	repository         Repository
+	accountsRepository accounts.Repository
+	now                func() time.Time
+}
+
+func NewService(repository Repository, accountsRepository accounts.Repository) Service {
+	return &service{repository: repository, accountsRepository: accountsRepository, now: time.Now}
+}
+
+func (s *service) Create(ctx context.Context, accountID int, rq domain.ContactRequest) (domain.Contact, error) {
+	nickname, err := normalizeNickname(rq.Nickname)
+	if err != nil {
+		return domain.Contact{}, err
+	}
+
+	destination, err := accounts.GetDestination(ctx, s.accountsRepository, strings.TrimSpace(rq.Destination))
+	if err != nil {
+		return domain.Contact{}, err
+	}
+
+	if destination.ID == accountID {
+		return domain.Contact{}, ErrSelfContact
+	}
+
+	contact := domain.Contact{
+		AccountID:            accountID,
+		Nickname:             nickname,
+		CVU:                  destination.CVU,
+		Alias:                destination.Alias,
+		CreatedAt:            s.now().UTC(),
+		DestinationAccountID: destination.ID,
+	}
+
+	contact.ID, err = s.repository.Save(ctx, contact)
+	if err != nil {
+		return domain.Contact{}, err
+	}
+
+	return contact, nil
+}
+
+func (s *service) GetAll(ctx context.Context, accountID int) ([]domain.Contact, error) {
+	contacts, err := s.repository.GetAll(ctx, accountID)
+	if err != nil {
+		return []domain.Contact{}, err
+	}
+
+	if contacts == nil {
+		return []domain.Contact{}, nil
+	}
+
+	return contacts, nil
+}
+
+func (s *service) Rename(ctx context.Context, accountID, id int, rq domain.RenameContactRequest) (domain.Contact, error) {
+	nickname, err := normalizeNickname(rq.Nickname)
+	if err != nil {
+		return domain.Contact{}, err
+	}
+
+	contact, err := s.repository.GetByID(ctx, accountID, id)
+	if err != nil {
+		return domain.Contact{}, err
+	}
+
+	if err = s.repository.Rename(ctx, accountID, id, nickname); err != nil {
+		return domain.Contact{}, err
+	}
+
+	contact.Nickname = nickname
+	return contact, nil
+}
+
+func (s *service) Delete(ctx context.Context, accountID, id int) error {
+	return s.repository.Delete(ctx, accountID, id)
+}
+
+// GetRecentDestinations returns the accounts transferred to in the last months,
+// most relevant first. Relevance grows with the number of transfers and decays
+// with the weeks since the last one, so a destination used often long ago does
+// not bury the one used yesterday.
+func (s *service) GetRecentDestinations(ctx context.Context, accountID int) ([]domain.RecentDestination, error) {
+	now := s.now().UTC()
+	destinations, err := s.repository.GetRecentDestinations(ctx, accountID, now.Add(-recentDestinationsWindow))
+	if err != nil {
+		return []domain.RecentDestination{}, err
+	}
+
+	sort.SliceStable(destinations, func(i, j int) bool {
+		si, sj := score(destinations[i], now), score(destinations[j], now)
+		if si != sj {
+			return si > sj
+		}
+		return destinations[i].LastTransferAt.After(destinations[j].LastTransferAt)
+	})
+
+	if len(destinations) > maxRecentDestinations {
+		destinations = destinations[:maxRecentDestinations]
+	}
+
+	if destinations == nil {
+		return []domain.RecentDestination{}, nil
+	}
+
+	return destinations, nil
+}
+
+func score(destination domain.RecentDestination, now time.Time) float64 {
+	weeks := now.Sub(destination.LastTransferAt).Hours() / (24 * 7)
+	if weeks < 0 {
+		weeks = 0
+	}
+
+	return float64(destination.Transfers) / (1 + weeks)
+}
+
+func normalizeNickname(nickname string) (string, error) {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" || utf8.RuneCountInString(nickname) > maxNicknameLength {
+		return "", ErrInvalidNickname
+	}
+
+	return nickname, nil
+}
@@ -0,0 +1,232 @@
+package contacts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) Save(ctx context.Context, contact domain.Contact) (int, error) {
+	args := r.Called(ctx, contact)
+	return args.Int(0), args.Error(1)
+}
+
+func (r *repositoryMock) GetAll(ctx context.Context, accountID int) ([]domain.Contact, error) {
+	args := r.Called(ctx, accountID)
+	return args.Get(0).([]domain.Contact), args.Error(1)
+}
+
+func (r *repositoryMock) GetByID(ctx context.Context, accountID, id int) (domain.Contact, error) {
+	args := r.Called(ctx, accountID, id)
+	return args.Get(0).(domain.Contact), args.Error(1)
+}
+
+func (r *repositoryMock) Rename(ctx context.Context, accountID, id int, nickname string) error {
+	args := r.Called(ctx, accountID, id, nickname)
+	return args.Error(0)
+}
+
+func (r *repositoryMock) Delete(ctx context.Context, accountID, id int) error {
+	args := r.Called(ctx, accountID, id)
+	return args.Error(0)
+}
+
+func (r *repositoryMock) GetRecentDestinations(ctx context.Context, accountID int, since time.Time) ([]domain.RecentDestination, error) {
+	args := r.Called(ctx, accountID, since)
+	return args.Get(0).([]domain.RecentDestination), args.Error(1)
+}
+
+type accountsRepositoryMock struct {
+	mock.Mock
+	accounts.Repository
+}
+
+func (r *accountsRepositoryMock) GetAccountByCVU(ctx context.Context, cvu string) (domain.Account, error) {
+	args := r.Called(ctx, cvu)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func (r *accountsRepositoryMock) GetAccountByAlias(ctx context.Context, alias string) (domain.Account, error) {
+	args := r.Called(ctx, alias)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func Test_service_Create(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 15, 9, 30, 0, 0, time.UTC)
+	destination := domain.Account{ID: 2, CVU: "0000003100000000000024", Alias: "casa.perro.gato"}
+
+	testCases := []struct {
+		name             string
+		rq               domain.ContactRequest
+		repoMock         func(m *mock.Mock)
+		accountsRepoMock func(m *mock.Mock)
+		expectedError    error
+	}{
+		{
+			name:          "Empty nickname",
+			rq:            domain.ContactRequest{Destination: destination.Alias, Nickname: "   "},
+			expectedError: ErrInvalidNickname,
+		},
+		{
+			name:          "Nickname too long",
+			rq:            domain.ContactRequest{Destination: destination.Alias, Nickname: "Juan Pérez de la cuenta del trabajo"},
+			expectedError: ErrInvalidNickname,
+		},
+		{
+			name:             "Mistyped CVU",
+			rq:               domain.ContactRequest{Destination: "0000003100000000000018", Nickname: "Juan"},
+			accountsRepoMock: func(m *mock.Mock) {},
+			expectedError:    accounts.ErrInvalidDestination,
+		},
+		{
+			name: "Destination not found",
+			rq:   domain.ContactRequest{Destination: "No.Existe.Alias", Nickname: "Juan"},
+			accountsRepoMock: func(m *mock.Mock) {
+				m.On("GetAccountByAlias", ctx, "no.existe.alias").Return(domain.Account{}, accounts.ErrAccountNotFound)
+			},
+			expectedError: accounts.ErrDestinationNotFound,
+		},
+		{
+			name: "Own account",
+			rq:   domain.ContactRequest{Destination: "0000003100000000000017", Nickname: "Yo"},
+			accountsRepoMock: func(m *mock.Mock) {
+				m.On("GetAccountByCVU", ctx, "0000003100000000000017").Return(domain.Account{ID: 1}, nil)
+			},
+			expectedError: ErrSelfContact,
+		},
+		{
+			name: "Already a contact",
+			rq:   domain.ContactRequest{Destination: destination.CVU, Nickname: "Juan"},
+			accountsRepoMock: func(m *mock.Mock) {
+				m.On("GetAccountByCVU", ctx, destination.CVU).Return(destination, nil)
+			},
+			repoMock: func(m *mock.Mock) {
+				m.On("Save", ctx, mock.Anything).Return(0, ErrContactAlreadyExists)
+			},
+			expectedError: ErrContactAlreadyExists,
+		},
+		{
+			name: "Saved by alias",
+			rq:   domain.ContactRequest{Destination: destination.Alias, Nickname: " Juan "},
+			accountsRepoMock: func(m *mock.Mock) {
+				m.On("GetAccountByAlias", ctx, destination.Alias).Return(destination, nil)
+			},
+			repoMock: func(m *mock.Mock) {
+				m.On("Save", ctx, domain.Contact{AccountID: 1, Nickname: "Juan", CVU: destination.CVU, Alias: destination.Alias,
+					CreatedAt: now, DestinationAccountID: destination.ID}).Return(5, nil)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			accountsRepoMock := new(accountsRepositoryMock)
+			if testCase.repoMock != nil {
+				testCase.repoMock(&repoMock.Mock)
+			}
+			if testCase.accountsRepoMock != nil {
+				testCase.accountsRepoMock(&accountsRepoMock.Mock)
+			}
+
+			s := &service{repository: repoMock, accountsRepository: accountsRepoMock, now: func() time.Time { return now }}
+
+			contact, err := s.Create(ctx, 1, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+			if err == nil {
+				assert.Equal(t, 5, contact.ID)
+				assert.Equal(t, "Juan", contact.Nickname)
+			}
+			repoMock.AssertExpectations(t)
+			accountsRepoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_service_Rename(t *testing.T) {
+	ctx := context.Background()
+	contact := domain.Contact{ID: 5, AccountID: 1, Nickname: "Juan", CVU: "0000003100000000000024"}
+
+	testCases := []struct {
+		name          string
+		nickname      string
+		repoMock      func(m *mock.Mock)
+		expectedError error
+	}{
+		{
+			name:          "Invalid nickname",
+			nickname:      "",
+			expectedError: ErrInvalidNickname,
+		},
+		{
+			name:     "Not found",
+			nickname: "Juancito",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 5).Return(domain.Contact{}, ErrContactNotFound)
+			},
+			expectedError: ErrContactNotFound,
+		},
+		{
+			name:     "Renamed",
+			nickname: "Juancito",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 5).Return(contact, nil)
+				m.On("Rename", ctx, 1, 5, "Juancito").Return(nil)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			if testCase.repoMock != nil {
+				testCase.repoMock(&repoMock.Mock)
+			}
+
+			s := NewService(repoMock, nil)
+
+			renamed, err := s.Rename(ctx, 1, 5, domain.RenameContactRequest{Nickname: testCase.nickname})
+
+			assert.Equal(t, testCase.expectedError, err)
+			if err == nil {
+				assert.Equal(t, testCase.nickname, renamed.Nickname)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_service_GetRecentDestinations(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-recentDestinationsWindow)
+
+	// paid often months ago, paid once yesterday, paid a few times last week
+	oldFavourite := domain.RecentDestination{CVU: "0000003100000000000017", Transfers: 6, LastTransferAt: now.AddDate(0, -3, 0)}
+	yesterday := domain.RecentDestination{CVU: "0000003100000000000024", Transfers: 1, LastTransferAt: now.AddDate(0, 0, -1)}
+	lastWeek := domain.RecentDestination{CVU: "2850590940090418135201", Nickname: "Alquiler", Transfers: 3, LastTransferAt: now.AddDate(0, 0, -7)}
+
+	repoMock := new(repositoryMock)
+	repoMock.On("GetRecentDestinations", ctx, 1, since).
+		Return([]domain.RecentDestination{oldFavourite, yesterday, lastWeek}, nil)
+	repoMock.On("GetRecentDestinations", ctx, 2, since).Return([]domain.RecentDestination(nil), nil)
+
+	s := &service{repository: repoMock, now: func() time.Time { return now }}
+
+	destinations, err := s.GetRecentDestinations(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.RecentDestination{lastWeek, yesterday, oldFavourite}, destinations)
+
+	destinations, err = s.GetRecentDestinations(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.RecentDestination{}, destinations)
+}
@@ -0,0 +1,41 @@
+package currency
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// ISO 4217 codes of the currencies an account can hold.
+const (
+	ARS = "ARS"
+	USD = "USD"
+)
+
+// Primary is the currency of accounts.balance and of every transfer, deposit
+// and fee. Other currencies are held in sub-accounts.
+const Primary = ARS
+
+// minorUnits is the number of decimals each currency is divided into.
+var minorUnits = map[string]int32{
+	ARS: 2,
+	USD: 2,
+}
+
+// Supported reports whether an account can hold code.
+func Supported(code string) bool {
+	_, ok := minorUnits[code]
+	return ok
+}
+
+// Round rounds amount to the minor units of the currency.
+func Round(code string, amount decimal.Decimal) (decimal.Decimal, error) {
+	units, ok := minorUnits[code]
+	if !ok {
+		return decimal.Decimal{}, ErrUnsupportedCurrency
+	}
+
+	return amount.Round(units), nil
+}
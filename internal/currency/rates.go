@@ -0,0 +1,98 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	ErrNoRatesFile  = errors.New("no exchange rates file given")
+)
+
+// RateProvider returns how many units of to one unit of from is worth.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// Rates maps a pair written as "FROM/TO", e.g. "USD/ARS", to its rate.
+type Rates map[string]decimal.Decimal
+
+// rate looks the pair up, falling back to the inverse of the opposite pair.
+func (r Rates) rate(from, to string) (decimal.Decimal, error) {
+	if rate, ok := r[from+"/"+to]; ok && rate.IsPositive() {
+		return rate, nil
+	}
+
+	if rate, ok := r[to+"/"+from]; ok && rate.IsPositive() {
+		return decimal.NewFromInt(1).Div(rate), nil
+	}
+
+	return decimal.Decimal{}, ErrRateNotFound
+}
+
+type memoryProvider struct {
+	rates Rates
+}
+
+// NewMemoryProvider returns a provider with fixed rates.
+func NewMemoryProvider(rates Rates) RateProvider {
+	return &memoryProvider{rates: rates}
+}
+
+func (m *memoryProvider) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	return m.rates.rate(from, to)
+}
+
+type fileProvider struct {
+	path string
+}
+
+// NewFileProvider returns a provider reading the rates from a JSON object in
+// path. The file is read on every lookup, so rates can be updated in place,
+// and once here so a missing or malformed file is found right away.
+func NewFileProvider(path string) (RateProvider, error) {
+	if _, err := readRates(path); err != nil {
+		return nil, err
+	}
+
+	return &fileProvider{path: path}, nil
+}
+
+func (f *fileProvider) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	rates, err := readRates(f.path)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return rates.rate(from, to)
+}
+
+func readRates(path string) (Rates, error) {
+	if path == "" {
+		return nil, ErrNoRatesFile
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates Rates
+	if err = json.Unmarshal(raw, &rates); err != nil {
+		return nil, fmt.Errorf("reading exchange rates %s: %w", path, err)
+	}
+
+	for pair, rate := range rates {
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("reading exchange rates %s: rate of %s must be positive", path, pair)
+		}
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,46 @@
+package currency
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	testCases := []struct {
+		name        string
+		path        string
+		expectError bool
+	}{
+		{name: "No path", path: "", expectError: true},
+		{name: "Missing file", path: filepath.Join(dir, "missing.json"), expectError: true},
+		{name: "Malformed file", path: write("malformed.json", `{"USD/ARS":`), expectError: true},
+		{name: "Non positive rate", path: write("zero.json", `{"USD/ARS": "0"}`), expectError: true},
+		{name: "Valid file", path: write("rates.json", `{"USD/ARS": "350.5"}`)},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			provider, err := NewFileProvider(testCase.path)
+			if testCase.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			rate, err := provider.Rate(context.Background(), "USD", "ARS")
+			assert.NoError(t, err)
+			assert.True(t, decimal.RequireFromString("350.5").Equal(rate))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Package cvu builds and validates CVUs (Clave Virtual Uniforme), the 22
+// digit account keys of payment service providers.
+//
+// A CVU has two blocks, each ending in a check digit computed as the BCRA
+// does for CBUs:
+//
+//	block 1: entity prefix (7 digits) + check digit
+//	block 2: account number (13 digits) + check digit
+package cvu
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+var (
+	ErrInvalidLength     = errors.New("cvu must have 22 digits")
+	ErrInvalidCharacters = errors.New("cvu must only have digits")
+	ErrInvalidCheckDigit = errors.New("invalid cvu check digit")
+	ErrInvalidPrefix     = errors.New("cvu entity prefix must have 7 digits")
+)
+
+const (
+	Length       = 22
+	PrefixLength = 7
+	// DefaultPrefix is used when no entity prefix is configured. PSPs get
+	// prefixes starting with 000.
+	DefaultPrefix = "0000003"
+
+	accountLength = 13
+)
+
+var (
+	prefixWeights  = []int{7, 1, 3, 9, 7, 1, 3}
+	accountWeights = []int{3, 9, 7, 1, 3, 9, 7, 1, 3, 9, 7, 1, 3}
+)
+
+type Generator struct {
+	block1 string
+}
+
+// NewGenerator returns a generator of CVUs for the entity prefix.
+func NewGenerator(prefix string) (*Generator, error) {
+	if len(prefix) != PrefixLength || !digits(prefix) {
+		return nil, ErrInvalidPrefix
+	}
+
+	return &Generator{block1: prefix + checkDigit(prefix, prefixWeights)}, nil
+}
+
+// Generate returns a valid CVU with a random account number. It is up to the
+// caller to make sure it is not taken.
+func (g *Generator) Generate() string {
+	account := fmt.Sprintf("%013d", rand.Int63n(1e13))
+	return g.block1 + account + checkDigit(account, accountWeights)
+}
+
+// Validate checks the length and the check digits of both blocks of cvu.
+func Validate(cvu string) error {
+	if len(cvu) != Length {
+		return ErrInvalidLength
+	}
+
+	if !digits(cvu) {
+		return ErrInvalidCharacters
+	}
+
+	block1, block2 := cvu[:PrefixLength+1], cvu[PrefixLength+1:]
+	if checkDigit(block1[:PrefixLength], prefixWeights) != block1[PrefixLength:] ||
+		checkDigit(block2[:accountLength], accountWeights) != block2[accountLength:] {
+		return ErrInvalidCheckDigit
+	}
+
+	return nil
+}
+
+// checkDigit weights each digit of block and returns the digit that takes
+// the sum to the next multiple of 10.
+func checkDigit(block string, weights []int) string {
+	sum := 0
+	for i, weight := range weights {
+		sum += int(block[i]-'0') * weight
+	}
+
+	return fmt.Sprint((10 - sum%10) % 10)
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,71 @@
+package cvu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cvu           string
+		expectedError error
+	}{
+		{
+			name: "Valid CBU",
+			cvu:  "2850590940090418135201",
+		},
+		{
+			name: "Valid CVU",
+			cvu:  "0000003100000000000017",
+		},
+		{
+			name:          "Too short",
+			cvu:           "000000310000000000001",
+			expectedError: ErrInvalidLength,
+		},
+		{
+			name:          "Not only digits",
+			cvu:           "000000310000000000001a",
+			expectedError: ErrInvalidCharacters,
+		},
+		{
+			name:          "Wrong first block check digit",
+			cvu:           "2850590840090418135201",
+			expectedError: ErrInvalidCheckDigit,
+		},
+		{
+			name:          "Wrong second block check digit",
+			cvu:           "2850590940090418135202",
+			expectedError: ErrInvalidCheckDigit,
+		},
+		{
+			name:          "Swapped digits",
+			cvu:           "2850590940090418153201",
+			expectedError: ErrInvalidCheckDigit,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedError, Validate(testCase.cvu))
+		})
+	}
+}
+
+func TestNewGenerator(t *testing.T) {
+	_, err := NewGenerator("123")
+	assert.Equal(t, ErrInvalidPrefix, err)
+
+	_, err = NewGenerator("000000a")
+	assert.Equal(t, ErrInvalidPrefix, err)
+
+	generator, err := NewGenerator("2850590")
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		cvu := generator.Generate()
+		assert.NoError(t, Validate(cvu))
+		assert.Equal(t, "28505909", cvu[:PrefixLength+1])
+	}
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/shopspring/decimal"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 type Account struct {
 	ID      int
@@ -32,3 +36,32 @@ type AccountDto struct {
 type Alias struct {
 	Alias string `json:"alias"`
 }
+
+// AccountLookup is what a payer is shown of a destination account to confirm
+// who they are paying.
+type AccountLookup struct {
+	HolderName string `json:"holder_name"`
+	DNI        string `json:"dni"`
+	Alias      string `json:"alias"`
+	CVU        string `json:"cvu"`
+	Active     bool   `json:"active"`
+}
+
+// AliasAvailability tells whether an alias can be taken and, if not, why.
+type AliasAvailability struct {
+	Alias     string `json:"alias"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type AliasChange struct {
+	OldAlias  string    `json:"old_alias"`
+	NewAlias  string    `json:"new_alias"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// Balance is what an account holds in one currency.
+type Balance struct {
+	Currency string          `json:"currency"`
+	Balance  decimal.Decimal `json:"balance"`
+}
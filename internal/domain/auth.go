@@ -26,14 +26,44 @@ type LoginRequest struct {
 	Password string
 }
 
+// LoginResponse is the session of a user. Token is the short lived access
+// token, the refresh token gets a new session once it expires.
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token            string `json:"token"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type ForgotRequest struct {
 	Email string `json:"email"`
 }
 
+// VerifyEmailRequest and ResetPasswordRequest carry the code of the email
+// sent by identity providers that do not host those pages themselves.
+type VerifyEmailRequest struct {
+	Code string `json:"code"`
+}
+
+type ResetPasswordRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// Identity is a user as the identity provider knows it.
+type Identity struct {
+	ID            string
+	FirstName     string
+	LastName      string
+	Email         string
+	Enabled       bool
+	EmailVerified bool
+}
+
 type GetUserFilters struct {
 	AuthID string
 	Email  string
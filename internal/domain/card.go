@@ -1,13 +1,35 @@
 package domain
 
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Verification states of a card. Cards start pending and can only fund
+// deposits once verified.
+const (
+	CardVerificationPending  = "pending"
+	CardVerificationVerified = "verified"
+	CardVerificationFailed   = "failed"
+	CardVerificationLocked   = "locked"
+)
+
+// Card is a card saved by an account. The number itself is kept in the vault
+// and the card only carries its token; clients get the last four digits. The
+// CVV is never stored.
 type Card struct {
-	ID             int    `json:"card_id"`
-	AccountID      int    `json:"account_id"`
-	PAN            string `json:"pan"`
-	HolderName     string `json:"holder_name"`
-	ExpirationDate string `json:"expiration_date"`
-	CID            string `json:"cvv"`
-	Type           string `json:"type"`
+	ID                 int    `json:"card_id"`
+	AccountID          int    `json:"account_id"`
+	LastFour           string `json:"last_four"`
+	HolderName         string `json:"holder_name"`
+	ExpirationDate     string `json:"expiration_date"`
+	Type               string `json:"type"`
+	Brand              string `json:"brand"`
+	Nickname           string `json:"nickname"`
+	Default            bool   `json:"default"`
+	VerificationStatus string `json:"verification_status"`
+	Token              string `json:"-"`
 }
 
 type CardDto struct {
@@ -16,4 +38,35 @@ type CardDto struct {
 	ExpirationDate string `json:"expiration_date"`
 	CID            string `json:"cvv"`
 	Type           string `json:"type"`
+	// Brand is detected from the PAN, it is not sent by the client
+	Brand string `json:"-"`
+}
+
+// CardUpdate changes the fields that are sent, leaving the rest as they are.
+// Setting Default makes the card the one deposits are funded from when they do
+// not name a card.
+type CardUpdate struct {
+	HolderName *string `json:"holder_name"`
+	Nickname   *string `json:"nickname"`
+	Default    *bool   `json:"default"`
+}
+
+// CardVerification holds the small charges made to a card to prove the user
+// owns it, until the user confirms their amounts.
+type CardVerification struct {
+	CardID     int
+	Amounts    []decimal.Decimal
+	References []string
+	Attempts   int
+	Status     string
+	CreatedAt  time.Time
+}
+
+type CardVerifyRequest struct {
+	Amounts []decimal.Decimal `json:"amounts"`
+}
+
+type CardVerificationResult struct {
+	Status       string `json:"status"`
+	AttemptsLeft int    `json:"attempts_left"`
 }
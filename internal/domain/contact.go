@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Contact is a destination saved by an account under a nickname. CVU and
+// Alias are the current ones of the destination account.
+type Contact struct {
+	ID        int       `json:"id"`
+	AccountID int       `json:"account_id"`
+	Nickname  string    `json:"nickname"`
+	CVU       string    `json:"cvu"`
+	Alias     string    `json:"alias"`
+	CreatedAt time.Time `json:"created_at"`
+
+	DestinationAccountID int `json:"-"`
+}
+
+type ContactRequest struct {
+	Destination string `json:"destination"`
+	Nickname    string `json:"nickname"`
+}
+
+type RenameContactRequest struct {
+	Nickname string `json:"nickname"`
+}
+
+// RecentDestination is an account recently transferred to, with the nickname
+// it is saved under if it is a contact.
+type RecentDestination struct {
+	CVU            string    `json:"cvu"`
+	Alias          string    `json:"alias"`
+	Nickname       string    `json:"nickname,omitempty"`
+	Transfers      int       `json:"transfers"`
+	LastTransferAt time.Time `json:"last_transfer_at"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "github.com/shopspring/decimal"
+
+// FeePreview is what an operation will cost before it is confirmed.
+type FeePreview struct {
+	Operation string          `json:"operation"`
+	Amount    decimal.Decimal `json:"amount"`
+	Fee       decimal.Decimal `json:"fee"`
+	Total     decimal.Decimal `json:"total"`
+}
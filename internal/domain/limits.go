@@ -0,0 +1,29 @@
+package domain
+
+import "github.com/shopspring/decimal"
+
+// AccountLimits are the outgoing transfer limits in force for an account,
+// together with what has been used of them.
+type AccountLimits struct {
+	AccountID           int             `json:"account_id"`
+	Tier                string          `json:"tier"`
+	PerTransaction      decimal.Decimal `json:"per_transaction"`
+	Daily               decimal.Decimal `json:"daily"`
+	Monthly             decimal.Decimal `json:"monthly"`
+	MaxTransfersPerHour int             `json:"max_transfers_per_hour"`
+	DailyUsed           decimal.Decimal `json:"daily_used"`
+	MonthlyUsed         decimal.Decimal `json:"monthly_used"`
+	TransfersLastHour   int             `json:"transfers_last_hour"`
+	DailyRemaining      decimal.Decimal `json:"daily_remaining"`
+	MonthlyRemaining    decimal.Decimal `json:"monthly_remaining"`
+}
+
+// LimitsRequest sets the tier of an account and overrides some of its
+// limits. Limits left empty take the tier default.
+type LimitsRequest struct {
+	Tier                string              `json:"tier"`
+	PerTransaction      decimal.NullDecimal `json:"per_transaction"`
+	Daily               decimal.NullDecimal `json:"daily"`
+	Monthly             decimal.NullDecimal `json:"monthly"`
+	MaxTransfersPerHour *int                `json:"max_transfers_per_hour"`
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+const (
+	FrequencyOnce    = "once"
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+const (
+	ScheduledTransferActive    = "active"
+	ScheduledTransferPaused    = "paused"
+	ScheduledTransferCancelled = "cancelled"
+	ScheduledTransferCompleted = "completed"
+	ScheduledTransferFailed    = "failed"
+)
+
+const (
+	ExecutionSucceeded = "succeeded"
+	ExecutionFailed    = "failed"
+)
+
+type ScheduledTransfer struct {
+	ID          int    `json:"id"`
+	AccountID   int    `json:"account_id"`
+	Destination string `json:"destination"`
+	// DestinationAccountID is the account Destination pointed to when the
+	// transfer was scheduled, which is the one every run pays.
+	DestinationAccountID int             `json:"destination_account_id"`
+	Amount               decimal.Decimal `json:"amount"`
+	Description          string          `json:"description"`
+	Frequency            string          `json:"frequency"`
+	DayOfMonth           int             `json:"day_of_month,omitempty"`
+	NextRunAt            time.Time       `json:"next_run_at"`
+	Status               string          `json:"status"`
+	Attempts             int             `json:"attempts"`
+	LastError            string          `json:"last_error,omitempty"`
+	LastRunAt            *time.Time      `json:"last_run_at,omitempty"`
+	CreatedAt            time.Time       `json:"created_at"`
+	RunAfter             time.Time       `json:"-"`
+}
+
+type ScheduledTransferRequest struct {
+	Destination string          `json:"destination"`
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description"`
+	Frequency   string          `json:"frequency"`
+	DayOfMonth  int             `json:"day_of_month"`
+	StartAt     time.Time       `json:"start_at"`
+}
+
+// ScheduledRun is one occurrence of a scheduled transfer, identified by the
+// time it was due. Retries of a failed run keep the same RunAt.
+type ScheduledRun struct {
+	ScheduledTransferID int
+	RunAt               time.Time
+}
+
+type ScheduledTransferExecution struct {
+	ID                  int       `json:"id"`
+	ScheduledTransferID int       `json:"scheduled_transfer_id"`
+	TransactionID       int       `json:"transaction_id,omitempty"`
+	Status              string    `json:"status"`
+	Error               string    `json:"error,omitempty"`
+	ExecutedAt          time.Time `json:"executed_at"`
+}
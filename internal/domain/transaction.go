@@ -5,6 +5,16 @@ import (
 	"time"
 )
 
+const (
+	TransactionTypeDeposit     = "deposit"
+	TransactionTypeTransferIn  = "transfer_in"
+	TransactionTypeTransferOut = "transfer_out"
+	TransactionTypeAdjustment  = "adjustment"
+	TransactionTypeReversal    = "reversal"
+	TransactionTypeFee         = "fee"
+	TransactionTypeConversion  = "conversion"
+)
+
 type Transaction struct {
 	ID             int
 	Account        Account
@@ -22,6 +32,99 @@ type TransactionInfo struct {
 	DestinationCVU string          `json:"destination_cvu"`
 	Description    string          `json:"description"`
 	Amount         decimal.Decimal `json:"amount"`
+	Currency       string          `json:"currency"`
 	DateTime       time.Time       `json:"date_time"`
 	Type           string          `json:"type"`
+	ReversalOf     int             `json:"reversal_of,omitempty"`
+	ReversedBy     int             `json:"reversed_by,omitempty"`
+	JournalEntryID int             `json:"-"`
+	// ProcessorReference is the card charge of a deposit.
+	ProcessorReference string `json:"-"`
+}
+
+// TransferRequest moves Amount to Destination. DestinationAccountID and
+// ScheduledRun are set by the scheduler only: the first pays the account the
+// transfer was scheduled to instead of resolving Destination again, and the
+// second keeps the same occurrence of a scheduled transfer from being paid
+// twice.
+type TransferRequest struct {
+	Destination          string          `json:"destination"`
+	Amount               decimal.Decimal `json:"amount"`
+	Description          string          `json:"description"`
+	DestinationAccountID int             `json:"-"`
+	ScheduledRun         *ScheduledRun   `json:"-"`
+}
+
+// ReversalRequest asks to undo a transaction. Amount is optional, but if sent
+// it must be the whole amount of the transaction.
+type ReversalRequest struct {
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// ConversionRequest converts Amount, in the From currency, to the To currency
+// between the sub-accounts of the same account.
+type ConversionRequest struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// Conversion is the result of a conversion: the debit of the From sub-account
+// and the credit of the To sub-account, made at Rate.
+type Conversion struct {
+	Rate   decimal.Decimal `json:"rate"`
+	Debit  TransactionInfo `json:"debit"`
+	Credit TransactionInfo `json:"credit"`
+}
+
+// DepositRequest funds the account from one of its cards. If CardID is not sent
+// the default card is used.
+type DepositRequest struct {
+	CardID int             `json:"card_id"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type ActivityFilters struct {
+	From        time.Time
+	To          time.Time
+	Types       []string
+	MinAmount   decimal.NullDecimal
+	MaxAmount   decimal.NullDecimal
+	Description string
+	Cursor      string
+	Limit       int
+}
+
+type ActivityPage struct {
+	Transactions []TransactionInfo `json:"transactions"`
+	NextCursor   string            `json:"next_cursor,omitempty"`
+}
+
+type Receipt struct {
+	TransactionID    int             `json:"transaction_id"`
+	Type             string          `json:"type"`
+	OriginCVU        string          `json:"origin_cvu"`
+	OriginAlias      string          `json:"origin_alias"`
+	DestinationCVU   string          `json:"destination_cvu"`
+	DestinationAlias string          `json:"destination_alias"`
+	Description      string          `json:"description"`
+	Amount           decimal.Decimal `json:"amount"`
+	DateTime         time.Time       `json:"date_time"`
+	VerificationCode string          `json:"verification_code"`
+}
+
+type Statement struct {
+	AccountID      int             `json:"account_id"`
+	CVU            string          `json:"cvu"`
+	Alias          string          `json:"alias"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance decimal.Decimal `json:"opening_balance"`
+	ClosingBalance decimal.Decimal `json:"closing_balance"`
+	Lines          []StatementLine `json:"lines"`
+}
+
+type StatementLine struct {
+	TransactionInfo
+	Balance decimal.Decimal `json:"balance"`
 }
@@ -0,0 +1,97 @@
+package exchange
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+type Repository interface {
+	Convert(ctx context.Context, account domain.Account, from, to string, amount, converted, rate decimal.Decimal) (domain.Conversion, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+// Convert debits amount from the from sub-account and credits converted to
+// the to sub-account in a single SQL transaction. Both legs go through the
+// exchange accounts of their currency, so each currency balances on its own.
+// The account row is locked first, serializing the conversion with every
+// other movement of the account.
+func (r *repository) Convert(ctx context.Context, account domain.Account, from, to string, amount, converted, rate decimal.Decimal) (domain.Conversion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err = accounts.GetBalanceForUpdate(ctx, tx, account.ID); err != nil {
+		return domain.Conversion{}, err
+	}
+
+	balance, err := accounts.GetCurrencyBalance(ctx, tx, account.ID, from)
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+
+	if balance.LessThan(amount) {
+		return domain.Conversion{}, ErrInsufficientFunds
+	}
+
+	now := time.Now().UTC()
+	description := fmt.Sprintf("Conversion %s to %s at %s", from, to, rate.String())
+	entryID, err := ledger.Post(ctx, tx, ledger.Entry{
+		Kind:        ledger.KindConversion,
+		Description: description,
+		Postings: []ledger.Posting{
+			{Account: ledger.CurrencyWalletAccount(account.ID, from), Amount: amount.Neg()},
+			{Account: ledger.ExchangeAccount(from), Amount: amount},
+			{Account: ledger.ExchangeAccount(to), Amount: converted.Neg()},
+			{Account: ledger.CurrencyWalletAccount(account.ID, to), Amount: converted},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+
+	debit := domain.TransactionInfo{
+		AccountID:      account.ID,
+		OriginCVU:      account.CVU,
+		DestinationCVU: account.CVU,
+		Description:    description,
+		Amount:         amount.Neg(),
+		Currency:       from,
+		DateTime:       now,
+		Type:           domain.TransactionTypeConversion,
+		JournalEntryID: entryID,
+	}
+	if debit.ID, err = transactions.Save(ctx, tx, debit); err != nil {
+		return domain.Conversion{}, err
+	}
+
+	credit := debit
+	credit.Amount = converted
+	credit.Currency = to
+	if credit.ID, err = transactions.Save(ctx, tx, credit); err != nil {
+		return domain.Conversion{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.Conversion{}, err
+	}
+
+	return domain.Conversion{Rate: rate, Debit: debit, Credit: credit}, nil
+}
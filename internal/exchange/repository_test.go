@@ -0,0 +1,73 @@
+package exchange
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+)
+
+func TestRepositoryConvertSuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	account := domain.Account{ID: 1, CVU: "0000000000000000000001"}
+	amount := decimal.NewFromInt(10)
+	converted := decimal.NewFromInt(3500)
+	rate := decimal.NewFromInt(350)
+	description := "Conversion USD to ARS at 350"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM currency_accounts WHERE account_id = ? AND currency = ? FOR UPDATE;")).WithArgs(1, currency.USD).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("20.00"))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindConversion, description, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(4, "wallet:1:USD", amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("INSERT INTO currency_accounts").ExpectExec().WithArgs(1, currency.USD, amount.Neg()).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(4, ledger.ExchangeAccount(currency.USD), amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(4, ledger.ExchangeAccount(currency.ARS), converted.Neg()).WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(4, "wallet:1", converted).WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(converted, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, account.CVU, account.CVU, description, amount.Neg(), currency.USD, sqlmock.AnyArg(), domain.TransactionTypeConversion, 4, nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, account.CVU, account.CVU, description, converted, currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeConversion, 4, nil, nil).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	conversion, err := repo.Convert(context.Background(), account, currency.USD, currency.ARS, amount, converted, rate)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, conversion.Debit.ID)
+	assert.Equal(t, 8, conversion.Credit.ID)
+	assert.Equal(t, currency.ARS, conversion.Credit.Currency)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryConvertInsufficientFunds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM currency_accounts WHERE account_id = ? AND currency = ? FOR UPDATE;")).WithArgs(1, currency.USD).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	_, err = repo.Convert(context.Background(), domain.Account{ID: 1}, currency.USD, currency.ARS, decimal.NewFromInt(10), decimal.NewFromInt(3500), decimal.NewFromInt(350))
+	assert.Equal(t, ErrInsufficientFunds, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,81 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var (
+	ErrSameCurrency      = errors.New("cannot convert a currency to itself")
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+type Service interface {
+	GetBalances(ctx context.Context, accountID int) ([]domain.Balance, error)
+	Convert(ctx context.Context, accountID int, rq domain.ConversionRequest) (domain.Conversion, error)
+}
+
+type service struct {
+	repository         Repository
+	accountsRepository accounts.Repository
+	rates              currency.RateProvider
+}
+
+func NewService(repository Repository, accountsRepository accounts.Repository, rates currency.RateProvider) Service {
+	return &service{
+		repository:         repository,
+		accountsRepository: accountsRepository,
+		rates:              rates,
+	}
+}
+
+func (s *service) GetBalances(ctx context.Context, accountID int) ([]domain.Balance, error) {
+	return s.accountsRepository.GetBalances(ctx, accountID)
+}
+
+// Convert converts between two sub-accounts of the account at the current
+// rate. The amount must be expressible in the minor units of its currency and
+// the converted amount is rounded to those of the target currency.
+func (s *service) Convert(ctx context.Context, accountID int, rq domain.ConversionRequest) (domain.Conversion, error) {
+	if !currency.Supported(rq.From) || !currency.Supported(rq.To) {
+		return domain.Conversion{}, currency.ErrUnsupportedCurrency
+	}
+
+	if rq.From == rq.To {
+		return domain.Conversion{}, ErrSameCurrency
+	}
+
+	amount, err := currency.Round(rq.From, rq.Amount)
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+	if !amount.IsPositive() || !amount.Equal(rq.Amount) {
+		return domain.Conversion{}, ErrInvalidAmount
+	}
+
+	account, err := s.accountsRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+
+	rate, err := s.rates.Rate(ctx, rq.From, rq.To)
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+
+	converted, err := currency.Round(rq.To, amount.Mul(rate))
+	if err != nil {
+		return domain.Conversion{}, err
+	}
+	// too small to be worth a minor unit of the target currency
+	if !converted.IsPositive() {
+		return domain.Conversion{}, ErrInvalidAmount
+	}
+
+	return s.repository.Convert(ctx, account, rq.From, rq.To, amount, converted, rate)
+}
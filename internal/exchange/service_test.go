@@ -0,0 +1,105 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) Convert(ctx context.Context, account domain.Account, from, to string, amount, converted, rate decimal.Decimal) (domain.Conversion, error) {
+	args := r.Called(ctx, account, from, to, amount.String(), converted.String(), rate.String())
+	return args.Get(0).(domain.Conversion), args.Error(1)
+}
+
+type accountsRepositoryMock struct {
+	mock.Mock
+	accounts.Repository
+}
+
+func (r *accountsRepositoryMock) GetAccountByID(ctx context.Context, id int) (domain.Account, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func Test_service_Convert(t *testing.T) {
+	ctx := context.Background()
+	account := domain.Account{ID: 1, CVU: "0000000000000000000001"}
+	rates := currency.NewMemoryProvider(currency.Rates{"USD/ARS": decimal.RequireFromString("350.00")})
+	testCases := []struct {
+		name          string
+		rq            domain.ConversionRequest
+		repoMock      func(m *mock.Mock)
+		expectedError error
+	}{
+		{
+			name:          "Unsupported currency",
+			rq:            domain.ConversionRequest{From: currency.ARS, To: "EUR", Amount: decimal.NewFromInt(100)},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: currency.ErrUnsupportedCurrency,
+		},
+		{
+			name:          "Same currency",
+			rq:            domain.ConversionRequest{From: currency.USD, To: currency.USD, Amount: decimal.NewFromInt(100)},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: ErrSameCurrency,
+		},
+		{
+			name:          "Amount below the minor unit",
+			rq:            domain.ConversionRequest{From: currency.USD, To: currency.ARS, Amount: decimal.RequireFromString("10.005")},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: ErrInvalidAmount,
+		},
+		{
+			name:          "Converted amount rounds to zero",
+			rq:            domain.ConversionRequest{From: currency.ARS, To: currency.USD, Amount: decimal.RequireFromString("1.00")},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: ErrInvalidAmount,
+		},
+		{
+			name: "USD to ARS",
+			rq:   domain.ConversionRequest{From: currency.USD, To: currency.ARS, Amount: decimal.RequireFromString("10.01")},
+			repoMock: func(m *mock.Mock) {
+				m.On("Convert", ctx, account, currency.USD, currency.ARS, "10.01", "3503.5", "350").Return(domain.Conversion{}, nil)
+			},
+		},
+		{
+			name: "ARS to USD rounds to cents with the inverse rate",
+			rq:   domain.ConversionRequest{From: currency.ARS, To: currency.USD, Amount: decimal.NewFromInt(1000)},
+			repoMock: func(m *mock.Mock) {
+				m.On("Convert", ctx, account, currency.ARS, currency.USD, "1000", "2.86", "0.0028571428571429").Return(domain.Conversion{}, nil)
+			},
+		},
+		{
+			name: "Insufficient funds",
+			rq:   domain.ConversionRequest{From: currency.USD, To: currency.ARS, Amount: decimal.NewFromInt(1)},
+			repoMock: func(m *mock.Mock) {
+				m.On("Convert", ctx, account, currency.USD, currency.ARS, "1", "350", "350").Return(domain.Conversion{}, ErrInsufficientFunds)
+			},
+			expectedError: ErrInsufficientFunds,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+			accountsMock := new(accountsRepositoryMock)
+			accountsMock.On("GetAccountByID", ctx, account.ID).Return(account, nil)
+
+			exchangeService := NewService(repoMock, accountsMock, rates)
+			_, err := exchangeService.Convert(ctx, account.ID, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package fees
+
+import (
+	"context"
+	"database/sql"
+)
+
+type repository struct {
+	db *sql.DB
+}
+
+// NewRepository returns a Source reading the rules from the fee_rules table.
+func NewRepository(db *sql.DB) Source {
+	return &repository{db: db}
+}
+
+func (r *repository) Rules(ctx context.Context) ([]Rule, error) {
+	query := "SELECT operation, tier, min_amount, max_amount, percentage, fixed FROM fee_rules ORDER BY priority, id;"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return []Rule{}, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+
+	for rows.Next() {
+		var rule Rule
+		var tier sql.NullString
+		if err = rows.Scan(&rule.Operation, &tier, &rule.MinAmount, &rule.MaxAmount, &rule.Percentage, &rule.Fixed); err != nil {
+			return []Rule{}, err
+		}
+		rule.Tier = tier.String
+
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []Rule{}, err
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,106 @@
+package fees
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/shopspring/decimal"
+)
+
+// Operations that can be charged a fee.
+const (
+	OperationDeposit  = "deposit"
+	OperationTransfer = "transfer"
+)
+
+// Rule charges Percentage of the amount plus Fixed to the operations of its
+// type whose amount is in [MinAmount, MaxAmount). An empty Tier matches every
+// tier and an invalid MaxAmount leaves the band open.
+type Rule struct {
+	Operation  string              `json:"operation"`
+	Tier       string              `json:"tier"`
+	MinAmount  decimal.Decimal     `json:"min_amount"`
+	MaxAmount  decimal.NullDecimal `json:"max_amount"`
+	Percentage decimal.Decimal     `json:"percentage"`
+	Fixed      decimal.Decimal     `json:"fixed"`
+}
+
+// Source provides the fee rules, in priority order.
+type Source interface {
+	Rules(ctx context.Context) ([]Rule, error)
+}
+
+func (r Rule) matches(operation, tier string, amount decimal.Decimal) bool {
+	if r.Operation != operation || (r.Tier != "" && r.Tier != tier) {
+		return false
+	}
+
+	if amount.LessThan(r.MinAmount) {
+		return false
+	}
+
+	return !r.MaxAmount.Valid || amount.LessThan(r.MaxAmount.Decimal)
+}
+
+// validate rejects rules that could never apply as meant: an operation that
+// is not charged, negative fees and an empty band.
+func (r Rule) validate() error {
+	switch {
+	case r.Operation != OperationDeposit && r.Operation != OperationTransfer:
+		return fmt.Errorf("%w %q", ErrUnknownOperation, r.Operation)
+	case r.Percentage.IsNegative():
+		return errors.New("percentage must not be negative")
+	case r.Fixed.IsNegative():
+		return errors.New("fixed fee must not be negative")
+	case r.MaxAmount.Valid && r.MinAmount.GreaterThan(r.MaxAmount.Decimal):
+		return errors.New("min_amount is greater than max_amount")
+	}
+
+	return nil
+}
+
+// compute returns the fee of the first rule matching the operation, rounded
+// to cents. Operations no rule matches are free.
+func compute(rules []Rule, operation, tier string, amount decimal.Decimal) decimal.Decimal {
+	for _, rule := range rules {
+		if rule.matches(operation, tier, amount) {
+			fee := amount.Mul(rule.Percentage).Div(decimal.NewFromInt(100)).Add(rule.Fixed)
+			return fee.Round(2)
+		}
+	}
+
+	return decimal.Zero
+}
+
+type fileSource struct {
+	rules []Rule
+}
+
+// NewFileSource reads the rules from a JSON file holding an array of rules.
+// The file is read once, and a file with an invalid rule is rejected.
+func NewFileSource(path string) (Source, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err = json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("reading fee rules %s: %w", path, err)
+	}
+
+	for i, rule := range rules {
+		if err = rule.validate(); err != nil {
+			return nil, fmt.Errorf("reading fee rules %s: rule %d: %w", path, i, err)
+		}
+	}
+
+	return &fileSource{rules: rules}, nil
+}
+
+func (f *fileSource) Rules(ctx context.Context) ([]Rule, error) {
+	return f.rules, nil
+}
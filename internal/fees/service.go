@@ -0,0 +1,84 @@
+package fees
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/limits"
+)
+
+var (
+	ErrUnknownOperation = errors.New("unknown operation")
+	ErrInvalidAmount    = errors.New("amount must be greater than zero")
+	ErrFeeExceedsAmount = errors.New("fee is greater than or equal to the amount")
+)
+
+type Service interface {
+	Compute(ctx context.Context, accountID int, operation string, amount decimal.Decimal) (decimal.Decimal, error)
+	Preview(ctx context.Context, accountID int, operation string, amount decimal.Decimal) (domain.FeePreview, error)
+}
+
+type service struct {
+	source           Source
+	limitsRepository limits.Repository
+}
+
+// NewService computes fees with the rules of source. The account tier is the
+// one its limits are set with.
+func NewService(source Source, limitsRepository limits.Repository) Service {
+	return &service{source: source, limitsRepository: limitsRepository}
+}
+
+func (s *service) Compute(ctx context.Context, accountID int, operation string, amount decimal.Decimal) (decimal.Decimal, error) {
+	rules, err := s.source.Rules(ctx)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	override, err := s.limitsRepository.GetOverride(ctx, accountID)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return compute(rules, operation, override.Tier, amount), nil
+}
+
+// Preview returns the fee of the operation. For transfers the total is what
+// leaves the account; for deposits, what is credited to it, which must be
+// positive.
+func (s *service) Preview(ctx context.Context, accountID int, operation string, amount decimal.Decimal) (domain.FeePreview, error) {
+	if operation != OperationDeposit && operation != OperationTransfer {
+		return domain.FeePreview{}, ErrUnknownOperation
+	}
+
+	if !amount.IsPositive() {
+		return domain.FeePreview{}, ErrInvalidAmount
+	}
+
+	fee, err := s.Compute(ctx, accountID, operation, amount)
+	if err != nil {
+		return domain.FeePreview{}, err
+	}
+
+	total := amount.Add(fee)
+	if operation == OperationDeposit {
+		if err = CheckDeposit(amount, fee); err != nil {
+			return domain.FeePreview{}, err
+		}
+		total = amount.Sub(fee)
+	}
+
+	return domain.FeePreview{Operation: operation, Amount: amount, Fee: fee, Total: total}, nil
+}
+
+// CheckDeposit rejects a deposit whose fee would take all of it, so a deposit
+// never debits the account.
+func CheckDeposit(amount, fee decimal.Decimal) error {
+	if fee.GreaterThanOrEqual(amount) {
+		return ErrFeeExceedsAmount
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/limits"
+)
+
+type limitsRepositoryMock struct {
+	mock.Mock
+}
+
+func (r *limitsRepositoryMock) GetOverride(ctx context.Context, accountID int) (limits.Override, error) {
+	args := r.Called(ctx, accountID)
+	return args.Get(0).(limits.Override), args.Error(1)
+}
+
+func (r *limitsRepositoryMock) SaveOverride(ctx context.Context, accountID int, override limits.Override) error {
+	args := r.Called(ctx, accountID, override)
+	return args.Error(0)
+}
+
+func (r *limitsRepositoryMock) GetUsage(ctx context.Context, accountID int, dayStart, monthStart, hourAgo time.Time) (limits.Usage, error) {
+	args := r.Called(ctx, accountID, dayStart, monthStart, hourAgo)
+	return args.Get(0).(limits.Usage), args.Error(1)
+}
+
+type staticSource []Rule
+
+func (s staticSource) Rules(ctx context.Context) ([]Rule, error) {
+	return s, nil
+}
+
+var rules = staticSource{
+	{Operation: OperationTransfer, Tier: limits.TierPremium, Percentage: decimal.Zero},
+	{Operation: OperationTransfer, MinAmount: decimal.NewFromInt(10000), Percentage: decimal.RequireFromString("0.5")},
+	{Operation: OperationDeposit, MaxAmount: decimal.NewNullDecimal(decimal.NewFromInt(1000)), Fixed: decimal.NewFromInt(10)},
+	{Operation: OperationDeposit, MinAmount: decimal.NewFromInt(1000), Percentage: decimal.RequireFromString("1.5"), Fixed: decimal.NewFromInt(2)},
+}
+
+func Test_compute(t *testing.T) {
+	testCases := []struct {
+		name      string
+		operation string
+		tier      string
+		amount    decimal.Decimal
+		expected  decimal.Decimal
+	}{
+		{
+			name:      "Transfer under every band is free",
+			operation: OperationTransfer,
+			tier:      limits.TierStandard,
+			amount:    decimal.NewFromInt(9999),
+			expected:  decimal.Zero,
+		},
+		{
+			name:      "Transfer in the percentage band",
+			operation: OperationTransfer,
+			tier:      limits.TierStandard,
+			amount:    decimal.NewFromInt(20000),
+			expected:  decimal.NewFromInt(100),
+		},
+		{
+			name:      "Premium tier rule has priority",
+			operation: OperationTransfer,
+			tier:      limits.TierPremium,
+			amount:    decimal.NewFromInt(20000),
+			expected:  decimal.Zero,
+		},
+		{
+			name:      "Fixed fee under the band maximum",
+			operation: OperationDeposit,
+			tier:      limits.TierStandard,
+			amount:    decimal.RequireFromString("999.99"),
+			expected:  decimal.NewFromInt(10),
+		},
+		{
+			name:      "Percentage plus fixed rounded to cents",
+			operation: OperationDeposit,
+			tier:      limits.TierStandard,
+			amount:    decimal.RequireFromString("1234.57"),
+			expected:  decimal.RequireFromString("20.52"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fee := compute(rules, testCase.operation, testCase.tier, testCase.amount)
+
+			assert.True(t, testCase.expected.Equal(fee), "expected %s, got %s", testCase.expected, fee)
+		})
+	}
+}
+
+func Test_service_Preview(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name           string
+		operation      string
+		amount         decimal.Decimal
+		expectedError  error
+		expectedResult domain.FeePreview
+	}{
+		{
+			name:          "Unknown operation",
+			operation:     "withdrawal",
+			amount:        decimal.NewFromInt(100),
+			expectedError: ErrUnknownOperation,
+		},
+		{
+			name:          "Invalid amount",
+			operation:     OperationTransfer,
+			amount:        decimal.Zero,
+			expectedError: ErrInvalidAmount,
+		},
+		{
+			name:      "Transfer total adds the fee",
+			operation: OperationTransfer,
+			amount:    decimal.NewFromInt(20000),
+			expectedResult: domain.FeePreview{
+				Operation: OperationTransfer,
+				Amount:    decimal.NewFromInt(20000),
+				Fee:       decimal.NewFromInt(100),
+				Total:     decimal.NewFromInt(20100),
+			},
+		},
+		{
+			name:      "Deposit total subtracts the fee",
+			operation: OperationDeposit,
+			amount:    decimal.NewFromInt(500),
+			expectedResult: domain.FeePreview{
+				Operation: OperationDeposit,
+				Amount:    decimal.NewFromInt(500),
+				Fee:       decimal.NewFromInt(10),
+				Total:     decimal.NewFromInt(490),
+			},
+		},
+		{
+			name:          "Deposit fee equal to the amount",
+			operation:     OperationDeposit,
+			amount:        decimal.NewFromInt(10),
+			expectedError: ErrFeeExceedsAmount,
+		},
+		{
+			name:          "Deposit fee greater than the amount",
+			operation:     OperationDeposit,
+			amount:        decimal.NewFromInt(4),
+			expectedError: ErrFeeExceedsAmount,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			limitsMock := new(limitsRepositoryMock)
+			limitsMock.On("GetOverride", ctx, 1).Return(limits.Override{Tier: limits.TierStandard}, nil)
+
+			feesService := NewService(rules, limitsMock)
+			preview, err := feesService.Preview(ctx, 1, testCase.operation, testCase.amount)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult.Operation, preview.Operation)
+			assert.True(t, testCase.expectedResult.Fee.Equal(preview.Fee))
+			assert.True(t, testCase.expectedResult.Total.Equal(preview.Total))
+		})
+	}
+}
+
+func TestNewFileSource(t *testing.T) {
+	testCases := []struct {
+		name          string
+		content       string
+		expectedRules int
+		expectedError string
+	}{
+		{
+			name:          "Valid rules",
+			content:       `[{"operation": "transfer", "min_amount": "0", "max_amount": "1000", "percentage": "1", "fixed": "0"}, {"operation": "deposit", "fixed": "5"}]`,
+			expectedRules: 2,
+		},
+		{
+			name:          "Unknown operation",
+			content:       `[{"operation": "withdrawal", "percentage": "1"}]`,
+			expectedError: `rule 0: unknown operation "withdrawal"`,
+		},
+		{
+			name:          "Negative percentage",
+			content:       `[{"operation": "transfer", "percentage": "1"}, {"operation": "transfer", "percentage": "-1"}]`,
+			expectedError: "rule 1: percentage must not be negative",
+		},
+		{
+			name:          "Negative fixed fee",
+			content:       `[{"operation": "deposit", "fixed": "-5"}]`,
+			expectedError: "rule 0: fixed fee must not be negative",
+		},
+		{
+			name:          "Min greater than max",
+			content:       `[{"operation": "transfer", "min_amount": "1000", "max_amount": "100", "percentage": "1"}]`,
+			expectedError: "rule 0: min_amount is greater than max_amount",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fees.json")
+			assert.NoError(t, os.WriteFile(path, []byte(testCase.content), 0o600))
+
+			source, err := NewFileSource(path)
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			rules, err := source.Rules(context.Background())
+			assert.NoError(t, err)
+			assert.Len(t, rules, testCase.expectedRules)
+		})
+	}
+
+	_, err := NewFileSource(filepath.Join(t.TempDir(), "missing.json"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
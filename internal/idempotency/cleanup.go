@@ -0,0 +1,31 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+)
+
+// RunCleanup deletes expired keys every interval until ctx is done.
+func RunCleanup(ctx context.Context, repository Repository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := repository.DeleteExpired(ctx, now.UTC())
+			if err != nil {
+				logger.Error(fmt.Sprintf("deleting expired idempotency keys: %s", err.Error()))
+				continue
+			}
+			if deleted > 0 {
+				logger.Info(fmt.Sprintf("deleted %d expired idempotency keys", deleted))
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrKeyNotFound      = errors.New("idempotency key not found")
+	ErrKeyAlreadyExists = errors.New("idempotency key already exists")
+)
+
+// mysqlDuplicateEntry is the MySQL error number for unique index violations.
+const mysqlDuplicateEntry = 1062
+
+// Record is a request made with an idempotency key. StatusCode is zero while
+// the original request is still being processed.
+type Record struct {
+	Key          string
+	Scope        string
+	RequestHash  string
+	StatusCode   int
+	ResponseBody []byte
+	ExpiresAt    time.Time
+}
+
+type Repository interface {
+	Get(ctx context.Context, key, scope string) (Record, error)
+	Reserve(ctx context.Context, record Record) error
+	Complete(ctx context.Context, key, scope string, statusCode int, responseBody []byte) error
+	Release(ctx context.Context, key, scope string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Get(ctx context.Context, key, scope string) (Record, error) {
+	query := "SELECT idempotency_key, scope, request_hash, status_code, response_body, expires_at FROM idempotency_keys " +
+		"WHERE idempotency_key = ? AND scope = ? AND expires_at > ?;"
+	row := r.db.QueryRowContext(ctx, query, key, scope, time.Now().UTC())
+
+	var record Record
+	var statusCode sql.NullInt64
+	err := row.Scan(&record.Key, &record.Scope, &record.RequestHash, &statusCode, &record.ResponseBody, &record.ExpiresAt)
+	if err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return Record{}, ErrKeyNotFound
+		}
+		return Record{}, err
+	}
+	record.StatusCode = int(statusCode.Int64)
+
+	return record, nil
+}
+
+// Reserve stores the key before the request is processed, so a concurrent
+// retry gets ErrKeyAlreadyExists instead of running the request twice.
+func (r *repository) Reserve(ctx context.Context, record Record) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// an expired key that was not cleaned up yet can be used again
+	_, err = tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE idempotency_key = ? AND scope = ? AND expires_at <= ?;",
+		record.Key, record.Scope, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO idempotency_keys(idempotency_key, scope, request_hash, expires_at) VALUES(?, ?, ?, ?);"
+	_, err = tx.ExecContext(ctx, query, record.Key, record.Scope, record.RequestHash, record.ExpiresAt)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return ErrKeyAlreadyExists
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) Complete(ctx context.Context, key, scope string, statusCode int, responseBody []byte) error {
+	query := "UPDATE idempotency_keys SET status_code = ?, response_body = ? WHERE idempotency_key = ? AND scope = ?;"
+	_, err := r.db.ExecContext(ctx, query, statusCode, responseBody, key, scope)
+	return err
+}
+
+// Release deletes a reserved key so the request can be retried.
+func (r *repository) Release(ctx context.Context, key, scope string) error {
+	query := "DELETE FROM idempotency_keys WHERE idempotency_key = ? AND scope = ?;"
+	_, err := r.db.ExecContext(ctx, query, key, scope)
+	return err
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?;", now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
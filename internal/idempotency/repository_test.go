@@ -0,0 +1,81 @@
+package idempotency
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryReserveSuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	record := Record{Key: "key-1", Scope: "POST /api/accounts/1/transfers", RequestHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND scope = ? AND expires_at <= ?;")).
+		WithArgs(record.Key, record.Scope, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).
+		WithArgs(record.Key, record.Scope, record.RequestHash, record.ExpiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	err = repo.Reserve(context.Background(), record)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryReserveDuplicated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	record := Record{Key: "key-1", Scope: "POST /api/accounts/1/transfers", RequestHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	err = repo.Reserve(context.Background(), record)
+	assert.Equal(t, ErrKeyAlreadyExists, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryGetNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT idempotency_key, scope, request_hash, status_code, response_body, expires_at FROM idempotency_keys").
+		WithArgs("key-1", "scope", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"idempotency_key", "scope", "request_hash", "status_code", "response_body", "expires_at"}))
+
+	repo := NewRepository(db)
+	_, err = repo.Get(context.Background(), "key-1", "scope")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryDeleteExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE expires_at <= ?;")).WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	repo := NewRepository(db)
+	deleted, err := repo.DeleteExpired(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,220 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+)
+
+var (
+	ErrEmptyEntry      = errors.New("journal entry without postings")
+	ErrUnbalancedEntry = errors.New("journal entry postings do not balance")
+	ErrInvalidPosting  = errors.New("invalid posting")
+)
+
+// System accounts hold the other side of the movements that enter or leave
+// the wallets.
+const (
+	// DepositsClearing is owed by the card processor for card deposits until settled.
+	DepositsClearing = "system:deposits_clearing"
+	// Fees collects the fees charged to the wallets.
+	Fees = "system:fees"
+	// Suspense holds corrections whose counterpart is still unknown.
+	Suspense = "system:suspense"
+	// OpeningBalances is the counterpart of the balances accounts already had
+	// when the ledger was introduced.
+	OpeningBalances = "system:opening_balances"
+)
+
+const (
+	walletPrefix   = "wallet:"
+	exchangePrefix = "system:exchange:"
+)
+
+// Entry kinds.
+const (
+	KindDeposit    = "deposit"
+	KindTransfer   = "transfer"
+	KindAdjustment = "adjustment"
+	KindReversal   = "reversal"
+	KindFee        = "fee"
+	KindConversion = "conversion"
+	KindOpening    = "opening"
+)
+
+// Posting moves Amount into a ledger account. Negative amounts move money out.
+type Posting struct {
+	Account string
+	Amount  decimal.Decimal
+}
+
+// Entry is a journal entry. Its postings always add up to zero, so money is
+// never created or lost, only moved between ledger accounts.
+type Entry struct {
+	ID          int
+	Kind        string
+	Description string
+	Postings    []Posting
+	CreatedAt   time.Time
+}
+
+// WalletAccount returns the ledger account of a user account.
+func WalletAccount(accountID int) string {
+	return walletPrefix + strconv.Itoa(accountID)
+}
+
+// CurrencyWalletAccount returns the ledger account of the sub-account a user
+// account holds in code. For the primary currency it is the WalletAccount.
+func CurrencyWalletAccount(accountID int, code string) string {
+	if code == currency.Primary {
+		return WalletAccount(accountID)
+	}
+
+	return WalletAccount(accountID) + ":" + code
+}
+
+// ExchangeAccount returns the system account conversions from and to code go
+// through. Its balance is the position of the wallet in that currency.
+func ExchangeAccount(code string) string {
+	return exchangePrefix + code
+}
+
+// walletID returns the user account and currency behind a wallet ledger account.
+func walletID(account string) (int, string, bool) {
+	if !strings.HasPrefix(account, walletPrefix) {
+		return 0, "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(account, walletPrefix), ":", 2)
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", false
+	}
+
+	if len(parts) == 1 {
+		return id, currency.Primary, true
+	}
+
+	return id, parts[1], true
+}
+
+func (e Entry) Validate() error {
+	if len(e.Postings) == 0 {
+		return ErrEmptyEntry
+	}
+
+	total := decimal.Zero
+	for _, posting := range e.Postings {
+		if posting.Account == "" || posting.Amount.IsZero() {
+			return ErrInvalidPosting
+		}
+		total = total.Add(posting.Amount)
+	}
+
+	if !total.IsZero() {
+		return ErrUnbalancedEntry
+	}
+
+	return nil
+}
+
+// Post records the entry within tx and applies the wallet postings to the
+// cached accounts.balance and currency_accounts projections, so both always
+// commit together.
+func Post(ctx context.Context, tx *sql.Tx, entry Entry) (int, error) {
+	return post(ctx, tx, entry, true)
+}
+
+// Adjust records an entry moving amount between the wallet and Suspense to
+// account for a balance that was changed outside the ledger. Unlike Post it
+// leaves accounts.balance untouched, since the balance already includes it.
+func Adjust(ctx context.Context, tx *sql.Tx, accountID int, amount decimal.Decimal, description string) (int, error) {
+	entry := Entry{
+		Kind:        KindAdjustment,
+		Description: description,
+		Postings: []Posting{
+			{Account: Suspense, Amount: amount.Neg()},
+			{Account: WalletAccount(accountID), Amount: amount},
+		},
+	}
+
+	return post(ctx, tx, entry, false)
+}
+
+// Balance derives the balance of a ledger account from its postings within tx,
+// so it sees the entries tx posted itself.
+func Balance(ctx context.Context, tx *sql.Tx, account string) (decimal.Decimal, error) {
+	query := "SELECT COALESCE(SUM(amount), 0) FROM postings WHERE ledger_account = ?;"
+
+	var balance decimal.Decimal
+	if err := tx.QueryRowContext(ctx, query, account).Scan(&balance); err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return balance, nil
+}
+
+// Open records the opening entry of a wallet whose balance predates the
+// ledger, moving amount from OpeningBalances. Like Adjust it leaves the
+// projections untouched.
+func Open(ctx context.Context, tx *sql.Tx, accountID int, amount decimal.Decimal) (int, error) {
+	entry := Entry{
+		Kind:        KindOpening,
+		Description: "Opening balance",
+		Postings: []Posting{
+			{Account: OpeningBalances, Amount: amount.Neg()},
+			{Account: WalletAccount(accountID), Amount: amount},
+		},
+	}
+
+	return post(ctx, tx, entry, false)
+}
+
+func post(ctx context.Context, tx *sql.Tx, entry Entry, updateProjection bool) (int, error) {
+	if err := entry.Validate(); err != nil {
+		return 0, err
+	}
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+
+	query := "INSERT INTO journal_entries(kind, description, created_at) VALUES(?, ?, ?);"
+	result, err := tx.ExecContext(ctx, query, entry.Kind, entry.Description, entry.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	entryID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO postings(journal_entry_id, ledger_account, amount) VALUES(?, ?, ?);")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, posting := range entry.Postings {
+		if _, err = stmt.ExecContext(ctx, entryID, posting.Account, posting.Amount); err != nil {
+			return 0, err
+		}
+
+		if id, code, ok := walletID(posting.Account); ok && updateProjection {
+			if err = accounts.UpdateCurrencyBalance(ctx, tx, id, code, posting.Amount); err != nil {
+				return 0, fmt.Errorf("updating balance of account %d: %w", id, err)
+			}
+		}
+	}
+
+	return int(entryID), nil
+}
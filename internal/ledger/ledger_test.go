@@ -0,0 +1,166 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+)
+
+func TestEntryValidate(t *testing.T) {
+	amount := decimal.NewFromInt(100)
+	testCases := []struct {
+		name          string
+		entry         Entry
+		expectedError error
+	}{
+		{
+			name:          "Without postings",
+			entry:         Entry{Kind: KindDeposit},
+			expectedError: ErrEmptyEntry,
+		},
+		{
+			name: "Unbalanced",
+			entry: Entry{Kind: KindTransfer, Postings: []Posting{
+				{Account: WalletAccount(1), Amount: amount.Neg()},
+				{Account: WalletAccount(2), Amount: decimal.NewFromInt(99)},
+			}},
+			expectedError: ErrUnbalancedEntry,
+		},
+		{
+			name: "Zero posting",
+			entry: Entry{Kind: KindTransfer, Postings: []Posting{
+				{Account: WalletAccount(1), Amount: decimal.Zero},
+			}},
+			expectedError: ErrInvalidPosting,
+		},
+		{
+			name: "Balanced with fee",
+			entry: Entry{Kind: KindDeposit, Postings: []Posting{
+				{Account: DepositsClearing, Amount: amount.Neg()},
+				{Account: WalletAccount(1), Amount: decimal.NewFromInt(98)},
+				{Account: Fees, Amount: decimal.NewFromInt(2)},
+			}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedError, testCase.entry.Validate())
+		})
+	}
+}
+
+func TestWalletID(t *testing.T) {
+	id, code, ok := walletID(WalletAccount(42))
+	assert.True(t, ok)
+	assert.Equal(t, 42, id)
+	assert.Equal(t, currency.ARS, code)
+
+	id, code, ok = walletID(CurrencyWalletAccount(42, currency.USD))
+	assert.True(t, ok)
+	assert.Equal(t, 42, id)
+	assert.Equal(t, currency.USD, code)
+
+	_, _, ok = walletID(Fees)
+	assert.False(t, ok)
+
+	_, _, ok = walletID(ExchangeAccount(currency.USD))
+	assert.False(t, ok)
+}
+
+func TestPostUpdatesWalletBalances(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	amount := decimal.NewFromInt(100)
+	entry := Entry{Kind: KindDeposit, Description: "deposit", Postings: []Posting{
+		{Account: DepositsClearing, Amount: amount.Neg()},
+		{Account: WalletAccount(1), Amount: amount},
+	}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO journal_entries(kind, description, created_at) VALUES(?, ?, ?);")).
+		WithArgs(KindDeposit, "deposit", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(3, DepositsClearing, amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(3, "wallet:1", amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	id, err := Post(context.Background(), tx, entry)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostRejectsUnbalancedEntry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	_, err = Post(context.Background(), tx, Entry{Postings: []Posting{{Account: WalletAccount(1), Amount: decimal.NewFromInt(10)}}})
+	assert.True(t, errors.Is(err, ErrUnbalancedEntry))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryGetBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(amount), 0) FROM postings WHERE ledger_account = ?;")).
+		WithArgs("wallet:1").WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("150.50"))
+
+	repo := NewRepository(db)
+	balance, err := repo.GetBalance(context.Background(), WalletAccount(1))
+	assert.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.RequireFromString("150.50")))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryBackfillOpeningBalances(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	amount := decimal.NewFromInt(250)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM accounts WHERE balance <> 0 ORDER BY id;")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	// account 1 predates the ledger and gets its opening entry
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(amount))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("wallet:1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(KindOpening, "Opening balance", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, OpeningBalances, amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, "wallet:1", amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	// account 2 already has postings
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(amount))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("wallet:2").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	opened, err := repo.BackfillOpeningBalances(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, opened)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
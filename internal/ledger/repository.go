@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+)
+
+type Repository interface {
+	GetBalance(ctx context.Context, account string) (decimal.Decimal, error)
+	BackfillOpeningBalances(ctx context.Context) (int, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+// GetBalance derives the balance of a ledger account from its postings.
+func (r *repository) GetBalance(ctx context.Context, account string) (decimal.Decimal, error) {
+	query := "SELECT COALESCE(SUM(amount), 0) FROM postings WHERE ledger_account = ?;"
+	row := r.db.QueryRowContext(ctx, query, account)
+
+	var balance decimal.Decimal
+	if err := row.Scan(&balance); err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return balance, nil
+}
+
+// BackfillOpeningBalances posts an opening entry for every account holding a
+// balance without any posting on its wallet, which is the case of the accounts
+// created before the ledger. It is safe to run again: accounts already opened
+// have postings and are skipped. It returns the number of entries posted.
+func (r *repository) BackfillOpeningBalances(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id FROM accounts WHERE balance <> 0 ORDER BY id;")
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	opened := 0
+	for _, id := range ids {
+		ok, err := r.open(ctx, id)
+		if err != nil {
+			return opened, err
+		}
+		if ok {
+			opened++
+		}
+	}
+
+	return opened, nil
+}
+
+// open posts the opening entry of the account with its row locked, so no
+// movement can post in between the check and the entry.
+func (r *repository) open(ctx context.Context, accountID int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	balance, err := accounts.GetBalanceForUpdate(ctx, tx, accountID)
+	if err != nil {
+		return false, err
+	}
+
+	var postings int
+	query := "SELECT COUNT(*) FROM postings WHERE ledger_account = ?;"
+	if err = tx.QueryRowContext(ctx, query, WalletAccount(accountID)).Scan(&postings); err != nil {
+		return false, err
+	}
+
+	if postings > 0 || balance.IsZero() {
+		return false, nil
+	}
+
+	if _, err = Open(ctx, tx, accountID, balance); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
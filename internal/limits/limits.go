@@ -0,0 +1,69 @@
+package limits
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnknownTier  = errors.New("unknown tier")
+	ErrInvalidLimit = errors.New("limits must be greater than zero")
+)
+
+// Account tiers.
+const (
+	TierStandard = "standard"
+	TierPremium  = "premium"
+)
+
+// Names of the limits, as reported by ErrLimitExceeded.
+const (
+	LimitPerTransaction = "per_transaction"
+	LimitDaily          = "daily"
+	LimitMonthly        = "monthly"
+	LimitVelocity       = "max_transfers_per_hour"
+)
+
+// Limits are the outgoing transfer limits of an account. Daily and monthly
+// limits apply to the current UTC calendar day and month, the velocity limit
+// to the last hour.
+type Limits struct {
+	PerTransaction      decimal.Decimal
+	Daily               decimal.Decimal
+	Monthly             decimal.Decimal
+	MaxTransfersPerHour int
+}
+
+// TierDefaults are the limits of each tier. Accounts without a tier set are
+// standard.
+var TierDefaults = map[string]Limits{
+	TierStandard: {
+		PerTransaction:      decimal.NewFromInt(100000),
+		Daily:               decimal.NewFromInt(250000),
+		Monthly:             decimal.NewFromInt(1000000),
+		MaxTransfersPerHour: 10,
+	},
+	TierPremium: {
+		PerTransaction:      decimal.NewFromInt(500000),
+		Daily:               decimal.NewFromInt(1500000),
+		Monthly:             decimal.NewFromInt(6000000),
+		MaxTransfersPerHour: 30,
+	},
+}
+
+// ErrLimitExceeded is returned when a transfer goes over one of the account
+// limits. Remaining is what can still be transferred under that limit, or
+// the number of transfers left for the velocity limit.
+type ErrLimitExceeded struct {
+	Limit     string
+	Remaining decimal.Decimal
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	if e.Limit == LimitVelocity {
+		return fmt.Sprintf("%s limit exceeded, %s transfers left", e.Limit, e.Remaining.String())
+	}
+	return fmt.Sprintf("%s limit exceeded, remaining allowance %s", e.Limit, e.Remaining.StringFixed(2))
+}
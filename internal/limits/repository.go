@@ -0,0 +1,109 @@
+package limits
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+// Override is the row of an account in account_limits. Limits that are not
+// valid take the tier default.
+type Override struct {
+	Tier                string
+	PerTransaction      decimal.NullDecimal
+	Daily               decimal.NullDecimal
+	Monthly             decimal.NullDecimal
+	MaxTransfersPerHour sql.NullInt64
+}
+
+// Usage is what an account has transferred out in each limit window.
+type Usage struct {
+	Daily             decimal.Decimal
+	Monthly           decimal.Decimal
+	TransfersLastHour int
+}
+
+type Repository interface {
+	GetOverride(ctx context.Context, accountID int) (Override, error)
+	SaveOverride(ctx context.Context, accountID int, override Override) error
+	GetUsage(ctx context.Context, accountID int, dayStart, monthStart, hourAgo time.Time) (Usage, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+// queryer is either the database or a transaction.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// GetOverride returns the standard tier without overrides for accounts that
+// have no row.
+func (r *repository) GetOverride(ctx context.Context, accountID int) (Override, error) {
+	return getOverride(ctx, r.db, accountID)
+}
+
+func getOverride(ctx context.Context, q queryer, accountID int) (Override, error) {
+	query := "SELECT tier, per_transaction, daily, monthly, max_transfers_per_hour FROM account_limits WHERE account_id = ?;"
+	row := q.QueryRowContext(ctx, query, accountID)
+
+	var override Override
+	err := row.Scan(&override.Tier, &override.PerTransaction, &override.Daily, &override.Monthly, &override.MaxTransfersPerHour)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Override{Tier: TierStandard}, nil
+		}
+		return Override{}, err
+	}
+
+	return override, nil
+}
+
+func (r *repository) SaveOverride(ctx context.Context, accountID int, override Override) error {
+	query := "INSERT INTO account_limits (account_id, tier, per_transaction, daily, monthly, max_transfers_per_hour) " +
+		"VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE tier = VALUES(tier), per_transaction = VALUES(per_transaction), " +
+		"daily = VALUES(daily), monthly = VALUES(monthly), max_transfers_per_hour = VALUES(max_transfers_per_hour);"
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, accountID, override.Tier, override.PerTransaction, override.Daily, override.Monthly,
+		override.MaxTransfersPerHour)
+	return err
+}
+
+// GetUsage adds up the outgoing transfers of the account since the start of
+// the day and of the month, and counts those made in the last hour.
+func (r *repository) GetUsage(ctx context.Context, accountID int, dayStart, monthStart, hourAgo time.Time) (Usage, error) {
+	return getUsage(ctx, r.db, accountID, dayStart, monthStart, hourAgo)
+}
+
+func getUsage(ctx context.Context, q queryer, accountID int, dayStart, monthStart, hourAgo time.Time) (Usage, error) {
+	since := monthStart
+	if hourAgo.Before(since) {
+		since = hourAgo
+	}
+
+	query := "SELECT COALESCE(SUM(CASE WHEN date_time >= ? THEN -amount ELSE 0 END), 0), " +
+		"COALESCE(SUM(CASE WHEN date_time >= ? THEN -amount ELSE 0 END), 0), " +
+		"COUNT(CASE WHEN date_time >= ? THEN 1 END) " +
+		"FROM transactions WHERE account_id = ? AND type = ? AND date_time >= ?;"
+	row := q.QueryRowContext(ctx, query, dayStart, monthStart, hourAgo, accountID, domain.TransactionTypeTransferOut, since)
+
+	var usage Usage
+	if err := row.Scan(&usage.Daily, &usage.Monthly, &usage.TransfersLastHour); err != nil {
+		return Usage{}, err
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,169 @@
+package limits
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+type Service interface {
+	GetLimits(ctx context.Context, accountID int) (domain.AccountLimits, error)
+	Update(ctx context.Context, accountID int, rq domain.LimitsRequest) (domain.AccountLimits, error)
+}
+
+type service struct {
+	repository Repository
+	now        func() time.Time
+}
+
+func NewService(repository Repository) Service {
+	return &service{repository: repository, now: time.Now}
+}
+
+func (s *service) GetLimits(ctx context.Context, accountID int) (domain.AccountLimits, error) {
+	override, err := s.repository.GetOverride(ctx, accountID)
+	if err != nil {
+		return domain.AccountLimits{}, err
+	}
+
+	usage, err := s.getUsage(ctx, accountID)
+	if err != nil {
+		return domain.AccountLimits{}, err
+	}
+
+	limits := resolve(override)
+	return domain.AccountLimits{
+		AccountID:           accountID,
+		Tier:                override.Tier,
+		PerTransaction:      limits.PerTransaction,
+		Daily:               limits.Daily,
+		Monthly:             limits.Monthly,
+		MaxTransfersPerHour: limits.MaxTransfersPerHour,
+		DailyUsed:           usage.Daily,
+		MonthlyUsed:         usage.Monthly,
+		TransfersLastHour:   usage.TransfersLastHour,
+		DailyRemaining:      remaining(limits.Daily, usage.Daily),
+		MonthlyRemaining:    remaining(limits.Monthly, usage.Monthly),
+	}, nil
+}
+
+func (s *service) Update(ctx context.Context, accountID int, rq domain.LimitsRequest) (domain.AccountLimits, error) {
+	if _, ok := TierDefaults[rq.Tier]; !ok {
+		return domain.AccountLimits{}, ErrUnknownTier
+	}
+
+	for _, limit := range []decimal.NullDecimal{rq.PerTransaction, rq.Daily, rq.Monthly} {
+		if limit.Valid && !limit.Decimal.IsPositive() {
+			return domain.AccountLimits{}, ErrInvalidLimit
+		}
+	}
+
+	override := Override{
+		Tier:           rq.Tier,
+		PerTransaction: rq.PerTransaction,
+		Daily:          rq.Daily,
+		Monthly:        rq.Monthly,
+	}
+	if rq.MaxTransfersPerHour != nil {
+		if *rq.MaxTransfersPerHour <= 0 {
+			return domain.AccountLimits{}, ErrInvalidLimit
+		}
+		override.MaxTransfersPerHour = sql.NullInt64{Int64: int64(*rq.MaxTransfersPerHour), Valid: true}
+	}
+
+	if err := s.repository.SaveOverride(ctx, accountID, override); err != nil {
+		return domain.AccountLimits{}, err
+	}
+
+	return s.GetLimits(ctx, accountID)
+}
+
+// Check returns an *ErrLimitExceeded if transferring amount out of the
+// account would go over any of its limits. It reads the limits and usage
+// within tx, which must hold the lock of the account row: that way concurrent
+// transfers are checked one after the other and cannot exceed the limits
+// together.
+func Check(ctx context.Context, tx *sql.Tx, accountID int, amount decimal.Decimal) error {
+	override, err := getOverride(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+
+	dayStart, monthStart, hourAgo := windows(time.Now().UTC())
+	usage, err := getUsage(ctx, tx, accountID, dayStart, monthStart, hourAgo)
+	if err != nil {
+		return err
+	}
+
+	return check(resolve(override), usage, amount)
+}
+
+// check compares amount with the per transaction limit, and amount plus the
+// usage with the windowed limits.
+func check(limits Limits, usage Usage, amount decimal.Decimal) error {
+	if amount.GreaterThan(limits.PerTransaction) {
+		return &ErrLimitExceeded{Limit: LimitPerTransaction, Remaining: limits.PerTransaction}
+	}
+
+	if usage.TransfersLastHour >= limits.MaxTransfersPerHour {
+		return &ErrLimitExceeded{Limit: LimitVelocity, Remaining: decimal.Zero}
+	}
+
+	if usage.Daily.Add(amount).GreaterThan(limits.Daily) {
+		return &ErrLimitExceeded{Limit: LimitDaily, Remaining: remaining(limits.Daily, usage.Daily)}
+	}
+
+	if usage.Monthly.Add(amount).GreaterThan(limits.Monthly) {
+		return &ErrLimitExceeded{Limit: LimitMonthly, Remaining: remaining(limits.Monthly, usage.Monthly)}
+	}
+
+	return nil
+}
+
+func (s *service) getUsage(ctx context.Context, accountID int) (Usage, error) {
+	dayStart, monthStart, hourAgo := windows(s.now().UTC())
+	return s.repository.GetUsage(ctx, accountID, dayStart, monthStart, hourAgo)
+}
+
+// windows returns the start of the daily, monthly and velocity windows at now.
+func windows(now time.Time) (time.Time, time.Time, time.Time) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return dayStart, monthStart, now.Add(-time.Hour)
+}
+
+// resolve fills the limits the override leaves empty with its tier defaults.
+func resolve(override Override) Limits {
+	limits, ok := TierDefaults[override.Tier]
+	if !ok {
+		limits = TierDefaults[TierStandard]
+	}
+
+	if override.PerTransaction.Valid {
+		limits.PerTransaction = override.PerTransaction.Decimal
+	}
+	if override.Daily.Valid {
+		limits.Daily = override.Daily.Decimal
+	}
+	if override.Monthly.Valid {
+		limits.Monthly = override.Monthly.Decimal
+	}
+	if override.MaxTransfersPerHour.Valid {
+		limits.MaxTransfersPerHour = int(override.MaxTransfersPerHour.Int64)
+	}
+
+	return limits
+}
+
+func remaining(limit, used decimal.Decimal) decimal.Decimal {
+	left := limit.Sub(used)
+	if left.IsNegative() {
+		return decimal.Zero
+	}
+
+	return left
+}
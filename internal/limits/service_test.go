@@ -0,0 +1,160 @@
+package limits
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) GetOverride(ctx context.Context, accountID int) (Override, error) {
+	args := r.Called(ctx, accountID)
+	return args.Get(0).(Override), args.Error(1)
+}
+
+func (r *repositoryMock) SaveOverride(ctx context.Context, accountID int, override Override) error {
+	args := r.Called(ctx, accountID, override)
+	return args.Error(0)
+}
+
+func (r *repositoryMock) GetUsage(ctx context.Context, accountID int, dayStart, monthStart, hourAgo time.Time) (Usage, error) {
+	args := r.Called(ctx, accountID, dayStart, monthStart, hourAgo)
+	return args.Get(0).(Usage), args.Error(1)
+}
+
+func Test_check(t *testing.T) {
+	override := Override{
+		Tier:                TierStandard,
+		Daily:               decimal.NewNullDecimal(decimal.NewFromInt(1000)),
+		MaxTransfersPerHour: sql.NullInt64{Int64: 3, Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		amount        decimal.Decimal
+		usage         Usage
+		expectedError error
+	}{
+		{
+			name:          "Over the tier per transaction limit",
+			amount:        decimal.NewFromInt(100001),
+			expectedError: &ErrLimitExceeded{Limit: LimitPerTransaction, Remaining: decimal.NewFromInt(100000)},
+		},
+		{
+			name:          "Too many transfers in the last hour",
+			amount:        decimal.NewFromInt(10),
+			usage:         Usage{TransfersLastHour: 3},
+			expectedError: &ErrLimitExceeded{Limit: LimitVelocity, Remaining: decimal.Zero},
+		},
+		{
+			name:          "Over the daily override",
+			amount:        decimal.NewFromInt(300),
+			usage:         Usage{Daily: decimal.NewFromInt(800), Monthly: decimal.NewFromInt(800), TransfersLastHour: 1},
+			expectedError: &ErrLimitExceeded{Limit: LimitDaily, Remaining: decimal.NewFromInt(200)},
+		},
+		{
+			name:          "Over the tier monthly limit",
+			amount:        decimal.NewFromInt(300),
+			usage:         Usage{Daily: decimal.Zero, Monthly: decimal.NewFromInt(999800)},
+			expectedError: &ErrLimitExceeded{Limit: LimitMonthly, Remaining: decimal.NewFromInt(200)},
+		},
+		{
+			name:   "Within limits",
+			amount: decimal.NewFromInt(200),
+			usage:  Usage{Daily: decimal.NewFromInt(800), Monthly: decimal.NewFromInt(800), TransfersLastHour: 2},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := check(resolve(override), testCase.usage, testCase.amount)
+
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+}
+
+func TestWindows(t *testing.T) {
+	now := time.Date(2023, 3, 15, 14, 30, 0, 0, time.UTC)
+
+	dayStart, monthStart, hourAgo := windows(now)
+	assert.Equal(t, time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC), dayStart)
+	assert.Equal(t, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), monthStart)
+	assert.Equal(t, now.Add(-time.Hour), hourAgo)
+}
+
+func Test_service_GetLimits(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 15, 14, 30, 0, 0, time.UTC)
+
+	repoMock := new(repositoryMock)
+	repoMock.On("GetOverride", ctx, 1).Return(Override{Tier: TierPremium}, nil)
+	repoMock.On("GetUsage", ctx, 1, mock.Anything, mock.Anything, mock.Anything).
+		Return(Usage{Daily: decimal.NewFromInt(2000000), Monthly: decimal.NewFromInt(2000000), TransfersLastHour: 4}, nil)
+
+	s := &service{repository: repoMock, now: func() time.Time { return now }}
+	accountLimits, err := s.GetLimits(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.AccountLimits{
+		AccountID:           1,
+		Tier:                TierPremium,
+		PerTransaction:      TierDefaults[TierPremium].PerTransaction,
+		Daily:               TierDefaults[TierPremium].Daily,
+		Monthly:             TierDefaults[TierPremium].Monthly,
+		MaxTransfersPerHour: TierDefaults[TierPremium].MaxTransfersPerHour,
+		DailyUsed:           decimal.NewFromInt(2000000),
+		MonthlyUsed:         decimal.NewFromInt(2000000),
+		TransfersLastHour:   4,
+		DailyRemaining:      decimal.Zero,
+		MonthlyRemaining:    decimal.NewFromInt(4000000),
+	}, accountLimits)
+}
+
+func Test_service_Update(t *testing.T) {
+	ctx := context.Background()
+	zero := 0
+
+	testCases := []struct {
+		name          string
+		rq            domain.LimitsRequest
+		expectedError error
+	}{
+		{name: "Unknown tier", rq: domain.LimitsRequest{Tier: "gold"}, expectedError: ErrUnknownTier},
+		{name: "Negative limit", rq: domain.LimitsRequest{Tier: TierStandard, Daily: decimal.NewNullDecimal(decimal.NewFromInt(-1))}, expectedError: ErrInvalidLimit},
+		{name: "Zero velocity", rq: domain.LimitsRequest{Tier: TierStandard, MaxTransfersPerHour: &zero}, expectedError: ErrInvalidLimit},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := &service{repository: new(repositoryMock), now: time.Now}
+
+			_, err := s.Update(ctx, 1, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+		})
+	}
+
+	t.Run("Update successfully", func(t *testing.T) {
+		five := 5
+		repoMock := new(repositoryMock)
+		repoMock.On("SaveOverride", ctx, 1, Override{Tier: TierPremium, MaxTransfersPerHour: sql.NullInt64{Int64: 5, Valid: true}}).Return(nil)
+		repoMock.On("GetOverride", ctx, 1).Return(Override{Tier: TierPremium, MaxTransfersPerHour: sql.NullInt64{Int64: 5, Valid: true}}, nil)
+		repoMock.On("GetUsage", ctx, 1, mock.Anything, mock.Anything, mock.Anything).Return(Usage{}, nil)
+
+		s := &service{repository: repoMock, now: time.Now}
+		accountLimits, err := s.Update(ctx, 1, domain.LimitsRequest{Tier: TierPremium, MaxTransfersPerHour: &five})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, accountLimits.MaxTransfersPerHour)
+		assert.Equal(t, TierDefaults[TierPremium].Daily, accountLimits.Daily)
+		repoMock.AssertExpectations(t)
+	})
+}
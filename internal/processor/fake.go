@@ -0,0 +1,81 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/vault"
+)
+
+// Fake is an in-process Processor that approves every charge except the ones
+// made with a declined PAN. It is meant for local development and tests.
+type Fake struct {
+	vault    vault.Detokenizer
+	mu       sync.Mutex
+	declined map[string]bool
+	charges  map[string]decimal.Decimal
+	refunded map[string]bool
+	next     int
+}
+
+func NewFake(vault vault.Detokenizer, declinedPANs ...string) *Fake {
+	declined := make(map[string]bool, len(declinedPANs))
+	for _, pan := range declinedPANs {
+		declined[pan] = true
+	}
+
+	return &Fake{
+		vault:    vault,
+		declined: declined,
+		charges:  map[string]decimal.Decimal{},
+		refunded: map[string]bool{},
+	}
+}
+
+func (f *Fake) Charge(ctx context.Context, card domain.Card, amount decimal.Decimal) (string, error) {
+	pan, err := f.vault.Detokenize(ctx, card.Token, "charge")
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.declined[pan] {
+		return "", ErrPaymentDeclined
+	}
+
+	f.next++
+	reference := fmt.Sprintf("fake-%d", f.next)
+	f.charges[reference] = amount
+
+	return reference, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, reference string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.refunded[reference] {
+		return nil
+	}
+	if _, ok := f.charges[reference]; !ok {
+		return fmt.Errorf("charge %s not found", reference)
+	}
+	delete(f.charges, reference)
+	f.refunded[reference] = true
+
+	return nil
+}
+
+// Charged returns the amount of a charge that was not refunded.
+func (f *Fake) Charged(reference string) (decimal.Decimal, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	amount, ok := f.charges[reference]
+	return amount, ok
+}
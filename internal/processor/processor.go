@@ -0,0 +1,25 @@
+package processor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var (
+	ErrPaymentDeclined = errors.New("payment declined")
+)
+
+// Processor is the card acquirer used to pull money from a card into the wallet.
+// Cards reach it tokenized; implementations are the only place outside the
+// vault where the card number is recovered.
+type Processor interface {
+	// Charge debits amount from the card and returns the processor reference of the operation.
+	Charge(ctx context.Context, card domain.Card, amount decimal.Decimal) (string, error)
+	// Refund reverts a previous charge identified by its reference. Refunding
+	// a charge already refunded succeeds without refunding it again, so a
+	// refund can be retried.
+	Refund(ctx context.Context, reference string) error
+}
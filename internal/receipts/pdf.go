@@ -0,0 +1,73 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var typeTitles = map[string]string{
+	domain.TransactionTypeDeposit:     "Deposit",
+	domain.TransactionTypeTransferIn:  "Transfer received",
+	domain.TransactionTypeTransferOut: "Transfer sent",
+	domain.TransactionTypeReversal:    "Reversal",
+	domain.TransactionTypeFee:         "Fee",
+	domain.TransactionTypeConversion:  "Currency conversion",
+}
+
+// RenderPDF writes the receipt as a single page PDF document.
+func RenderPDF(receipt domain.Receipt) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Receipt %d", receipt.TransactionID), true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.Cell(0, 12, "Digital Money House")
+	pdf.Ln(14)
+
+	title, ok := typeTitles[receipt.Type]
+	if !ok {
+		title = receipt.Type
+	}
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.Cell(0, 10, fmt.Sprintf("%s receipt", title))
+	pdf.Ln(14)
+
+	pdf.SetFont("Helvetica", "B", 22)
+	pdf.Cell(0, 12, "$ "+receipt.Amount.StringFixed(2))
+	pdf.Ln(16)
+
+	lines := [][2]string{
+		{"Date", receipt.DateTime.Format("2006-01-02 15:04:05 MST")},
+		{"Transaction", fmt.Sprint(receipt.TransactionID)},
+		{"Origin CVU", receipt.OriginCVU},
+		{"Origin alias", receipt.OriginAlias},
+		{"Destination CVU", receipt.DestinationCVU},
+		{"Destination alias", receipt.DestinationAlias},
+		{"Description", receipt.Description},
+	}
+	// core fonts are cp1252, translate names with accents
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	for _, line := range lines {
+		if line[1] == "" {
+			continue
+		}
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(50, 8, line[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.CellFormat(0, 8, tr(line[1]), "", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(8)
+	pdf.SetFont("Courier", "B", 12)
+	pdf.Cell(0, 8, "Verification code: "+receipt.VerificationCode)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
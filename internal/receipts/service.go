@@ -0,0 +1,110 @@
+package receipts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+var ErrInvalidSecret = errors.New("receipt secret must have at least 32 bytes")
+
+// minSecretLength keeps the verification codes out of reach of a brute force
+// of the secret: with an empty or short one anyone could forge them.
+const minSecretLength = 32
+
+type Service interface {
+	GetReceipt(ctx context.Context, accountID, transactionID int) (domain.Receipt, error)
+}
+
+type service struct {
+	transactionsRepository transactions.Repository
+	accountsRepository     accounts.Repository
+	secret                 []byte
+}
+
+// NewService creates the receipts service. secret signs the verification
+// code printed on every receipt. The code is deterministic, so support can
+// regenerate the receipt of a transaction and tell a genuine copy from an
+// edited one.
+func NewService(transactionsRepository transactions.Repository, accountsRepository accounts.Repository, secret string) Service {
+	return &service{
+		transactionsRepository: transactionsRepository,
+		accountsRepository:     accountsRepository,
+		secret:                 []byte(secret),
+	}
+}
+
+// ValidateSecret checks that secret is long enough to sign receipts.
+func ValidateSecret(secret string) error {
+	if len(secret) < minSecretLength {
+		return ErrInvalidSecret
+	}
+
+	return nil
+}
+
+func (s *service) GetReceipt(ctx context.Context, accountID, transactionID int) (domain.Receipt, error) {
+	trx, err := s.transactionsRepository.GetByID(ctx, accountID, transactionID)
+	if err != nil {
+		return domain.Receipt{}, err
+	}
+
+	receipt := domain.Receipt{
+		TransactionID:  trx.ID,
+		Type:           trx.Type,
+		OriginCVU:      trx.OriginCVU,
+		DestinationCVU: trx.DestinationCVU,
+		Description:    trx.Description,
+		Amount:         trx.Amount.Abs(),
+		DateTime:       trx.DateTime.UTC(),
+	}
+
+	if receipt.OriginAlias, err = s.getAlias(ctx, trx.OriginCVU); err != nil {
+		return domain.Receipt{}, err
+	}
+	if receipt.DestinationAlias, err = s.getAlias(ctx, trx.DestinationCVU); err != nil {
+		return domain.Receipt{}, err
+	}
+
+	receipt.VerificationCode = s.verificationCode(receipt)
+
+	return receipt, nil
+}
+
+// getAlias returns the current alias of the account owning cvu. Deposits have
+// no origin CVU and accounts may no longer exist, both render without alias.
+func (s *service) getAlias(ctx context.Context, cvu string) (string, error) {
+	if cvu == "" {
+		return "", nil
+	}
+
+	account, err := s.accountsRepository.GetAccountByCVU(ctx, cvu)
+	if err != nil {
+		if err == accounts.ErrAccountNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return account.Alias, nil
+}
+
+func (s *service) verificationCode(receipt domain.Receipt) string {
+	payload := fmt.Sprintf("%d|%s|%s|%s|%s", receipt.TransactionID, receipt.OriginCVU, receipt.DestinationCVU,
+		receipt.Amount.StringFixed(2), receipt.DateTime.UTC().Format(time.RFC3339))
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	sum := strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
+
+	return fmt.Sprintf("%s-%s-%s-%s", sum[0:4], sum[4:8], sum[8:12], sum[12:16])
+}
@@ -0,0 +1,165 @@
+package receipts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+type transactionsRepositoryMock struct {
+	mock.Mock
+	transactions.Repository
+}
+
+func (r *transactionsRepositoryMock) GetByID(ctx context.Context, accountID, transactionID int) (domain.TransactionInfo, error) {
+	args := r.Called(ctx, accountID, transactionID)
+	return args.Get(0).(domain.TransactionInfo), args.Error(1)
+}
+
+type accountsRepositoryMock struct {
+	mock.Mock
+	accounts.Repository
+}
+
+func (r *accountsRepositoryMock) GetAccountByCVU(ctx context.Context, cvu string) (domain.Account, error) {
+	args := r.Called(ctx, cvu)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func Test_service_GetReceipt(t *testing.T) {
+	var ctx = context.Background()
+	dateTime := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+	trx := domain.TransactionInfo{
+		ID:             7,
+		AccountID:      1,
+		OriginCVU:      "0000000000000000000001",
+		DestinationCVU: "0000000000000000000002",
+		Description:    "rent",
+		Amount:         decimal.NewFromInt(-50),
+		DateTime:       dateTime,
+		Type:           domain.TransactionTypeTransferOut,
+	}
+	testCases := []struct {
+		name          string
+		trxMock       func(m *mock.Mock)
+		accountsMock  func(m *mock.Mock)
+		expectedError error
+		expectedAlias [2]string
+	}{
+		{
+			name: "Transaction not found",
+			trxMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 7).Return(domain.TransactionInfo{}, transactions.ErrTransactionNotFound)
+			},
+			accountsMock:  func(m *mock.Mock) {},
+			expectedError: transactions.ErrTransactionNotFound,
+		},
+		{
+			name: "Accounts error",
+			trxMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 7).Return(trx, nil)
+			},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByCVU", ctx, trx.OriginCVU).Return(domain.Account{}, errors.New("error"))
+			},
+			expectedError: errors.New("error"),
+		},
+		{
+			name: "Receipt with deleted destination account",
+			trxMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 7).Return(trx, nil)
+			},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByCVU", ctx, trx.OriginCVU).Return(domain.Account{Alias: "casa.perro.gato"}, nil)
+				m.On("GetAccountByCVU", ctx, trx.DestinationCVU).Return(domain.Account{}, accounts.ErrAccountNotFound)
+			},
+			expectedAlias: [2]string{"casa.perro.gato", ""},
+		},
+		{
+			name: "Receipt successfully",
+			trxMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, 1, 7).Return(trx, nil)
+			},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByCVU", ctx, trx.OriginCVU).Return(domain.Account{Alias: "casa.perro.gato"}, nil)
+				m.On("GetAccountByCVU", ctx, trx.DestinationCVU).Return(domain.Account{Alias: "mesa.silla.sol"}, nil)
+			},
+			expectedAlias: [2]string{"casa.perro.gato", "mesa.silla.sol"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trxMock := new(transactionsRepositoryMock)
+			testCase.trxMock(&trxMock.Mock)
+			accountsMock := new(accountsRepositoryMock)
+			testCase.accountsMock(&accountsMock.Mock)
+
+			receiptsService := NewService(trxMock, accountsMock, "secret")
+
+			receipt, err := receiptsService.GetReceipt(ctx, 1, 7)
+
+			assert.Equal(t, testCase.expectedError, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, testCase.expectedAlias[0], receipt.OriginAlias)
+			assert.Equal(t, testCase.expectedAlias[1], receipt.DestinationAlias)
+			assert.True(t, receipt.Amount.Equal(decimal.NewFromInt(50)))
+			assert.Regexp(t, `^[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}$`, receipt.VerificationCode)
+		})
+	}
+}
+
+func Test_service_verificationCode(t *testing.T) {
+	receipt := domain.Receipt{
+		TransactionID:  7,
+		OriginCVU:      "0000000000000000000001",
+		DestinationCVU: "0000000000000000000002",
+		Amount:         decimal.NewFromInt(50),
+		DateTime:       time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC),
+	}
+	s := &service{secret: []byte("secret")}
+
+	code := s.verificationCode(receipt)
+	assert.Equal(t, code, s.verificationCode(receipt))
+
+	receipt.Amount = decimal.NewFromInt(500)
+	assert.NotEqual(t, code, s.verificationCode(receipt))
+
+	other := &service{secret: []byte("other")}
+	receipt.Amount = decimal.NewFromInt(50)
+	assert.NotEqual(t, code, other.verificationCode(receipt))
+}
+
+func TestRenderPDF(t *testing.T) {
+	receipt := domain.Receipt{
+		TransactionID:    7,
+		Type:             domain.TransactionTypeTransferOut,
+		OriginCVU:        "0000000000000000000001",
+		OriginAlias:      "casa.perro.gato",
+		DestinationCVU:   "0000000000000000000002",
+		DestinationAlias: "mesa.silla.sol",
+		Amount:           decimal.NewFromInt(50),
+		DateTime:         time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC),
+		VerificationCode: "ABCD-EF01-2345-6789",
+	}
+
+	pdf, err := RenderPDF(receipt)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+}
+
+func TestValidateSecret(t *testing.T) {
+	assert.Equal(t, ErrInvalidSecret, ValidateSecret(""))
+	assert.Equal(t, ErrInvalidSecret, ValidateSecret("secret"))
+	assert.NoError(t, ValidateSecret("0123456789abcdef0123456789abcdef"))
+}
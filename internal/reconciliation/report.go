@@ -0,0 +1,43 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func WriteJSON(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func WriteCSV(w io.Writer, report Report) error {
+	cw := csv.NewWriter(w)
+
+	rows := [][]string{{"account_id", "cvu", "stored_balance", "computed_balance", "ledger_balance", "difference", "adjustment_transaction_id", "adjustment_entry_id", "error"}}
+	for _, d := range report.Discrepancies {
+		adjustmentID, adjustmentEntry := "", ""
+		if d.AdjustmentID != 0 {
+			adjustmentID = fmt.Sprint(d.AdjustmentID)
+		}
+		if d.AdjustmentEntry != 0 {
+			adjustmentEntry = fmt.Sprint(d.AdjustmentEntry)
+		}
+
+		rows = append(rows, []string{
+			fmt.Sprint(d.AccountID),
+			d.CVU,
+			d.StoredBalance.StringFixed(2),
+			d.ComputedBalance.StringFixed(2),
+			d.LedgerBalance.StringFixed(2),
+			d.Difference.StringFixed(2),
+			adjustmentID,
+			adjustmentEntry,
+			d.Error,
+		})
+	}
+
+	return cw.WriteAll(rows)
+}
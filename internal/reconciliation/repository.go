@@ -0,0 +1,135 @@
+package reconciliation
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+const adjustmentDescription = "Balance reconciliation adjustment"
+
+// AccountBalance is the stored balance of an account next to the one computed
+// from its transactions, both in the primary currency.
+type AccountBalance struct {
+	AccountID int
+	CVU       string
+	Stored    decimal.Decimal
+	Computed  decimal.Decimal
+}
+
+type Repository interface {
+	GetAccountBalances(ctx context.Context) ([]AccountBalance, error)
+	Adjust(ctx context.Context, accountID int) (domain.TransactionInfo, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) GetAccountBalances(ctx context.Context) ([]AccountBalance, error) {
+	query := "SELECT a.id, a.cvu, a.balance, COALESCE(SUM(t.amount), 0) FROM accounts a " +
+		"LEFT JOIN transactions t ON t.account_id = a.id AND t.currency = ? GROUP BY a.id, a.cvu, a.balance ORDER BY a.id;"
+	rows, err := r.db.QueryContext(ctx, query, currency.Primary)
+	if err != nil {
+		return []AccountBalance{}, err
+	}
+	defer rows.Close()
+
+	var balances []AccountBalance
+
+	for rows.Next() {
+		var balance AccountBalance
+		var cvu sql.NullString
+		if err = rows.Scan(&balance.AccountID, &cvu, &balance.Stored, &balance.Computed); err != nil {
+			return []AccountBalance{}, err
+		}
+		balance.CVU = cvu.String
+
+		balances = append(balances, balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []AccountBalance{}, err
+	}
+
+	return balances, nil
+}
+
+// Adjust books the corrections that bring the account transactions and its
+// ledger wallet back to the stored balance, worked out again with the account
+// row locked so concurrent movements are taken into account. Each side is
+// compared with the stored balance on its own: a wallet already opened by the
+// backfill gets no second entry for the same legacy amount.
+// The adjustment transaction is saved only when the transactions disagree, and
+// its JournalEntryID is the ledger entry posted, if any. It returns a zero
+// transaction if there was nothing to adjust.
+func (r *repository) Adjust(ctx context.Context, accountID int) (domain.TransactionInfo, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+	defer tx.Rollback()
+
+	stored, err := accounts.GetBalanceForUpdate(ctx, tx, accountID)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	var computed decimal.Decimal
+	var cvu sql.NullString
+	query := "SELECT COALESCE(SUM(t.amount), 0), a.cvu FROM accounts a LEFT JOIN transactions t ON t.account_id = a.id AND t.currency = ? " +
+		"WHERE a.id = ? GROUP BY a.cvu;"
+	if err = tx.QueryRowContext(ctx, query, currency.Primary, accountID).Scan(&computed, &cvu); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	ledgerBalance, err := ledger.Balance(ctx, tx, ledger.WalletAccount(accountID))
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	transactionsCorrection := stored.Sub(computed)
+	ledgerCorrection := stored.Sub(ledgerBalance)
+	if transactionsCorrection.IsZero() && ledgerCorrection.IsZero() {
+		return domain.TransactionInfo{}, nil
+	}
+
+	trx := domain.TransactionInfo{
+		AccountID:      accountID,
+		DestinationCVU: cvu.String,
+		Description:    adjustmentDescription,
+		Amount:         transactionsCorrection,
+		DateTime:       time.Now().UTC(),
+		Type:           domain.TransactionTypeAdjustment,
+		Currency:       currency.Primary,
+	}
+
+	if !ledgerCorrection.IsZero() {
+		if trx.JournalEntryID, err = ledger.Adjust(ctx, tx, accountID, ledgerCorrection, adjustmentDescription); err != nil {
+			return domain.TransactionInfo{}, err
+		}
+	}
+
+	if !transactionsCorrection.IsZero() {
+		if trx.ID, err = transactions.Save(ctx, tx, trx); err != nil {
+			return domain.TransactionInfo{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	return trx, nil
+}
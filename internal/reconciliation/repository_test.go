@@ -0,0 +1,105 @@
+package reconciliation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+)
+
+// An account with a balance from before the ledger and no transactions: the
+// backfill opens its wallet, so the fix only books the missing transaction and
+// the next run finds nothing.
+func TestBackfillThenFix(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	cvu := "0000003100000000000017"
+	legacy := decimal.NewFromInt(100)
+	balanceColumns := []string{"id", "cvu", "balance", "computed"}
+
+	// backfill
+	mock.ExpectQuery("SELECT id FROM accounts").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(legacy))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("wallet:1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindOpening, "Opening balance", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, ledger.OpeningBalances, legacy.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, "wallet:1", legacy).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	// fix: the transactions are short of the legacy amount, the ledger is not
+	mock.ExpectQuery("SELECT a.id, a.cvu, a.balance").
+		WillReturnRows(sqlmock.NewRows(balanceColumns).AddRow(1, cvu, legacy, decimal.Zero))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM postings").WithArgs("wallet:1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(legacy))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(legacy))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(t.amount\\), 0\\), a.cvu").WithArgs("ARS", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"computed", "cvu"}).AddRow(decimal.Zero, cvu))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM postings").WithArgs("wallet:1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(legacy))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, "", cvu, adjustmentDescription, legacy, "ARS", sqlmock.AnyArg(), domain.TransactionTypeAdjustment, 0, nil, nil).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.ExpectCommit()
+
+	// second run
+	mock.ExpectQuery("SELECT a.id, a.cvu, a.balance").
+		WillReturnRows(sqlmock.NewRows(balanceColumns).AddRow(1, cvu, legacy, legacy))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM postings").WithArgs("wallet:1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(legacy))
+
+	ledgerRepository := ledger.NewRepository(db)
+	opened, err := ledgerRepository.BackfillOpeningBalances(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, opened)
+
+	service := NewService(NewRepository(db), ledgerRepository)
+
+	report, err := service.Reconcile(ctx, true)
+	assert.NoError(t, err)
+	assert.Len(t, report.Discrepancies, 1)
+	assert.Equal(t, 10, report.Discrepancies[0].AdjustmentID)
+
+	report, err = service.Reconcile(ctx, true)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Discrepancies)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryAdjustLedgerOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	amount := decimal.NewFromInt(80)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(amount))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(t.amount\\), 0\\), a.cvu").WithArgs("ARS", 4).
+		WillReturnRows(sqlmock.NewRows([]string{"computed", "cvu"}).AddRow(amount, "0000003100000000000024"))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM postings").WithArgs("wallet:4").
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(decimal.Zero))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindAdjustment, adjustmentDescription, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(12, ledger.Suspense, amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(12, "wallet:4", amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	trx, err := NewRepository(db).Adjust(context.Background(), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, trx.ID)
+	assert.Equal(t, 12, trx.JournalEntryID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,100 @@
+package reconciliation
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+)
+
+type Report struct {
+	GeneratedAt     time.Time     `json:"generated_at"`
+	AccountsChecked int           `json:"accounts_checked"`
+	Fix             bool          `json:"fix"`
+	Discrepancies   []Discrepancy `json:"discrepancies"`
+}
+
+type Discrepancy struct {
+	AccountID       int             `json:"account_id"`
+	CVU             string          `json:"cvu"`
+	StoredBalance   decimal.Decimal `json:"stored_balance"`
+	ComputedBalance decimal.Decimal `json:"computed_balance"`
+	LedgerBalance   decimal.Decimal `json:"ledger_balance"`
+	Difference      decimal.Decimal `json:"difference"`
+	AdjustmentID    int             `json:"adjustment_transaction_id,omitempty"`
+	AdjustmentEntry int             `json:"adjustment_entry_id,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// Fixed tells whether the run booked an adjustment for the discrepancy, be it
+// a transaction, a ledger entry or both.
+func (d Discrepancy) Fixed() bool {
+	return d.AdjustmentID != 0 || d.AdjustmentEntry != 0
+}
+
+type Service interface {
+	Reconcile(ctx context.Context, fix bool) (Report, error)
+}
+
+type service struct {
+	repository       Repository
+	ledgerRepository ledger.Repository
+	now              func() time.Time
+}
+
+func NewService(repository Repository, ledgerRepository ledger.Repository) Service {
+	return &service{repository: repository, ledgerRepository: ledgerRepository, now: time.Now}
+}
+
+// Reconcile compares every stored balance with the sum of the account
+// transactions and with the balance of its wallet in the ledger. With fix,
+// every discrepancy is adjusted: a difference with the transactions is booked
+// as an adjustment transaction and one with the ledger as an adjustment entry.
+// A failed fix is reported in the discrepancy instead of stopping the run.
+func (s *service) Reconcile(ctx context.Context, fix bool) (Report, error) {
+	balances, err := s.repository.GetAccountBalances(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		GeneratedAt:     s.now().UTC(),
+		AccountsChecked: len(balances),
+		Fix:             fix,
+		Discrepancies:   []Discrepancy{},
+	}
+
+	for _, balance := range balances {
+		ledgerBalance, err := s.ledgerRepository.GetBalance(ctx, ledger.WalletAccount(balance.AccountID))
+		if err != nil {
+			return Report{}, err
+		}
+
+		if balance.Stored.Equal(balance.Computed) && balance.Stored.Equal(ledgerBalance) {
+			continue
+		}
+
+		discrepancy := Discrepancy{
+			AccountID:       balance.AccountID,
+			CVU:             balance.CVU,
+			StoredBalance:   balance.Stored,
+			ComputedBalance: balance.Computed,
+			LedgerBalance:   ledgerBalance,
+			Difference:      balance.Stored.Sub(balance.Computed),
+		}
+
+		if fix {
+			trx, err := s.repository.Adjust(ctx, balance.AccountID)
+			if err != nil {
+				discrepancy.Error = err.Error()
+			}
+			discrepancy.AdjustmentID = trx.ID
+			discrepancy.AdjustmentEntry = trx.JournalEntryID
+		}
+
+		report.Discrepancies = append(report.Discrepancies, discrepancy)
+	}
+
+	return report, nil
+}
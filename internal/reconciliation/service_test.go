@@ -0,0 +1,145 @@
+package reconciliation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+)
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) GetAccountBalances(ctx context.Context) ([]AccountBalance, error) {
+	args := r.Called(ctx)
+	return args.Get(0).([]AccountBalance), args.Error(1)
+}
+
+func (r *repositoryMock) Adjust(ctx context.Context, accountID int) (domain.TransactionInfo, error) {
+	args := r.Called(ctx, accountID)
+	return args.Get(0).(domain.TransactionInfo), args.Error(1)
+}
+
+type ledgerRepositoryMock struct {
+	mock.Mock
+	ledger.Repository
+}
+
+func (r *ledgerRepositoryMock) GetBalance(ctx context.Context, account string) (decimal.Decimal, error) {
+	args := r.Called(ctx, account)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func Test_service_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	balances := []AccountBalance{
+		{AccountID: 1, CVU: "0000000000000000000001", Stored: decimal.NewFromInt(100), Computed: decimal.NewFromInt(100)},
+		{AccountID: 2, CVU: "0000000000000000000002", Stored: decimal.NewFromInt(150), Computed: decimal.NewFromInt(100)},
+		{AccountID: 3, CVU: "0000000000000000000003", Stored: decimal.NewFromInt(0), Computed: decimal.NewFromInt(20)},
+		{AccountID: 4, CVU: "0000000000000000000004", Stored: decimal.NewFromInt(80), Computed: decimal.NewFromInt(80)},
+	}
+	ledgerBalances := func(m *mock.Mock) {
+		m.On("GetBalance", ctx, "wallet:1").Return(decimal.NewFromInt(100), nil)
+		m.On("GetBalance", ctx, "wallet:2").Return(decimal.NewFromInt(100), nil)
+		m.On("GetBalance", ctx, "wallet:3").Return(decimal.NewFromInt(20), nil)
+		m.On("GetBalance", ctx, "wallet:4").Return(decimal.Zero, nil)
+	}
+
+	testCases := []struct {
+		name                  string
+		fix                   bool
+		repoMock              func(m *mock.Mock)
+		ledgerMock            func(m *mock.Mock)
+		expectedError         error
+		expectedDiscrepancies []Discrepancy
+	}{
+		{
+			name: "Error getting balances",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetAccountBalances", ctx).Return([]AccountBalance{}, errors.New("error"))
+			},
+			ledgerMock:    func(m *mock.Mock) {},
+			expectedError: errors.New("error"),
+		},
+		{
+			name: "Error getting ledger balance",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetAccountBalances", ctx).Return(balances, nil)
+			},
+			ledgerMock: func(m *mock.Mock) {
+				m.On("GetBalance", ctx, "wallet:1").Return(decimal.Decimal{}, errors.New("error"))
+			},
+			expectedError: errors.New("error"),
+		},
+		{
+			name: "Report only",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetAccountBalances", ctx).Return(balances, nil)
+			},
+			ledgerMock: ledgerBalances,
+			expectedDiscrepancies: []Discrepancy{
+				{AccountID: 2, CVU: balances[1].CVU, StoredBalance: balances[1].Stored, ComputedBalance: balances[1].Computed, LedgerBalance: decimal.NewFromInt(100), Difference: decimal.NewFromInt(50)},
+				{AccountID: 3, CVU: balances[2].CVU, StoredBalance: balances[2].Stored, ComputedBalance: balances[2].Computed, LedgerBalance: decimal.NewFromInt(20), Difference: decimal.NewFromInt(-20)},
+				{AccountID: 4, CVU: balances[3].CVU, StoredBalance: balances[3].Stored, ComputedBalance: balances[3].Computed, LedgerBalance: decimal.Zero, Difference: balances[3].Stored.Sub(balances[3].Computed)},
+			},
+		},
+		{
+			name: "Fix records adjustments, ledger-only adjustments and failures",
+			fix:  true,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetAccountBalances", ctx).Return(balances, nil)
+				m.On("Adjust", ctx, 2).Return(domain.TransactionInfo{ID: 10, JournalEntryID: 11}, nil)
+				m.On("Adjust", ctx, 3).Return(domain.TransactionInfo{}, errors.New("lock wait timeout"))
+				m.On("Adjust", ctx, 4).Return(domain.TransactionInfo{JournalEntryID: 12}, nil)
+			},
+			ledgerMock: ledgerBalances,
+			expectedDiscrepancies: []Discrepancy{
+				{AccountID: 2, CVU: balances[1].CVU, StoredBalance: balances[1].Stored, ComputedBalance: balances[1].Computed, LedgerBalance: decimal.NewFromInt(100), Difference: decimal.NewFromInt(50), AdjustmentID: 10, AdjustmentEntry: 11},
+				{AccountID: 3, CVU: balances[2].CVU, StoredBalance: balances[2].Stored, ComputedBalance: balances[2].Computed, LedgerBalance: decimal.NewFromInt(20), Difference: decimal.NewFromInt(-20), Error: "lock wait timeout"},
+				{AccountID: 4, CVU: balances[3].CVU, StoredBalance: balances[3].Stored, ComputedBalance: balances[3].Computed, LedgerBalance: decimal.Zero, Difference: balances[3].Stored.Sub(balances[3].Computed), AdjustmentEntry: 12},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+			ledgerMock := new(ledgerRepositoryMock)
+			testCase.ledgerMock(&ledgerMock.Mock)
+
+			reconciliationService := NewService(repoMock, ledgerMock)
+
+			report, err := reconciliationService.Reconcile(ctx, testCase.fix)
+
+			assert.Equal(t, testCase.expectedError, err)
+			if err == nil {
+				assert.Equal(t, len(balances), report.AccountsChecked)
+				assert.Equal(t, testCase.expectedDiscrepancies, report.Discrepancies)
+			}
+			repoMock.AssertExpectations(t)
+			ledgerMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	report := Report{
+		GeneratedAt: time.Date(2022, 11, 1, 3, 0, 0, 0, time.UTC),
+		Discrepancies: []Discrepancy{
+			{AccountID: 2, CVU: "0000000000000000000002", StoredBalance: decimal.NewFromInt(150), ComputedBalance: decimal.NewFromInt(100), LedgerBalance: decimal.NewFromInt(100), Difference: decimal.NewFromInt(50), AdjustmentID: 10, AdjustmentEntry: 11},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteCSV(&buf, report))
+	assert.Equal(t, "account_id,cvu,stored_balance,computed_balance,ledger_balance,difference,adjustment_transaction_id,adjustment_entry_id,error\n"+
+		"2,0000000000000000000002,150.00,100.00,100.00,50.00,10,11,\n", buf.String())
+}
@@ -0,0 +1,50 @@
+package scheduled
+
+import (
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+// firstRun returns the first execution time of a transfer starting at start.
+// Monthly transfers run on their day of month, so the first run may fall in
+// the following month.
+func firstRun(frequency string, dayOfMonth int, start time.Time) time.Time {
+	if frequency != domain.FrequencyMonthly {
+		return start
+	}
+
+	run := onDay(start.Year(), start.Month(), dayOfMonth, start)
+	if run.Before(start) {
+		run = onDay(start.Year(), start.Month()+1, dayOfMonth, start)
+	}
+
+	return run
+}
+
+// nextRun returns the run that follows last, or false for one-off transfers.
+func nextRun(frequency string, dayOfMonth int, last time.Time) (time.Time, bool) {
+	switch frequency {
+	case domain.FrequencyDaily:
+		return last.AddDate(0, 0, 1), true
+	case domain.FrequencyWeekly:
+		return last.AddDate(0, 0, 7), true
+	case domain.FrequencyMonthly:
+		return onDay(last.Year(), last.Month()+1, dayOfMonth, last), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// onDay builds the date for day in the given month at the time of day of clock.
+// Days past the end of the month fall on its last day, so a transfer set for
+// the 31st runs on February 28th.
+func onDay(year int, month time.Month, day int, clock time.Time) time.Time {
+	first := time.Date(year, month, 1, clock.Hour(), clock.Minute(), clock.Second(), 0, clock.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
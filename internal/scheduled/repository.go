@@ -0,0 +1,235 @@
+package scheduled
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+const scheduledTransferColumns = "id, account_id, destination, destination_account_id, amount, description, frequency, day_of_month, next_run_at, " +
+	"run_after, status, attempts, last_error, last_run_at, created_at"
+
+// RunFunc executes a due scheduled transfer and returns it with its schedule
+// moved forward, together with the execution to record.
+type RunFunc func(st domain.ScheduledTransfer) (domain.ScheduledTransfer, domain.ScheduledTransferExecution)
+
+type Repository interface {
+	Save(ctx context.Context, st domain.ScheduledTransfer) (int, error)
+	GetAll(ctx context.Context, accountID int) ([]domain.ScheduledTransfer, error)
+	GetByID(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error)
+	GetExecutions(ctx context.Context, scheduledTransferID int) ([]domain.ScheduledTransferExecution, error)
+	UpdateStatus(ctx context.Context, st domain.ScheduledTransfer, from string) error
+	RunDue(ctx context.Context, now time.Time, run RunFunc) (bool, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Save(ctx context.Context, st domain.ScheduledTransfer) (int, error) {
+	query := "INSERT INTO scheduled_transfers (account_id, destination, destination_account_id, amount, description, frequency, " +
+		"day_of_month, next_run_at, run_after, status, attempts, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, st.AccountID, st.Destination, st.DestinationAccountID, st.Amount, st.Description, st.Frequency, st.DayOfMonth,
+		st.NextRunAt, st.RunAfter, st.Status, st.Attempts, st.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (r *repository) GetAll(ctx context.Context, accountID int) ([]domain.ScheduledTransfer, error) {
+	query := "SELECT " + scheduledTransferColumns + " FROM scheduled_transfers WHERE account_id = ? ORDER BY id;"
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return []domain.ScheduledTransfer{}, err
+	}
+	defer rows.Close()
+
+	var scheduledTransfers []domain.ScheduledTransfer
+
+	for rows.Next() {
+		st, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return []domain.ScheduledTransfer{}, err
+		}
+
+		scheduledTransfers = append(scheduledTransfers, st)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []domain.ScheduledTransfer{}, err
+	}
+
+	return scheduledTransfers, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error) {
+	query := "SELECT " + scheduledTransferColumns + " FROM scheduled_transfers WHERE id = ? AND account_id = ?;"
+	row := r.db.QueryRowContext(ctx, query, id, accountID)
+
+	st, err := scanScheduledTransfer(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.ScheduledTransfer{}, ErrScheduledTransferNotFound
+		}
+		return domain.ScheduledTransfer{}, err
+	}
+
+	return st, nil
+}
+
+func (r *repository) GetExecutions(ctx context.Context, scheduledTransferID int) ([]domain.ScheduledTransferExecution, error) {
+	query := "SELECT id, scheduled_transfer_id, transaction_id, status, error, executed_at FROM scheduled_transfer_executions " +
+		"WHERE scheduled_transfer_id = ? ORDER BY executed_at DESC, id DESC;"
+	rows, err := r.db.QueryContext(ctx, query, scheduledTransferID)
+	if err != nil {
+		return []domain.ScheduledTransferExecution{}, err
+	}
+	defer rows.Close()
+
+	var executions []domain.ScheduledTransferExecution
+
+	for rows.Next() {
+		var execution domain.ScheduledTransferExecution
+		var transactionID sql.NullInt64
+		var execError sql.NullString
+		err = rows.Scan(&execution.ID, &execution.ScheduledTransferID, &transactionID, &execution.Status, &execError,
+			&execution.ExecutedAt)
+		if err != nil {
+			return []domain.ScheduledTransferExecution{}, err
+		}
+		execution.TransactionID = int(transactionID.Int64)
+		execution.Error = execError.String
+
+		executions = append(executions, execution)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []domain.ScheduledTransferExecution{}, err
+	}
+
+	return executions, nil
+}
+
+// UpdateStatus changes the status and next run of st only if it is still in
+// status from, so a change racing with the worker cannot undo its update.
+func (r *repository) UpdateStatus(ctx context.Context, st domain.ScheduledTransfer, from string) error {
+	query := "UPDATE scheduled_transfers SET status = ?, next_run_at = ?, run_after = ?, attempts = ? " +
+		"WHERE id = ? AND account_id = ? AND status = ?;"
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, st.Status, st.NextRunAt, st.RunAfter, st.Attempts, st.ID, st.AccountID, from)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrInvalidStatusTransition
+	}
+
+	return nil
+}
+
+// RunDue locks the oldest due transfer and hands it to run, keeping the row
+// locked until the result is stored. Rows locked by another replica are
+// skipped, so each run happens once. It returns false if nothing was due.
+func (r *repository) RunDue(ctx context.Context, now time.Time, run RunFunc) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := "SELECT " + scheduledTransferColumns + " FROM scheduled_transfers WHERE status = ? AND run_after <= ? " +
+		"ORDER BY run_after, id LIMIT 1 FOR UPDATE SKIP LOCKED;"
+	st, err := scanScheduledTransfer(tx.QueryRowContext(ctx, query, domain.ScheduledTransferActive, now))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	st, execution := run(st)
+
+	query = "UPDATE scheduled_transfers SET next_run_at = ?, run_after = ?, status = ?, attempts = ?, last_error = ?, " +
+		"last_run_at = ? WHERE id = ?;"
+	_, err = tx.ExecContext(ctx, query, st.NextRunAt, st.RunAfter, st.Status, st.Attempts, nullString(st.LastError),
+		st.LastRunAt, st.ID)
+	if err != nil {
+		return false, err
+	}
+
+	query = "INSERT INTO scheduled_transfer_executions (scheduled_transfer_id, transaction_id, status, error, executed_at) " +
+		"VALUES (?, ?, ?, ?, ?);"
+	_, err = tx.ExecContext(ctx, query, st.ID, nullInt(execution.TransactionID), execution.Status,
+		nullString(execution.Error), execution.ExecutedAt)
+	if err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanScheduledTransfer(row scanner) (domain.ScheduledTransfer, error) {
+	var st domain.ScheduledTransfer
+	var description, lastError sql.NullString
+	var dayOfMonth sql.NullInt64
+	var lastRunAt sql.NullTime
+	err := row.Scan(&st.ID, &st.AccountID, &st.Destination, &st.DestinationAccountID, &st.Amount, &description, &st.Frequency, &dayOfMonth,
+		&st.NextRunAt, &st.RunAfter, &st.Status, &st.Attempts, &lastError, &lastRunAt, &st.CreatedAt)
+	if err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+
+	st.Description = description.String
+	st.DayOfMonth = int(dayOfMonth.Int64)
+	st.LastError = lastError.String
+	if lastRunAt.Valid {
+		st.LastRunAt = &lastRunAt.Time
+	}
+
+	return st, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
+}
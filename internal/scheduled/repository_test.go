@@ -0,0 +1,70 @@
+package scheduled
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var scheduledTransferRowColumns = []string{"id", "account_id", "destination", "destination_account_id", "amount", "description", "frequency", "day_of_month",
+	"next_run_at", "run_after", "status", "attempts", "last_error", "last_run_at", "created_at"}
+
+func TestRepositoryRunDueNothingDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2023, 2, 1, 9, 1, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).WithArgs(domain.ScheduledTransferActive, now).
+		WillReturnRows(sqlmock.NewRows(scheduledTransferRowColumns))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	ran, err := repo.RunDue(context.Background(), now, func(st domain.ScheduledTransfer) (domain.ScheduledTransfer, domain.ScheduledTransferExecution) {
+		t.Fatal("run called with nothing due")
+		return st, domain.ScheduledTransferExecution{}
+	})
+	assert.NoError(t, err)
+	assert.False(t, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryRunDueSuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2023, 2, 1, 9, 1, 0, 0, time.UTC)
+	runAt := time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC)
+	nextRunAt := time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows(scheduledTransferRowColumns).
+		AddRow(5, 1, "casa.perro.gato", 2, "1000.00", "Rent", domain.FrequencyMonthly, 1, runAt, runAt, domain.ScheduledTransferActive, 0, nil, nil, runAt)
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).WithArgs(domain.ScheduledTransferActive, now).WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE scheduled_transfers SET")).
+		WithArgs(nextRunAt, nextRunAt, domain.ScheduledTransferActive, 0, nil, now, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO scheduled_transfer_executions")).
+		WithArgs(5, 42, domain.ExecutionSucceeded, nil, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	ran, err := repo.RunDue(context.Background(), now, func(st domain.ScheduledTransfer) (domain.ScheduledTransfer, domain.ScheduledTransferExecution) {
+		assert.Equal(t, "Rent", st.Description)
+		st.NextRunAt = nextRunAt
+		st.RunAfter = nextRunAt
+		st.LastRunAt = &now
+		return st, domain.ScheduledTransferExecution{ScheduledTransferID: st.ID, TransactionID: 42, Status: domain.ExecutionSucceeded, ExecutedAt: now}
+	})
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
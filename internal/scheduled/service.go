@@ -0,0 +1,189 @@
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+var (
+	ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+	ErrInvalidStatusTransition   = errors.New("invalid status transition")
+	ErrInvalidFrequency          = errors.New("invalid frequency")
+	ErrInvalidDayOfMonth         = errors.New("day of month must be between 1 and 31")
+	ErrStartInPast               = errors.New("start date is in the past")
+)
+
+type Service interface {
+	Create(ctx context.Context, accountID int, rq domain.ScheduledTransferRequest) (domain.ScheduledTransfer, error)
+	GetAll(ctx context.Context, accountID int) ([]domain.ScheduledTransfer, error)
+	GetExecutions(ctx context.Context, accountID, id int) ([]domain.ScheduledTransferExecution, error)
+	Pause(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error)
+	Resume(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error)
+	Cancel(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error)
+}
+
+type service struct {
+	repository         Repository
+	accountsRepository accounts.Repository
+	now                func() time.Time
+}
+
+func NewService(repository Repository, accountsRepository accounts.Repository) Service {
+	return &service{repository: repository, accountsRepository: accountsRepository, now: time.Now}
+}
+
+func (s *service) Create(ctx context.Context, accountID int, rq domain.ScheduledTransferRequest) (domain.ScheduledTransfer, error) {
+	if !rq.Amount.IsPositive() {
+		return domain.ScheduledTransfer{}, transactions.ErrInvalidAmount
+	}
+	if utf8.RuneCountInString(rq.Description) > transactions.MaxDescriptionLength {
+		return domain.ScheduledTransfer{}, transactions.ErrDescriptionTooLong
+	}
+
+	switch rq.Frequency {
+	case domain.FrequencyOnce, domain.FrequencyDaily, domain.FrequencyWeekly:
+	case domain.FrequencyMonthly:
+		if rq.DayOfMonth < 1 || rq.DayOfMonth > 31 {
+			return domain.ScheduledTransfer{}, ErrInvalidDayOfMonth
+		}
+	default:
+		return domain.ScheduledTransfer{}, ErrInvalidFrequency
+	}
+
+	now := s.now().UTC()
+	start := rq.StartAt.UTC()
+	if rq.StartAt.IsZero() {
+		start = now
+	}
+	if start.Before(now.Add(-time.Minute)) {
+		return domain.ScheduledTransfer{}, ErrStartInPast
+	}
+
+	// check the destination now rather than on the first run
+	origin, err := s.accountsRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+	destination, err := accounts.GetDestination(ctx, s.accountsRepository, rq.Destination)
+	if err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+	if origin.ID == destination.ID {
+		return domain.ScheduledTransfer{}, transactions.ErrSelfTransfer
+	}
+
+	st := domain.ScheduledTransfer{
+		AccountID:            accountID,
+		Destination:          rq.Destination,
+		DestinationAccountID: destination.ID,
+		Amount:               rq.Amount,
+		Description:          rq.Description,
+		Frequency:            rq.Frequency,
+		Status:               domain.ScheduledTransferActive,
+		CreatedAt:            now,
+	}
+	if rq.Frequency == domain.FrequencyMonthly {
+		st.DayOfMonth = rq.DayOfMonth
+	}
+	st.NextRunAt = firstRun(st.Frequency, st.DayOfMonth, start)
+	st.RunAfter = st.NextRunAt
+
+	st.ID, err = s.repository.Save(ctx, st)
+	if err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+
+	return st, nil
+}
+
+func (s *service) GetAll(ctx context.Context, accountID int) ([]domain.ScheduledTransfer, error) {
+	scheduledTransfers, err := s.repository.GetAll(ctx, accountID)
+	if err != nil {
+		return []domain.ScheduledTransfer{}, err
+	}
+
+	if scheduledTransfers == nil {
+		return []domain.ScheduledTransfer{}, nil
+	}
+
+	return scheduledTransfers, nil
+}
+
+func (s *service) GetExecutions(ctx context.Context, accountID, id int) ([]domain.ScheduledTransferExecution, error) {
+	if _, err := s.repository.GetByID(ctx, accountID, id); err != nil {
+		return []domain.ScheduledTransferExecution{}, err
+	}
+
+	executions, err := s.repository.GetExecutions(ctx, id)
+	if err != nil {
+		return []domain.ScheduledTransferExecution{}, err
+	}
+
+	if executions == nil {
+		return []domain.ScheduledTransferExecution{}, nil
+	}
+
+	return executions, nil
+}
+
+func (s *service) Pause(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error) {
+	return s.changeStatus(ctx, accountID, id, domain.ScheduledTransferPaused)
+}
+
+// Resume reactivates a paused transfer. Runs missed while it was paused are
+// skipped, not made up for.
+func (s *service) Resume(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error) {
+	return s.changeStatus(ctx, accountID, id, domain.ScheduledTransferActive)
+}
+
+func (s *service) Cancel(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error) {
+	return s.changeStatus(ctx, accountID, id, domain.ScheduledTransferCancelled)
+}
+
+func (s *service) changeStatus(ctx context.Context, accountID, id int, status string) (domain.ScheduledTransfer, error) {
+	st, err := s.repository.GetByID(ctx, accountID, id)
+	if err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+
+	from := st.Status
+	switch {
+	case status == domain.ScheduledTransferPaused && from == domain.ScheduledTransferActive:
+	case status == domain.ScheduledTransferActive && from == domain.ScheduledTransferPaused:
+		st.Attempts = 0
+		st.NextRunAt = skipMissed(st, s.now().UTC())
+		st.RunAfter = st.NextRunAt
+	case status == domain.ScheduledTransferCancelled &&
+		(from == domain.ScheduledTransferActive || from == domain.ScheduledTransferPaused):
+	default:
+		return domain.ScheduledTransfer{}, ErrInvalidStatusTransition
+	}
+
+	st.Status = status
+	if err = s.repository.UpdateStatus(ctx, st, from); err != nil {
+		return domain.ScheduledTransfer{}, err
+	}
+
+	return st, nil
+}
+
+// skipMissed returns the first run of st that is not before now. One-off
+// transfers keep their date and run as soon as they are due.
+func skipMissed(st domain.ScheduledTransfer, now time.Time) time.Time {
+	run := st.NextRunAt
+	for run.Before(now) {
+		next, ok := nextRun(st.Frequency, st.DayOfMonth, run)
+		if !ok {
+			break
+		}
+		run = next
+	}
+
+	return run
+}
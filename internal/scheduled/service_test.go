@@ -0,0 +1,360 @@
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) Save(ctx context.Context, st domain.ScheduledTransfer) (int, error) {
+	args := r.Called(ctx, st)
+	return args.Int(0), args.Error(1)
+}
+
+func (r *repositoryMock) GetAll(ctx context.Context, accountID int) ([]domain.ScheduledTransfer, error) {
+	args := r.Called(ctx, accountID)
+	return args.Get(0).([]domain.ScheduledTransfer), args.Error(1)
+}
+
+func (r *repositoryMock) GetByID(ctx context.Context, accountID, id int) (domain.ScheduledTransfer, error) {
+	args := r.Called(ctx, accountID, id)
+	return args.Get(0).(domain.ScheduledTransfer), args.Error(1)
+}
+
+func (r *repositoryMock) GetExecutions(ctx context.Context, scheduledTransferID int) ([]domain.ScheduledTransferExecution, error) {
+	args := r.Called(ctx, scheduledTransferID)
+	return args.Get(0).([]domain.ScheduledTransferExecution), args.Error(1)
+}
+
+func (r *repositoryMock) UpdateStatus(ctx context.Context, st domain.ScheduledTransfer, from string) error {
+	args := r.Called(ctx, st, from)
+	return args.Error(0)
+}
+
+func (r *repositoryMock) RunDue(ctx context.Context, now time.Time, run RunFunc) (bool, error) {
+	args := r.Called(ctx, now)
+	return args.Bool(0), args.Error(1)
+}
+
+type accountsRepositoryMock struct {
+	mock.Mock
+	accounts.Repository
+}
+
+func (r *accountsRepositoryMock) GetAccountByID(ctx context.Context, id int) (domain.Account, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func (r *accountsRepositoryMock) GetAccountByCVU(ctx context.Context, cvu string) (domain.Account, error) {
+	args := r.Called(ctx, cvu)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func (r *accountsRepositoryMock) GetAccountByAlias(ctx context.Context, alias string) (domain.Account, error) {
+	args := r.Called(ctx, alias)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+type transfersMock struct {
+	mock.Mock
+	transactions.Service
+}
+
+func (t *transfersMock) Transfer(ctx context.Context, accountID int, rq domain.TransferRequest) (domain.TransactionInfo, error) {
+	args := t.Called(ctx, accountID, rq)
+	return args.Get(0).(domain.TransactionInfo), args.Error(1)
+}
+
+func TestRecurrence(t *testing.T) {
+	start := time.Date(2023, 1, 15, 9, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, start, firstRun(domain.FrequencyDaily, 0, start))
+	assert.Equal(t, time.Date(2023, 1, 20, 9, 30, 0, 0, time.UTC), firstRun(domain.FrequencyMonthly, 20, start))
+	assert.Equal(t, time.Date(2023, 2, 1, 9, 30, 0, 0, time.UTC), firstRun(domain.FrequencyMonthly, 1, start))
+
+	next, ok := nextRun(domain.FrequencyWeekly, 0, start)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2023, 1, 22, 9, 30, 0, 0, time.UTC), next)
+
+	// the 31st falls on the last day of shorter months and comes back afterwards
+	next, ok = nextRun(domain.FrequencyMonthly, 31, time.Date(2023, 1, 31, 9, 30, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2023, 2, 28, 9, 30, 0, 0, time.UTC), next)
+	next, _ = nextRun(domain.FrequencyMonthly, 31, next)
+	assert.Equal(t, time.Date(2023, 3, 31, 9, 30, 0, 0, time.UTC), next)
+
+	_, ok = nextRun(domain.FrequencyOnce, 0, start)
+	assert.False(t, ok)
+}
+
+func Test_service_Create(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 15, 9, 30, 0, 0, time.UTC)
+	origin := domain.Account{ID: 1, CVU: "0000003100000000000017"}
+	destination := domain.Account{ID: 2, CVU: "0000003100000000000024", Alias: "casa.perro.gato"}
+
+	testCases := []struct {
+		name             string
+		rq               domain.ScheduledTransferRequest
+		repoMock         func(m *mock.Mock)
+		accountsRepoMock func(m *mock.Mock)
+		expectedError    error
+		expectedNextRun  time.Time
+	}{
+		{
+			name:          "Invalid amount",
+			rq:            domain.ScheduledTransferRequest{Destination: destination.Alias, Amount: decimal.NewFromInt(-1), Frequency: domain.FrequencyOnce},
+			expectedError: transactions.ErrInvalidAmount,
+		},
+		{
+			name:          "Invalid frequency",
+			rq:            domain.ScheduledTransferRequest{Destination: destination.Alias, Amount: decimal.NewFromInt(10), Frequency: "yearly"},
+			expectedError: ErrInvalidFrequency,
+		},
+		{
+			name:          "Monthly without day",
+			rq:            domain.ScheduledTransferRequest{Destination: destination.Alias, Amount: decimal.NewFromInt(10), Frequency: domain.FrequencyMonthly},
+			expectedError: ErrInvalidDayOfMonth,
+		},
+		{
+			name: "Start in the past",
+			rq: domain.ScheduledTransferRequest{Destination: destination.Alias, Amount: decimal.NewFromInt(10), Frequency: domain.FrequencyOnce,
+				StartAt: now.AddDate(0, 0, -1)},
+			expectedError: ErrStartInPast,
+		},
+		{
+			name: "Destination not found",
+			rq:   domain.ScheduledTransferRequest{Destination: "no.existe.alias", Amount: decimal.NewFromInt(10), Frequency: domain.FrequencyDaily},
+			accountsRepoMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByAlias", ctx, "no.existe.alias").Return(domain.Account{}, accounts.ErrAccountNotFound)
+			},
+			expectedError: transactions.ErrDestinationNotFound,
+		},
+		{
+			name: "Self transfer",
+			rq:   domain.ScheduledTransferRequest{Destination: origin.CVU, Amount: decimal.NewFromInt(10), Frequency: domain.FrequencyDaily},
+			accountsRepoMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByCVU", ctx, origin.CVU).Return(origin, nil)
+			},
+			expectedError: transactions.ErrSelfTransfer,
+		},
+		{
+			name: "Monthly rent on the 1st",
+			rq: domain.ScheduledTransferRequest{Destination: destination.Alias, Amount: decimal.NewFromInt(1000), Description: "Rent",
+				Frequency: domain.FrequencyMonthly, DayOfMonth: 1},
+			accountsRepoMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByAlias", ctx, destination.Alias).Return(destination, nil)
+			},
+			repoMock: func(m *mock.Mock) {
+				m.On("Save", ctx, mock.MatchedBy(func(st domain.ScheduledTransfer) bool {
+					return st.Status == domain.ScheduledTransferActive && st.DayOfMonth == 1 && st.RunAfter.Equal(st.NextRunAt) &&
+						st.Destination == destination.Alias && st.DestinationAccountID == destination.ID
+				})).Return(7, nil)
+			},
+			expectedNextRun: time.Date(2023, 2, 1, 9, 30, 0, 0, time.UTC),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			accountsRepoMock := new(accountsRepositoryMock)
+			if testCase.repoMock != nil {
+				testCase.repoMock(&repoMock.Mock)
+			}
+			if testCase.accountsRepoMock != nil {
+				testCase.accountsRepoMock(&accountsRepoMock.Mock)
+			}
+
+			s := &service{repository: repoMock, accountsRepository: accountsRepoMock, now: func() time.Time { return now }}
+
+			st, err := s.Create(ctx, origin.ID, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+			if err == nil {
+				assert.Equal(t, 7, st.ID)
+				assert.Equal(t, testCase.expectedNextRun, st.NextRunAt)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_service_ChangeStatus(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 3, 10, 12, 0, 0, 0, time.UTC)
+	paused := domain.ScheduledTransfer{ID: 3, AccountID: 1, Frequency: domain.FrequencyWeekly, Status: domain.ScheduledTransferPaused,
+		NextRunAt: time.Date(2023, 2, 20, 9, 0, 0, 0, time.UTC), Attempts: 2}
+
+	t.Run("Resume skips missed runs", func(t *testing.T) {
+		repoMock := new(repositoryMock)
+		repoMock.On("GetByID", ctx, 1, 3).Return(paused, nil)
+		expected := paused
+		expected.Status = domain.ScheduledTransferActive
+		expected.Attempts = 0
+		expected.NextRunAt = time.Date(2023, 3, 13, 9, 0, 0, 0, time.UTC)
+		expected.RunAfter = expected.NextRunAt
+		repoMock.On("UpdateStatus", ctx, expected, domain.ScheduledTransferPaused).Return(nil)
+
+		s := &service{repository: repoMock, now: func() time.Time { return now }}
+		st, err := s.Resume(ctx, 1, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, st)
+	})
+
+	t.Run("Pause a paused transfer", func(t *testing.T) {
+		repoMock := new(repositoryMock)
+		repoMock.On("GetByID", ctx, 1, 3).Return(paused, nil)
+
+		s := &service{repository: repoMock, now: func() time.Time { return now }}
+		_, err := s.Pause(ctx, 1, 3)
+
+		assert.Equal(t, ErrInvalidStatusTransition, err)
+	})
+
+	t.Run("Cancel a missing transfer", func(t *testing.T) {
+		repoMock := new(repositoryMock)
+		repoMock.On("GetByID", ctx, 1, 4).Return(domain.ScheduledTransfer{}, ErrScheduledTransferNotFound)
+
+		s := &service{repository: repoMock, now: func() time.Time { return now }}
+		_, err := s.Cancel(ctx, 1, 4)
+
+		assert.Equal(t, ErrScheduledTransferNotFound, err)
+	})
+}
+
+func TestWorker_execute(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 2, 1, 9, 1, 0, 0, time.UTC)
+	monthly := domain.ScheduledTransfer{ID: 5, AccountID: 1, Destination: "casa.perro.gato", DestinationAccountID: 2, Amount: decimal.NewFromInt(1000),
+		Description: "Rent", Frequency: domain.FrequencyMonthly, DayOfMonth: 1, Status: domain.ScheduledTransferActive,
+		NextRunAt: time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC)}
+	monthly.RunAfter = monthly.NextRunAt
+	rq := domain.TransferRequest{Destination: monthly.Destination, DestinationAccountID: monthly.DestinationAccountID, Amount: monthly.Amount,
+		Description: monthly.Description, ScheduledRun: &domain.ScheduledRun{ScheduledTransferID: monthly.ID, RunAt: monthly.NextRunAt}}
+
+	testCases := []struct {
+		name              string
+		st                domain.ScheduledTransfer
+		transferErr       error
+		expectedStatus    string
+		expectedAttempts  int
+		expectedNextRun   time.Time
+		expectedRunAfter  time.Time
+		expectedExecution string
+	}{
+		{
+			name:              "Success moves to next month",
+			st:                monthly,
+			expectedStatus:    domain.ScheduledTransferActive,
+			expectedNextRun:   time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+			expectedRunAfter:  time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+			expectedExecution: domain.ExecutionSucceeded,
+		},
+		{
+			name:              "Insufficient funds is retried with backoff",
+			st:                func() domain.ScheduledTransfer { st := monthly; st.Attempts = 2; return st }(),
+			transferErr:       transactions.ErrInsufficientFunds,
+			expectedStatus:    domain.ScheduledTransferActive,
+			expectedAttempts:  3,
+			expectedNextRun:   monthly.NextRunAt,
+			expectedRunAfter:  now.Add(20 * time.Minute),
+			expectedExecution: domain.ExecutionFailed,
+		},
+		{
+			name:              "Last attempt gives up the run",
+			st:                func() domain.ScheduledTransfer { st := monthly; st.Attempts = maxAttempts - 1; return st }(),
+			transferErr:       transactions.ErrInsufficientFunds,
+			expectedStatus:    domain.ScheduledTransferActive,
+			expectedNextRun:   time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+			expectedRunAfter:  time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+			expectedExecution: domain.ExecutionFailed,
+		},
+		{
+			name:              "One-off completes",
+			st:                func() domain.ScheduledTransfer { st := monthly; st.Frequency = domain.FrequencyOnce; return st }(),
+			expectedStatus:    domain.ScheduledTransferCompleted,
+			expectedNextRun:   monthly.NextRunAt,
+			expectedRunAfter:  monthly.RunAfter,
+			expectedExecution: domain.ExecutionSucceeded,
+		},
+		{
+			name:              "Run already paid moves to next month",
+			st:                func() domain.ScheduledTransfer { st := monthly; st.Attempts = 1; return st }(),
+			transferErr:       transactions.ErrAlreadyExecuted,
+			expectedStatus:    domain.ScheduledTransferActive,
+			expectedNextRun:   time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+			expectedRunAfter:  time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+			expectedExecution: domain.ExecutionSucceeded,
+		},
+		{
+			name:              "Missing destination fails right away",
+			st:                monthly,
+			transferErr:       transactions.ErrDestinationNotFound,
+			expectedStatus:    domain.ScheduledTransferFailed,
+			expectedAttempts:  1,
+			expectedNextRun:   monthly.NextRunAt,
+			expectedRunAfter:  monthly.RunAfter,
+			expectedExecution: domain.ExecutionFailed,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transfers := new(transfersMock)
+			transfers.On("Transfer", ctx, 1, rq).Return(domain.TransactionInfo{ID: 42}, testCase.transferErr)
+
+			w := &Worker{transfers: transfers, now: func() time.Time { return now }}
+			st, execution := w.execute(ctx, testCase.st)
+
+			assert.Equal(t, testCase.expectedStatus, st.Status)
+			assert.Equal(t, testCase.expectedAttempts, st.Attempts)
+			assert.Equal(t, testCase.expectedNextRun, st.NextRunAt)
+			assert.Equal(t, testCase.expectedRunAfter, st.RunAfter)
+			assert.Equal(t, testCase.expectedExecution, execution.Status)
+			assert.Equal(t, now, *st.LastRunAt)
+			if testCase.expectedExecution == domain.ExecutionFailed {
+				assert.Equal(t, testCase.transferErr.Error(), st.LastError)
+			} else {
+				assert.Empty(t, st.LastError)
+			}
+			if testCase.transferErr == nil {
+				assert.Equal(t, 42, execution.TransactionID)
+			}
+		})
+	}
+}
+
+func TestWorker_RunDue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 2, 1, 9, 1, 0, 0, time.UTC)
+
+	repoMock := new(repositoryMock)
+	repoMock.On("RunDue", ctx, now).Return(true, nil).Twice()
+	repoMock.On("RunDue", ctx, now).Return(false, nil).Once()
+
+	w := &Worker{repository: repoMock, now: func() time.Time { return now }}
+	assert.NoError(t, w.RunDue(ctx))
+	repoMock.AssertNumberOfCalls(t, "RunDue", 3)
+
+	repoMock = new(repositoryMock)
+	repoMock.On("RunDue", ctx, now).Return(false, errors.New("error"))
+
+	w = &Worker{repository: repoMock, now: func() time.Time { return now }}
+	assert.Equal(t, errors.New("error"), w.RunDue(ctx))
+}
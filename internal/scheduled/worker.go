@@ -0,0 +1,136 @@
+package scheduled
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+)
+
+const (
+	// maxAttempts is how many times a run is tried before it is given up.
+	maxAttempts    = 5
+	retryBaseDelay = 5 * time.Minute
+)
+
+// Worker executes due scheduled transfers through the transfer service.
+// Several workers, one per replica, can share the same database.
+type Worker struct {
+	repository Repository
+	transfers  transactions.Service
+	now        func() time.Time
+}
+
+func NewWorker(repository Repository, transfers transactions.Service) *Worker {
+	return &Worker{repository: repository, transfers: transfers, now: time.Now}
+}
+
+// Run processes due transfers every interval until ctx is done.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.RunDue(ctx); err != nil {
+				logger.Error(fmt.Sprintf("running scheduled transfers: %s", err.Error()))
+			}
+		}
+	}
+}
+
+// RunDue executes every transfer that is due, one at a time.
+func (w *Worker) RunDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		ran, err := w.repository.RunDue(ctx, w.now().UTC(), func(st domain.ScheduledTransfer) (domain.ScheduledTransfer, domain.ScheduledTransferExecution) {
+			return w.execute(ctx, st)
+		})
+		if err != nil {
+			return err
+		}
+		if !ran {
+			return nil
+		}
+	}
+
+	return ctx.Err()
+}
+
+// execute makes the transfer and moves st to its next run. Failed runs are
+// retried with exponential backoff and given up after maxAttempts, which
+// marks one-off transfers as failed. Errors that cannot go away by themselves,
+// such as a missing destination, fail the transfer right away.
+// The transfer records the run it pays, so a run whose transfer committed but
+// whose schedule update was lost is not paid again: it is just moved forward.
+func (w *Worker) execute(ctx context.Context, st domain.ScheduledTransfer) (domain.ScheduledTransfer, domain.ScheduledTransferExecution) {
+	now := w.now().UTC()
+	st.LastRunAt = &now
+	execution := domain.ScheduledTransferExecution{ScheduledTransferID: st.ID, ExecutedAt: now}
+
+	rq := domain.TransferRequest{
+		Destination:          st.Destination,
+		DestinationAccountID: st.DestinationAccountID,
+		Amount:               st.Amount,
+		Description:          st.Description,
+		ScheduledRun:         &domain.ScheduledRun{ScheduledTransferID: st.ID, RunAt: st.NextRunAt},
+	}
+	trx, err := w.transfers.Transfer(ctx, st.AccountID, rq)
+	if err == nil || err == transactions.ErrAlreadyExecuted {
+		execution.Status = domain.ExecutionSucceeded
+		execution.TransactionID = trx.ID
+		st.Attempts = 0
+		st.LastError = ""
+		return advance(st, now, domain.ScheduledTransferCompleted), execution
+	}
+
+	logger.Error(fmt.Sprintf("scheduled transfer %d: %s", st.ID, err.Error()))
+	execution.Status = domain.ExecutionFailed
+	execution.Error = err.Error()
+	st.LastError = err.Error()
+	st.Attempts++
+
+	if permanent(err) {
+		st.Status = domain.ScheduledTransferFailed
+		return st, execution
+	}
+
+	if st.Attempts >= maxAttempts {
+		st.Attempts = 0
+		return advance(st, now, domain.ScheduledTransferFailed), execution
+	}
+
+	st.RunAfter = now.Add(retryBaseDelay << (st.Attempts - 1))
+	return st, execution
+}
+
+// advance moves st to its next run after now, or sets it to finalStatus when
+// there is none.
+func advance(st domain.ScheduledTransfer, now time.Time, finalStatus string) domain.ScheduledTransfer {
+	next, ok := nextRun(st.Frequency, st.DayOfMonth, st.NextRunAt)
+	if !ok {
+		st.Status = finalStatus
+		return st
+	}
+
+	st.NextRunAt = next
+	st.NextRunAt = skipMissed(st, now)
+	st.RunAfter = st.NextRunAt
+	return st
+}
+
+// permanent reports whether retrying a transfer that failed with err is
+// pointless.
+func permanent(err error) bool {
+	switch err {
+	case transactions.ErrDestinationNotFound, transactions.ErrInvalidDestination, transactions.ErrSelfTransfer, transactions.ErrInvalidAmount:
+		return true
+	default:
+		return false
+	}
+}
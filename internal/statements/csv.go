@@ -0,0 +1,54 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var csvHeader = []string{"date", "transaction_id", "type", "description", "origin_cvu", "destination_cvu", "amount", "balance"}
+
+// RenderCSV writes one row per transaction, between an opening and a closing
+// balance row.
+func RenderCSV(statement domain.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{
+		csvHeader,
+		{statement.From.Format(time.RFC3339), "", "opening_balance", "", "", "", "", statement.OpeningBalance.StringFixed(2)},
+	}
+	for _, line := range statement.Lines {
+		rows = append(rows, []string{
+			line.DateTime.Format(time.RFC3339),
+			fmt.Sprint(line.ID),
+			line.Type,
+			escapeFormula(line.Description),
+			line.OriginCVU,
+			line.DestinationCVU,
+			line.Amount.StringFixed(2),
+			line.Balance.StringFixed(2),
+		})
+	}
+	rows = append(rows, []string{statement.To.Format(time.RFC3339), "", "closing_balance", "", "", "", "", statement.ClosingBalance.StringFixed(2)})
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// escapeFormula keeps spreadsheets from running a description as a formula by
+// prefixing it with a quote when it starts like one.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
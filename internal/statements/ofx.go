@@ -0,0 +1,134 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+const (
+	ofxHeader   = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`
+	ofxDate     = "20060102150405"
+	ofxCurrency = "ARS"
+)
+
+type ofx struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response struct {
+			Status   ofxStatus `xml:"STATUS"`
+			DTServer string    `xml:"DTSERVER"`
+			Language string    `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Transaction struct {
+			TrnUID    string    `xml:"TRNUID"`
+			Status    ofxStatus `xml:"STATUS"`
+			Statement struct {
+				CurDef      string `xml:"CURDEF"`
+				AccountFrom struct {
+					BankID   string `xml:"BANKID"`
+					AcctID   string `xml:"ACCTID"`
+					AcctType string `xml:"ACCTTYPE"`
+				} `xml:"BANKACCTFROM"`
+				TranList struct {
+					DTStart      string           `xml:"DTSTART"`
+					DTEnd        string           `xml:"DTEND"`
+					Transactions []ofxTransaction `xml:"STMTTRN"`
+				} `xml:"BANKTRANLIST"`
+				LedgerBalance struct {
+					BalAmt string `xml:"BALAMT"`
+					DTAsOf string `xml:"DTASOF"`
+				} `xml:"LEDGERBAL"`
+			} `xml:"STMTRS"`
+		} `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTransaction struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FitID    string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+var ofxTypes = map[string]string{
+	domain.TransactionTypeDeposit:     "DEP",
+	domain.TransactionTypeTransferIn:  "XFER",
+	domain.TransactionTypeTransferOut: "XFER",
+	domain.TransactionTypeReversal:    "XFER",
+	domain.TransactionTypeFee:         "SRVCHG",
+	domain.TransactionTypeConversion:  "XFER",
+}
+
+// RenderOFX writes the statement as an OFX 2.2 bank statement response.
+func RenderOFX(statement domain.Statement, now time.Time) ([]byte, error) {
+	var doc ofx
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+
+	doc.SignOn.Response.Status = ok
+	doc.SignOn.Response.DTServer = now.UTC().Format(ofxDate)
+	doc.SignOn.Response.Language = "SPA"
+
+	trn := &doc.Bank.Transaction
+	trn.TrnUID = fmt.Sprintf("%d-%s", statement.AccountID, statement.From.UTC().Format(ofxDate))
+	trn.Status = ok
+
+	stmt := &trn.Statement
+	stmt.CurDef = ofxCurrency
+	// the first three digits of a CVU identify the entity
+	if len(statement.CVU) >= 3 {
+		stmt.AccountFrom.BankID = statement.CVU[:3]
+	}
+	stmt.AccountFrom.AcctID = statement.CVU
+	stmt.AccountFrom.AcctType = "CHECKING"
+	stmt.TranList.DTStart = statement.From.UTC().Format(ofxDate)
+	stmt.TranList.DTEnd = statement.To.UTC().Format(ofxDate)
+
+	for _, line := range statement.Lines {
+		trnType, ok := ofxTypes[line.Type]
+		if !ok {
+			trnType = "OTHER"
+		}
+
+		name := line.DestinationCVU
+		if line.Amount.IsPositive() {
+			name = line.OriginCVU
+		}
+
+		stmt.TranList.Transactions = append(stmt.TranList.Transactions, ofxTransaction{
+			TrnType:  trnType,
+			DTPosted: line.DateTime.UTC().Format(ofxDate),
+			TrnAmt:   line.Amount.StringFixed(2),
+			FitID:    fmt.Sprint(line.ID),
+			Name:     name,
+			Memo:     line.Description,
+		})
+	}
+
+	stmt.LedgerBalance.BalAmt = statement.ClosingBalance.StringFixed(2)
+	stmt.LedgerBalance.DTAsOf = statement.To.UTC().Format(ofxDate)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(ofxHeader + "\n")
+
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package statements
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Date", 32, "L"},
+	{"Type", 26, "L"},
+	{"Description", 62, "L"},
+	{"Amount", 35, "R"},
+	{"Balance", 35, "R"},
+}
+
+// RenderPDF writes the statement as a table, repeating the header on every page.
+func RenderPDF(statement domain.Statement) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Statement %s", statement.CVU), true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	tableHeader := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		for _, column := range pdfColumns {
+			pdf.CellFormat(column.width, 7, column.title, "B", 0, column.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() > 1 {
+			tableHeader()
+		}
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.Cell(0, 10, "Digital Money House - Account statement")
+	pdf.Ln(12)
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.Cell(0, 6, fmt.Sprintf("CVU: %s    Alias: %s", statement.CVU, statement.Alias))
+	pdf.Ln(6)
+	pdf.Cell(0, 6, fmt.Sprintf("Period: %s to %s", statement.From.Format("2006-01-02"),
+		statement.To.AddDate(0, 0, -1).Format("2006-01-02")))
+	pdf.Ln(6)
+	pdf.Cell(0, 6, "Opening balance: $ "+statement.OpeningBalance.StringFixed(2))
+	pdf.Ln(10)
+
+	tableHeader()
+	for _, line := range statement.Lines {
+		values := []string{
+			line.DateTime.Format("2006-01-02 15:04"),
+			line.Type,
+			tr(line.Description),
+			line.Amount.StringFixed(2),
+			line.Balance.StringFixed(2),
+		}
+		for i, column := range pdfColumns {
+			pdf.CellFormat(column.width, 6, values[i], "", 0, column.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.Cell(0, 6, "Closing balance: $ "+statement.ClosingBalance.StringFixed(2))
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package statements
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+var (
+	ErrInvalidPeriod     = errors.New("invalid statement period")
+	ErrUnsupportedFormat = errors.New("unsupported statement format")
+)
+
+const (
+	FormatCSV = "csv"
+	FormatOFX = "ofx"
+	FormatPDF = "pdf"
+)
+
+// maxPeriod keeps statements to a size that can be rendered in a request.
+const maxPeriod = 366 * 24 * time.Hour
+
+type Service interface {
+	GetStatement(ctx context.Context, accountID int, from, to time.Time) (domain.Statement, error)
+}
+
+type service struct {
+	transactionsRepository transactions.Repository
+	accountsRepository     accounts.Repository
+}
+
+func NewService(transactionsRepository transactions.Repository, accountsRepository accounts.Repository) Service {
+	return &service{
+		transactionsRepository: transactionsRepository,
+		accountsRepository:     accountsRepository,
+	}
+}
+
+// GetStatement builds the account statement for the period [from, to).
+func (s *service) GetStatement(ctx context.Context, accountID int, from, to time.Time) (domain.Statement, error) {
+	if !from.Before(to) || to.Sub(from) > maxPeriod {
+		return domain.Statement{}, ErrInvalidPeriod
+	}
+
+	account, err := s.accountsRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return domain.Statement{}, err
+	}
+
+	// the opening balance is worked out backwards from the current balance,
+	// so the statement matches the balance the user sees in the app
+	sinceFrom, err := s.transactionsRepository.SumSince(ctx, accountID, from)
+	if err != nil {
+		return domain.Statement{}, err
+	}
+
+	trxs, err := s.transactionsRepository.GetByDateRange(ctx, accountID, from, to)
+	if err != nil {
+		return domain.Statement{}, err
+	}
+
+	statement := domain.Statement{
+		AccountID:      account.ID,
+		CVU:            account.CVU,
+		Alias:          account.Alias,
+		From:           from,
+		To:             to,
+		OpeningBalance: account.Balance.Sub(sinceFrom),
+		Lines:          make([]domain.StatementLine, 0, len(trxs)),
+	}
+
+	balance := statement.OpeningBalance
+	for _, trx := range trxs {
+		balance = balance.Add(trx.Amount)
+		statement.Lines = append(statement.Lines, domain.StatementLine{TransactionInfo: trx, Balance: balance})
+	}
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
+
+// Render writes the statement in the given format and returns the document
+// with its content type.
+func Render(statement domain.Statement, format string) ([]byte, string, error) {
+	switch format {
+	case FormatCSV:
+		content, err := RenderCSV(statement)
+		return content, "text/csv", err
+	case FormatOFX:
+		content, err := RenderOFX(statement, time.Now())
+		return content, "application/x-ofx", err
+	case FormatPDF:
+		content, err := RenderPDF(statement)
+		return content, "application/pdf", err
+	default:
+		return nil, "", ErrUnsupportedFormat
+	}
+}
@@ -0,0 +1,231 @@
+package statements
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/transactions"
+)
+
+type transactionsRepositoryMock struct {
+	mock.Mock
+	transactions.Repository
+}
+
+func (r *transactionsRepositoryMock) GetByDateRange(ctx context.Context, accountID int, from, to time.Time) ([]domain.TransactionInfo, error) {
+	args := r.Called(ctx, accountID, from, to)
+	return args.Get(0).([]domain.TransactionInfo), args.Error(1)
+}
+
+func (r *transactionsRepositoryMock) SumSince(ctx context.Context, accountID int, since time.Time) (decimal.Decimal, error) {
+	args := r.Called(ctx, accountID, since)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+type accountsRepositoryMock struct {
+	mock.Mock
+	accounts.Repository
+}
+
+func (r *accountsRepositoryMock) GetAccountByID(ctx context.Context, id int) (domain.Account, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+var (
+	from = time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	to   = time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func testStatement() domain.Statement {
+	return domain.Statement{
+		AccountID:      1,
+		CVU:            "0000003100000000000011",
+		Alias:          "casa.perro.gato",
+		From:           from,
+		To:             to,
+		OpeningBalance: decimal.NewFromInt(100),
+		ClosingBalance: decimal.NewFromInt(550),
+		Lines: []domain.StatementLine{
+			{
+				TransactionInfo: domain.TransactionInfo{ID: 1, AccountID: 1, DestinationCVU: "0000003100000000000011", Description: "Deposit from card **** 3704",
+					Amount: decimal.NewFromInt(500), DateTime: from.Add(time.Hour), Type: domain.TransactionTypeDeposit},
+				Balance: decimal.NewFromInt(600),
+			},
+			{
+				TransactionInfo: domain.TransactionInfo{ID: 2, AccountID: 1, OriginCVU: "0000003100000000000011", DestinationCVU: "0000003100000000000022",
+					Description: "rent, november", Amount: decimal.NewFromInt(-50), DateTime: from.Add(2 * time.Hour), Type: domain.TransactionTypeTransferOut},
+				Balance: decimal.NewFromInt(550),
+			},
+		},
+	}
+}
+
+func Test_service_GetStatement(t *testing.T) {
+	var ctx = context.Background()
+	account := domain.Account{ID: 1, CVU: "0000003100000000000011", Alias: "casa.perro.gato", Balance: decimal.NewFromInt(1000)}
+	trxs := []domain.TransactionInfo{
+		{ID: 1, Amount: decimal.NewFromInt(500)},
+		{ID: 2, Amount: decimal.NewFromInt(-50)},
+	}
+	testCases := []struct {
+		name           string
+		from, to       time.Time
+		trxMock        func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.Statement
+	}{
+		{
+			name:          "Invalid period",
+			from:          to,
+			to:            from,
+			trxMock:       func(m *mock.Mock) {},
+			expectedError: ErrInvalidPeriod,
+		},
+		{
+			name:          "Period too long",
+			from:          from.AddDate(-2, 0, 0),
+			to:            to,
+			trxMock:       func(m *mock.Mock) {},
+			expectedError: ErrInvalidPeriod,
+		},
+		{
+			name: "Repository error",
+			from: from,
+			to:   to,
+			trxMock: func(m *mock.Mock) {
+				m.On("SumSince", ctx, 1, from).Return(decimal.Decimal{}, errors.New("error"))
+			},
+			expectedError: errors.New("error"),
+		},
+		{
+			name: "Statement with running balance",
+			from: from,
+			to:   to,
+			trxMock: func(m *mock.Mock) {
+				// 450 moved during the period plus 550 deposited after it
+				m.On("SumSince", ctx, 1, from).Return(decimal.NewFromInt(1000), nil)
+				m.On("GetByDateRange", ctx, 1, from, to).Return(trxs, nil)
+			},
+			expectedResult: domain.Statement{
+				AccountID:      1,
+				CVU:            account.CVU,
+				Alias:          account.Alias,
+				From:           from,
+				To:             to,
+				OpeningBalance: decimal.NewFromInt(0),
+				ClosingBalance: decimal.NewFromInt(450),
+				Lines: []domain.StatementLine{
+					{TransactionInfo: trxs[0], Balance: decimal.NewFromInt(500)},
+					{TransactionInfo: trxs[1], Balance: decimal.NewFromInt(450)},
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trxMock := new(transactionsRepositoryMock)
+			testCase.trxMock(&trxMock.Mock)
+			accountsMock := new(accountsRepositoryMock)
+			accountsMock.On("GetAccountByID", ctx, 1).Return(account, nil)
+
+			statementsService := NewService(trxMock, accountsMock)
+
+			statement, err := statementsService.GetStatement(ctx, 1, testCase.from, testCase.to)
+
+			assert.Equal(t, testCase.expectedError, err)
+			if err != nil {
+				return
+			}
+			assert.True(t, testCase.expectedResult.OpeningBalance.Equal(statement.OpeningBalance))
+			assert.True(t, testCase.expectedResult.ClosingBalance.Equal(statement.ClosingBalance))
+			assert.Len(t, statement.Lines, len(testCase.expectedResult.Lines))
+			for i, line := range testCase.expectedResult.Lines {
+				assert.Equal(t, line.ID, statement.Lines[i].ID)
+				assert.True(t, line.Balance.Equal(statement.Lines[i].Balance))
+			}
+		})
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	content, err := RenderCSV(testStatement())
+	assert.NoError(t, err)
+
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 5)
+	assert.Equal(t, csvHeader, rows[0])
+	assert.Equal(t, []string{"2022-11-01T00:00:00Z", "", "opening_balance", "", "", "", "", "100.00"}, rows[1])
+	assert.Equal(t, "rent, november", rows[3][3])
+	assert.Equal(t, "-50.00", rows[3][6])
+	assert.Equal(t, "550.00", rows[3][7])
+	assert.Equal(t, "closing_balance", rows[4][2])
+}
+
+func TestRenderCSV_formulas(t *testing.T) {
+	statement := testStatement()
+	descriptions := map[string]string{
+		"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+		"+1":                       "'+1",
+		"-1+1":                     "'-1+1",
+		"@SUM(A1)":                 "'@SUM(A1)",
+		"rent":                     "rent",
+	}
+	for description, expected := range descriptions {
+		statement.Lines[1].Description = description
+		content, err := RenderCSV(statement)
+		assert.NoError(t, err)
+
+		rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rows[3][3])
+	}
+}
+
+func TestRenderOFX(t *testing.T) {
+	content, err := RenderOFX(testStatement(), time.Date(2022, 12, 2, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `<?OFX OFXHEADER="200" VERSION="220"`)
+
+	var doc ofx
+	assert.NoError(t, xml.Unmarshal(content, &doc))
+	stmt := doc.Bank.Transaction.Statement
+	assert.Equal(t, "ARS", stmt.CurDef)
+	assert.Equal(t, "000", stmt.AccountFrom.BankID)
+	assert.Equal(t, "20221101000000", stmt.TranList.DTStart)
+	assert.Len(t, stmt.TranList.Transactions, 2)
+	assert.Equal(t, "DEP", stmt.TranList.Transactions[0].TrnType)
+	assert.Equal(t, "-50.00", stmt.TranList.Transactions[1].TrnAmt)
+	assert.Equal(t, "0000003100000000000022", stmt.TranList.Transactions[1].Name)
+	assert.Equal(t, "550.00", stmt.LedgerBalance.BalAmt)
+}
+
+func TestRender(t *testing.T) {
+	for format, contentType := range map[string]string{
+		FormatCSV: "text/csv",
+		FormatOFX: "application/x-ofx",
+		FormatPDF: "application/pdf",
+	} {
+		content, ct, err := Render(testStatement(), format)
+		assert.NoError(t, err)
+		assert.Equal(t, contentType, ct)
+		assert.NotEmpty(t, content)
+	}
+
+	pdf, _, _ := Render(testStatement(), FormatPDF)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+
+	_, _, err := Render(testStatement(), "xls")
+	assert.Equal(t, ErrUnsupportedFormat, err)
+}
@@ -0,0 +1,36 @@
+package transactions
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+)
+
+// cursor points at the last transaction of an activity page. The next page
+// starts right after it in (date_time, id) descending order.
+type cursor struct {
+	dateTime time.Time
+	id       int
+}
+
+func encodeCursor(trx domain.TransactionInfo) string {
+	raw := fmt.Sprintf("%d:%d", trx.DateTime.UnixNano(), trx.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	var nanos int64
+	var id int
+	if _, err = fmt.Sscanf(string(raw), "%d:%d", &nanos, &id); err != nil || id <= 0 {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	return cursor{dateTime: time.Unix(0, nanos).UTC(), id: id}, nil
+}
@@ -3,13 +3,46 @@ package transactions
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/fees"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+	"gitlab.com/leorodriguez/grupo-04/internal/limits"
 )
 
 type Repository interface {
-	GetAllByIDLimit(ctx context.Context, id, limit int) ([]domain.TransactionInfo, error)
+	GetActivity(ctx context.Context, accountID int, filters domain.ActivityFilters) ([]domain.TransactionInfo, error)
+	GetByID(ctx context.Context, accountID, transactionID int) (domain.TransactionInfo, error)
+	GetByDateRange(ctx context.Context, accountID int, from, to time.Time) ([]domain.TransactionInfo, error)
+	SumSince(ctx context.Context, accountID int, since time.Time) (decimal.Decimal, error)
+	Transfer(ctx context.Context, origin, destination domain.Account, amount, fee decimal.Decimal, description string, run *domain.ScheduledRun) (domain.TransactionInfo, error)
+	Deposit(ctx context.Context, account domain.Account, amount, fee decimal.Decimal, description, reference string) (domain.TransactionInfo, error)
+	Reverse(ctx context.Context, transactionID int, amount decimal.Decimal, description string, refund RefundFunc) (domain.TransactionInfo, error)
 }
 
+// RefundFunc gives back the card charge with the processor reference.
+type RefundFunc func(ctx context.Context, reference string) error
+
+// States of the card refund of a deposit reversal, kept in its refund_status.
+const (
+	refundPending   = "pending"
+	refundSucceeded = "refunded"
+	refundFailed    = "failed"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for unique index violations.
+const mysqlDuplicateEntry = 1062
+
+const transactionColumns = "id, account_id, origin_cvu, destination_cvu, description, amount, currency, date_time, type, reversal_of, reversed_by"
+
 type repository struct {
 	db *sql.DB
 }
@@ -18,18 +51,104 @@ func NewRepository(db *sql.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) GetAllByIDLimit(ctx context.Context, id, limit int) ([]domain.TransactionInfo, error) {
-	query := "SELECT * FROM transactions WHERE account_id = ? LIMIT ?;"
-	rows, err := r.db.Query(query, id, limit)
+// GetActivity returns the account transactions matching filters, newest first.
+// At most filters.Limit transactions are returned.
+func (r *repository) GetActivity(ctx context.Context, accountID int, filters domain.ActivityFilters) ([]domain.TransactionInfo, error) {
+	where := []string{"account_id = ?"}
+	args := []interface{}{accountID}
+
+	if filters.Cursor != "" {
+		c, err := decodeCursor(filters.Cursor)
+		if err != nil {
+			return []domain.TransactionInfo{}, err
+		}
+		where = append(where, "(date_time < ? OR (date_time = ? AND id < ?))")
+		args = append(args, c.dateTime, c.dateTime, c.id)
+	}
+	if !filters.From.IsZero() {
+		where = append(where, "date_time >= ?")
+		args = append(args, filters.From)
+	}
+	if !filters.To.IsZero() {
+		where = append(where, "date_time <= ?")
+		args = append(args, filters.To)
+	}
+	if len(filters.Types) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filters.Types)), ", ")
+		where = append(where, fmt.Sprintf("type IN (%s)", placeholders))
+		for _, t := range filters.Types {
+			args = append(args, t)
+		}
+	}
+	if filters.MinAmount.Valid {
+		where = append(where, "ABS(amount) >= ?")
+		args = append(args, filters.MinAmount.Decimal)
+	}
+	if filters.MaxAmount.Valid {
+		where = append(where, "ABS(amount) <= ?")
+		args = append(args, filters.MaxAmount.Decimal)
+	}
+	if filters.Description != "" {
+		where = append(where, "description LIKE ?")
+		args = append(args, "%"+filters.Description+"%")
+	}
+	args = append(args, filters.Limit)
+
+	query := fmt.Sprintf("SELECT %s FROM transactions WHERE %s ORDER BY date_time DESC, id DESC LIMIT ?;",
+		transactionColumns, strings.Join(where, " AND "))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []domain.TransactionInfo{}, err
+	}
+	defer rows.Close()
+
+	var transactions []domain.TransactionInfo
+
+	for rows.Next() {
+		trx, err := scanTransaction(rows)
+		if err != nil {
+			return []domain.TransactionInfo{}, err
+		}
+
+		transactions = append(transactions, trx)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []domain.TransactionInfo{}, err
+	}
+
+	return transactions, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, accountID, transactionID int) (domain.TransactionInfo, error) {
+	query := fmt.Sprintf("SELECT %s FROM transactions WHERE id = ? AND account_id = ?;", transactionColumns)
+	row := r.db.QueryRowContext(ctx, query, transactionID, accountID)
+
+	trx, err := scanTransaction(row)
+	if err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			return domain.TransactionInfo{}, ErrTransactionNotFound
+		}
+		return domain.TransactionInfo{}, err
+	}
+
+	return trx, nil
+}
+
+// GetByDateRange returns the account transactions in the primary currency in
+// [from, to), oldest first.
+func (r *repository) GetByDateRange(ctx context.Context, accountID int, from, to time.Time) ([]domain.TransactionInfo, error) {
+	query := fmt.Sprintf("SELECT %s FROM transactions WHERE account_id = ? AND currency = ? AND date_time >= ? AND date_time < ? ORDER BY date_time, id;", transactionColumns)
+	rows, err := r.db.QueryContext(ctx, query, accountID, currency.Primary, from, to)
 	if err != nil {
 		return []domain.TransactionInfo{}, err
 	}
+	defer rows.Close()
 
 	var transactions []domain.TransactionInfo
 
 	for rows.Next() {
-		trx := domain.TransactionInfo{}
-		err = rows.Scan(&trx.ID, &trx.AccountID, &trx.DestinationCVU, &trx.Description, &trx.Amount, &trx.DateTime, &trx.Type, &trx.OriginCVU)
+		trx, err := scanTransaction(rows)
 		if err != nil {
 			return []domain.TransactionInfo{}, err
 		}
@@ -37,5 +156,566 @@ func (r *repository) GetAllByIDLimit(ctx context.Context, id, limit int) ([]doma
 		transactions = append(transactions, trx)
 	}
 
+	if err = rows.Err(); err != nil {
+		return []domain.TransactionInfo{}, err
+	}
+
 	return transactions, nil
 }
+
+// SumSince returns the sum of the account transactions in the primary currency
+// made at or after since.
+func (r *repository) SumSince(ctx context.Context, accountID int, since time.Time) (decimal.Decimal, error) {
+	query := "SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE account_id = ? AND currency = ? AND date_time >= ?;"
+	row := r.db.QueryRowContext(ctx, query, accountID, currency.Primary, since)
+
+	var sum decimal.Decimal
+	if err := row.Scan(&sum); err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return sum, nil
+}
+
+// Transfer moves amount from origin to destination in a single SQL transaction,
+// posting it to the ledger and recording a transaction row for each account.
+// A non-zero fee is charged to the origin in the same SQL transaction.
+// Both account rows are locked before the origin balance and transfer limits
+// are checked, so concurrent transfers cannot overdraw the account nor go over
+// its limits together.
+// A non-nil run is recorded with the transfer, and ErrAlreadyExecuted is
+// returned if that occurrence of the scheduled transfer was already paid.
+func (r *repository) Transfer(ctx context.Context, origin, destination domain.Account, amount, fee decimal.Decimal, description string, run *domain.ScheduledRun) (domain.TransactionInfo, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+	defer tx.Rollback()
+
+	// lock rows always in the same order to avoid deadlocks between
+	// transfers going in opposite directions
+	first, second := origin.ID, destination.ID
+	if first > second {
+		first, second = second, first
+	}
+
+	balances := make(map[int]decimal.Decimal, 2)
+	for _, id := range []int{first, second} {
+		balance, err := accounts.GetBalanceForUpdate(ctx, tx, id)
+		if err != nil {
+			return domain.TransactionInfo{}, err
+		}
+		balances[id] = balance
+	}
+
+	if balances[origin.ID].LessThan(amount.Add(fee)) {
+		return domain.TransactionInfo{}, ErrInsufficientFunds
+	}
+
+	if err = limits.Check(ctx, tx, origin.ID, amount); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	now := time.Now().UTC()
+	entryID, err := ledger.Post(ctx, tx, ledger.Entry{
+		Kind:        ledger.KindTransfer,
+		Description: description,
+		Postings: []ledger.Posting{
+			{Account: ledger.WalletAccount(origin.ID), Amount: amount.Neg()},
+			{Account: ledger.WalletAccount(destination.ID), Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	out := domain.TransactionInfo{
+		AccountID:      origin.ID,
+		OriginCVU:      origin.CVU,
+		DestinationCVU: destination.CVU,
+		Description:    description,
+		Amount:         amount.Neg(),
+		DateTime:       now,
+		Type:           domain.TransactionTypeTransferOut,
+		Currency:       currency.Primary,
+		JournalEntryID: entryID,
+	}
+	out.ID, err = Save(ctx, tx, out)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	in := domain.TransactionInfo{
+		AccountID:      destination.ID,
+		OriginCVU:      origin.CVU,
+		DestinationCVU: destination.CVU,
+		Description:    description,
+		Amount:         amount,
+		DateTime:       now,
+		Type:           domain.TransactionTypeTransferIn,
+		Currency:       currency.Primary,
+		JournalEntryID: entryID,
+	}
+	if _, err = Save(ctx, tx, in); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if run != nil {
+		if err = saveScheduledRun(ctx, tx, *run, out.ID); err != nil {
+			return domain.TransactionInfo{}, err
+		}
+	}
+
+	if err = chargeFee(ctx, tx, origin, fee, out.ID, now); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	return out, nil
+}
+
+// saveScheduledRun records that run was paid by transactionID. The run is the
+// primary key, so paying the same occurrence again fails and rolls back.
+func saveScheduledRun(ctx context.Context, tx *sql.Tx, run domain.ScheduledRun, transactionID int) error {
+	query := "INSERT INTO scheduled_transfer_runs(scheduled_transfer_id, run_at, transaction_id) VALUES(?, ?, ?);"
+	_, err := tx.ExecContext(ctx, query, run.ScheduledTransferID, run.RunAt, transactionID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return ErrAlreadyExecuted
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Deposit credits amount to the account against the deposits clearing account
+// and records the deposit in the same SQL transaction, charging a non-zero fee
+// out of it. reference is the card charge, kept to refund it on reversal.
+func (r *repository) Deposit(ctx context.Context, account domain.Account, amount, fee decimal.Decimal, description, reference string) (domain.TransactionInfo, error) {
+	if err := fees.CheckDeposit(amount, fee); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err = accounts.GetBalanceForUpdate(ctx, tx, account.ID); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	now := time.Now().UTC()
+	entryID, err := ledger.Post(ctx, tx, ledger.Entry{
+		Kind:        ledger.KindDeposit,
+		Description: description,
+		Postings: []ledger.Posting{
+			{Account: ledger.DepositsClearing, Amount: amount.Neg()},
+			{Account: ledger.WalletAccount(account.ID), Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	trx := domain.TransactionInfo{
+		AccountID:          account.ID,
+		DestinationCVU:     account.CVU,
+		Description:        description,
+		Amount:             amount,
+		DateTime:           now,
+		Type:               domain.TransactionTypeDeposit,
+		Currency:           currency.Primary,
+		JournalEntryID:     entryID,
+		ProcessorReference: reference,
+	}
+	trx.ID, err = Save(ctx, tx, trx)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if err = chargeFee(ctx, tx, account, fee, trx.ID, now); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	return trx, nil
+}
+
+// chargeFee moves fee from the account to the fees revenue account and
+// records it as a transaction of its own, referring to the charged one.
+func chargeFee(ctx context.Context, tx *sql.Tx, account domain.Account, fee decimal.Decimal, transactionID int, now time.Time) error {
+	if fee.IsZero() {
+		return nil
+	}
+
+	description := fmt.Sprintf("Fee for transaction %d", transactionID)
+	entryID, err := ledger.Post(ctx, tx, ledger.Entry{
+		Kind:        ledger.KindFee,
+		Description: description,
+		Postings: []ledger.Posting{
+			{Account: ledger.WalletAccount(account.ID), Amount: fee.Neg()},
+			{Account: ledger.Fees, Amount: fee},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = Save(ctx, tx, domain.TransactionInfo{
+		AccountID:      account.ID,
+		OriginCVU:      account.CVU,
+		Description:    description,
+		Amount:         fee.Neg(),
+		DateTime:       now,
+		Type:           domain.TransactionTypeFee,
+		Currency:       currency.Primary,
+		JournalEntryID: entryID,
+	})
+	return err
+}
+
+// Reverse undoes the transfer the transaction belongs to. Both rows of the
+// transfer are locked and checked again so a transfer is never reversed twice,
+// then a reversal row is recorded for each account, linked with the row it
+// compensates, and the money goes back to the origin. A non-zero amount must
+// match the transfer, since only whole transfers are reversed. It returns the
+// reversal row of the account of transactionID.
+// Card deposits are reversed the same way, the money going back to the card
+// through refund once the reversal is committed. See reverseDeposit.
+func (r *repository) Reverse(ctx context.Context, transactionID int, amount decimal.Decimal, description string, refund RefundFunc) (domain.TransactionInfo, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+	defer tx.Rollback()
+
+	var entryID sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT journal_entry_id FROM transactions WHERE id = ?;", transactionID).Scan(&entryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.TransactionInfo{}, ErrTransactionNotFound
+		}
+		return domain.TransactionInfo{}, err
+	}
+	if !entryID.Valid {
+		return domain.TransactionInfo{}, ErrNotReversible
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM transactions WHERE journal_entry_id = ? ORDER BY id FOR UPDATE;", transactionColumns)
+	rows, err := tx.QueryContext(ctx, query, entryID.Int64)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	var out, in, deposit domain.TransactionInfo
+	for rows.Next() {
+		trx, err := scanTransaction(rows)
+		if err != nil {
+			rows.Close()
+			return domain.TransactionInfo{}, err
+		}
+
+		switch trx.Type {
+		case domain.TransactionTypeTransferOut:
+			out = trx
+		case domain.TransactionTypeTransferIn:
+			in = trx
+		case domain.TransactionTypeDeposit:
+			deposit = trx
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if deposit.ID != 0 {
+		reversal, reference, err := reverseDeposit(ctx, tx, deposit, amount, description)
+		if err != nil {
+			return domain.TransactionInfo{}, err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return domain.TransactionInfo{}, err
+		}
+
+		if err = r.refund(ctx, reversal.ID, reference, refund); err != nil {
+			return domain.TransactionInfo{}, err
+		}
+
+		return reversal, nil
+	}
+
+	if out.ID == 0 || in.ID == 0 {
+		return domain.TransactionInfo{}, ErrNotReversible
+	}
+	if out.ReversedBy != 0 || in.ReversedBy != 0 {
+		return domain.TransactionInfo{}, ErrAlreadyReversed
+	}
+	if !amount.IsZero() && !amount.Equal(in.Amount) {
+		return domain.TransactionInfo{}, ErrPartialReversal
+	}
+	amount = in.Amount
+
+	first, second := out.AccountID, in.AccountID
+	if first > second {
+		first, second = second, first
+	}
+
+	balances := make(map[int]decimal.Decimal, 2)
+	for _, id := range []int{first, second} {
+		balance, err := accounts.GetBalanceForUpdate(ctx, tx, id)
+		if err != nil {
+			return domain.TransactionInfo{}, err
+		}
+		balances[id] = balance
+	}
+
+	if balances[in.AccountID].LessThan(amount) {
+		return domain.TransactionInfo{}, ErrInsufficientFunds
+	}
+
+	now := time.Now().UTC()
+	reversalEntryID, err := ledger.Post(ctx, tx, ledger.Entry{
+		Kind:        ledger.KindReversal,
+		Description: description,
+		Postings: []ledger.Posting{
+			{Account: ledger.WalletAccount(in.AccountID), Amount: amount.Neg()},
+			{Account: ledger.WalletAccount(out.AccountID), Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	// the money goes back, so origin and destination swap
+	reversals := []domain.TransactionInfo{
+		{
+			AccountID:      in.AccountID,
+			OriginCVU:      in.DestinationCVU,
+			DestinationCVU: in.OriginCVU,
+			Description:    description,
+			Amount:         amount.Neg(),
+			DateTime:       now,
+			Type:           domain.TransactionTypeReversal,
+			ReversalOf:     in.ID,
+			Currency:       currency.Primary,
+			JournalEntryID: reversalEntryID,
+		},
+		{
+			AccountID:      out.AccountID,
+			OriginCVU:      out.DestinationCVU,
+			DestinationCVU: out.OriginCVU,
+			Description:    description,
+			Amount:         amount,
+			DateTime:       now,
+			Type:           domain.TransactionTypeReversal,
+			ReversalOf:     out.ID,
+			Currency:       currency.Primary,
+			JournalEntryID: reversalEntryID,
+		},
+	}
+
+	var result domain.TransactionInfo
+	for _, reversal := range reversals {
+		reversal.ID, err = Save(ctx, tx, reversal)
+		if err != nil {
+			return domain.TransactionInfo{}, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE transactions SET reversed_by = ? WHERE id = ?;", reversal.ID, reversal.ReversalOf)
+		if err != nil {
+			return domain.TransactionInfo{}, err
+		}
+
+		if reversal.ReversalOf == transactionID {
+			result = reversal
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	return result, nil
+}
+
+// reverseDeposit takes the deposit back from the account to the deposits
+// clearing account, with the deposit row already locked by the caller. The
+// reversal is saved with its refund pending and the charge reference to give
+// back, which is refunded only after tx commits: a refund that fails is marked
+// on the reversal and made again when the deposit is reversed again, instead
+// of leaving a refunded charge with its deposit in place. The fee charged on
+// the deposit is not returned.
+// Deposits made before their charge reference was kept cannot be reversed.
+func reverseDeposit(ctx context.Context, tx *sql.Tx, deposit domain.TransactionInfo, amount decimal.Decimal, description string) (domain.TransactionInfo, string, error) {
+	if deposit.ReversedBy != 0 {
+		return pendingRefund(ctx, tx, deposit.ReversedBy)
+	}
+	if !amount.IsZero() && !amount.Equal(deposit.Amount) {
+		return domain.TransactionInfo{}, "", ErrPartialReversal
+	}
+	amount = deposit.Amount
+
+	var reference sql.NullString
+	err := tx.QueryRowContext(ctx, "SELECT processor_reference FROM transactions WHERE id = ?;", deposit.ID).Scan(&reference)
+	if err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+	if reference.String == "" {
+		return domain.TransactionInfo{}, "", ErrNotReversible
+	}
+
+	balance, err := accounts.GetBalanceForUpdate(ctx, tx, deposit.AccountID)
+	if err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+	if balance.LessThan(amount) {
+		return domain.TransactionInfo{}, "", ErrInsufficientFunds
+	}
+
+	now := time.Now().UTC()
+	entryID, err := ledger.Post(ctx, tx, ledger.Entry{
+		Kind:        ledger.KindReversal,
+		Description: description,
+		Postings: []ledger.Posting{
+			{Account: ledger.WalletAccount(deposit.AccountID), Amount: amount.Neg()},
+			{Account: ledger.DepositsClearing, Amount: amount},
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+
+	reversal := domain.TransactionInfo{
+		AccountID:          deposit.AccountID,
+		OriginCVU:          deposit.DestinationCVU,
+		Description:        description,
+		Amount:             amount.Neg(),
+		DateTime:           now,
+		Type:               domain.TransactionTypeReversal,
+		ReversalOf:         deposit.ID,
+		Currency:           currency.Primary,
+		JournalEntryID:     entryID,
+		ProcessorReference: reference.String,
+	}
+	if reversal.ID, err = Save(ctx, tx, reversal); err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE transactions SET reversed_by = ? WHERE id = ?;", reversal.ID, deposit.ID)
+	if err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+
+	if err = setRefundStatus(ctx, tx, reversal.ID, refundPending); err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+
+	return reversal, reference.String, nil
+}
+
+// pendingRefund returns the reversal of a deposit and the charge to refund if
+// its refund has not gone through yet, so reversing the deposit again retries
+// the refund instead of failing.
+func pendingRefund(ctx context.Context, tx *sql.Tx, reversalID int) (domain.TransactionInfo, string, error) {
+	var reference, status sql.NullString
+	query := "SELECT processor_reference, refund_status FROM transactions WHERE id = ? FOR UPDATE;"
+	if err := tx.QueryRowContext(ctx, query, reversalID).Scan(&reference, &status); err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+
+	if status.String != refundPending && status.String != refundFailed {
+		return domain.TransactionInfo{}, "", ErrAlreadyReversed
+	}
+
+	query = fmt.Sprintf("SELECT %s FROM transactions WHERE id = ?;", transactionColumns)
+	reversal, err := scanTransaction(tx.QueryRowContext(ctx, query, reversalID))
+	if err != nil {
+		return domain.TransactionInfo{}, "", err
+	}
+
+	return reversal, reference.String, nil
+}
+
+// refund gives back the charge of a committed deposit reversal and records
+// the result on it. The processor refunds a reference only once, so a refund
+// made again after a lost status update does not pay twice.
+func (r *repository) refund(ctx context.Context, reversalID int, reference string, refund RefundFunc) error {
+	refundErr := refund(ctx, reference)
+
+	status := refundSucceeded
+	if refundErr != nil {
+		status = refundFailed
+	}
+	if err := setRefundStatus(ctx, r.db, reversalID, status); err != nil {
+		return err
+	}
+
+	return refundErr
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func setRefundStatus(ctx context.Context, db execer, transactionID int, status string) error {
+	_, err := db.ExecContext(ctx, "UPDATE transactions SET refund_status = ? WHERE id = ?;", status, transactionID)
+	return err
+}
+
+func Save(ctx context.Context, tx *sql.Tx, trx domain.TransactionInfo) (int, error) {
+	query := "INSERT INTO transactions(account_id, origin_cvu, destination_cvu, description, amount, currency, date_time, type, journal_entry_id, reversal_of, processor_reference) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, trx.AccountID, trx.OriginCVU, trx.DestinationCVU, trx.Description, trx.Amount, trx.Currency, trx.DateTime, trx.Type, trx.JournalEntryID,
+		sql.NullInt64{Int64: int64(trx.ReversalOf), Valid: trx.ReversalOf != 0}, sql.NullString{String: trx.ProcessorReference, Valid: trx.ProcessorReference != ""})
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row scanner) (domain.TransactionInfo, error) {
+	var trx domain.TransactionInfo
+	var reversalOf, reversedBy sql.NullInt64
+	err := row.Scan(&trx.ID, &trx.AccountID, &trx.OriginCVU, &trx.DestinationCVU, &trx.Description, &trx.Amount, &trx.Currency,
+		&trx.DateTime, &trx.Type, &reversalOf, &reversedBy)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+	trx.ReversalOf = int(reversalOf.Int64)
+	trx.ReversedBy = int(reversedBy.Int64)
+
+	return trx, nil
+}
@@ -0,0 +1,426 @@
+package transactions
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/leorodriguez/grupo-04/internal/currency"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/fees"
+	"gitlab.com/leorodriguez/grupo-04/internal/ledger"
+	"gitlab.com/leorodriguez/grupo-04/internal/limits"
+)
+
+func TestRepositoryTransferSuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	origin := domain.Account{ID: 2, CVU: "0000000000000000000002"}
+	destination := domain.Account{ID: 1, CVU: "0000000000000000000001"}
+	amount := decimal.NewFromInt(50)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("100.00"))
+	expectLimits(mock, 2, "0.00", 0)
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindTransfer, "rent", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(4, "wallet:2", amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount.Neg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(4, "wallet:1", amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(2, origin.CVU, destination.CVU, "rent", amount.Neg(), currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeTransferOut, 4, nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, origin.CVU, destination.CVU, "rent", amount, currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeTransferIn, 4, nil, nil).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	trx, err := repo.Transfer(context.Background(), origin, destination, amount, decimal.Zero, "rent", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, trx.ID)
+	assert.Equal(t, domain.TransactionTypeTransferOut, trx.Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryTransferScheduledRunAlreadyExecuted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	origin := domain.Account{ID: 1, CVU: "0000000000000000000001"}
+	destination := domain.Account{ID: 2, CVU: "0000000000000000000002"}
+	amount := decimal.NewFromInt(50)
+	run := &domain.ScheduledRun{ScheduledTransferID: 3, RunAt: time.Date(2022, 12, 1, 9, 0, 0, 0, time.UTC)}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("100.00"))
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	expectLimits(mock, 1, "0.00", 0)
+	mock.ExpectExec("INSERT INTO journal_entries").WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO postings").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO scheduled_transfer_runs(scheduled_transfer_id, run_at, transaction_id) VALUES(?, ?, ?);")).
+		WithArgs(3, run.RunAt, 7).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	_, err = repo.Transfer(context.Background(), origin, destination, amount, decimal.Zero, "rent", run)
+	assert.Equal(t, ErrAlreadyExecuted, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryTransferLimitExceeded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	origin := domain.Account{ID: 1}
+	destination := domain.Account{ID: 2}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("300000.00"))
+	mock.ExpectQuery("SELECT balance FROM accounts").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	expectLimits(mock, 1, "240000.00", 1)
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	_, err = repo.Transfer(context.Background(), origin, destination, decimal.NewFromInt(20000), decimal.Zero, "", nil)
+	assert.Equal(t, &limits.ErrLimitExceeded{Limit: limits.LimitDaily, Remaining: decimal.RequireFromString("10000.00")}, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectLimits expects the limits check of a standard account that already
+// transferred used today and this month, transfers times in the last hour.
+func expectLimits(mock sqlmock.Sqlmock, accountID int, used string, transfers int) {
+	mock.ExpectQuery("SELECT tier, per_transaction, daily, monthly, max_transfers_per_hour FROM account_limits").WithArgs(accountID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("FROM transactions WHERE account_id = ").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), accountID,
+		domain.TransactionTypeTransferOut, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"daily", "monthly", "count"}).AddRow(used, used, transfers))
+}
+
+func TestRepositoryTransferInsufficientFunds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	origin := domain.Account{ID: 1}
+	destination := domain.Account{ID: 2}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("10.00"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	_, err = repo.Transfer(context.Background(), origin, destination, decimal.NewFromInt(50), decimal.Zero, "", nil)
+	assert.Equal(t, ErrInsufficientFunds, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryDepositSuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	account := domain.Account{ID: 1, CVU: "0000000000000000000001"}
+	amount := decimal.NewFromInt(500)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindDeposit, "Deposit from card **** 3704", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, ledger.DepositsClearing, amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, "wallet:1", amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, "", account.CVU, "Deposit from card **** 3704", amount, currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeDeposit, 5, nil, "fake-1").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	trx, err := repo.Deposit(context.Background(), account, amount, decimal.Zero, "Deposit from card **** 3704", "fake-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, trx.ID)
+	assert.Equal(t, domain.TransactionTypeDeposit, trx.Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryDepositChargesFee(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	account := domain.Account{ID: 1, CVU: "0000000000000000000001"}
+	amount := decimal.NewFromInt(500)
+	fee := decimal.RequireFromString("7.50")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindDeposit, "Deposit from card **** 3704", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, ledger.DepositsClearing, amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(5, "wallet:1", amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, "", account.CVU, "Deposit from card **** 3704", amount, currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeDeposit, 5, nil, "fake-1").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindFee, "Fee for transaction 3", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(6, "wallet:1", fee.Neg()).WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(fee.Neg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(6, ledger.Fees, fee).WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, account.CVU, "", "Fee for transaction 3", fee.Neg(), currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeFee, 6, nil, nil).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	trx, err := repo.Deposit(context.Background(), account, amount, fee, "Deposit from card **** 3704", "fake-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, trx.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryDepositFeeExceedsAmount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+	_, err = repo.Deposit(context.Background(), domain.Account{ID: 1}, decimal.NewFromInt(5), decimal.NewFromInt(10), "Deposit from card **** 3704", "fake-1")
+	assert.Equal(t, fees.ErrFeeExceedsAmount, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryGetActivitySuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+	cursorTrx := domain.TransactionInfo{ID: 9, DateTime: now}
+	filters := domain.ActivityFilters{
+		Types:       []string{domain.TransactionTypeTransferIn, domain.TransactionTypeTransferOut},
+		MinAmount:   decimal.NewNullDecimal(decimal.NewFromInt(10)),
+		Description: "rent",
+		Cursor:      encodeCursor(cursorTrx),
+		Limit:       21,
+	}
+
+	columns := []string{"id", "account_id", "origin_cvu", "destination_cvu", "description", "amount", "currency", "date_time", "type", "reversal_of", "reversed_by"}
+	rows := sqlmock.NewRows(columns).
+		AddRow(8, 1, "0000000000000000000001", "0000000000000000000002", "rent", "-50.00", currency.ARS, now.Add(-time.Hour), domain.TransactionTypeTransferOut, nil, 12)
+	query := "SELECT id, account_id, origin_cvu, destination_cvu, description, amount, currency, date_time, type, reversal_of, reversed_by FROM transactions " +
+		"WHERE account_id = ? AND (date_time < ? OR (date_time = ? AND id < ?)) AND type IN (?, ?) AND ABS(amount) >= ? " +
+		"AND description LIKE ? ORDER BY date_time DESC, id DESC LIMIT ?;"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(1, now, now, 9, domain.TransactionTypeTransferIn, domain.TransactionTypeTransferOut, decimal.NewFromInt(10), "%rent%", 21).
+		WillReturnRows(rows)
+
+	repo := NewRepository(db)
+	trxs, err := repo.GetActivity(context.Background(), 1, filters)
+	assert.NoError(t, err)
+	assert.Len(t, trxs, 1)
+	assert.Equal(t, 8, trxs[0].ID)
+	assert.Equal(t, 12, trxs[0].ReversedBy)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryReverseSuccesfully(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+	originCVU, destinationCVU := "0000000000000000000002", "0000000000000000000001"
+	amount := decimal.NewFromInt(50)
+	columns := []string{"id", "account_id", "origin_cvu", "destination_cvu", "description", "amount", "currency", "date_time", "type", "reversal_of", "reversed_by"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT journal_entry_id FROM transactions WHERE id = ?;")).WithArgs(8).
+		WillReturnRows(sqlmock.NewRows([]string{"journal_entry_id"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE journal_entry_id = ? ORDER BY id FOR UPDATE;")).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, 2, originCVU, destinationCVU, "rent", "-50.00", currency.ARS, now, domain.TransactionTypeTransferOut, nil, nil).
+			AddRow(8, 1, originCVU, destinationCVU, "rent", "50.00", currency.ARS, now, domain.TransactionTypeTransferIn, nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("50.00"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindReversal, "Reversal of transaction 8", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(6, "wallet:1", amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount.Neg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(6, "wallet:2", amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, destinationCVU, originCVU, "Reversal of transaction 8", amount.Neg(), currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeReversal, 6, 8, nil).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions SET reversed_by = ? WHERE id = ?;")).WithArgs(12, 8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(2, destinationCVU, originCVU, "Reversal of transaction 8", amount, currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeReversal, 6, 7, nil).
+		WillReturnResult(sqlmock.NewResult(13, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions SET reversed_by = ? WHERE id = ?;")).WithArgs(13, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRepository(db)
+	trx, err := repo.Reverse(context.Background(), 8, decimal.Zero, "Reversal of transaction 8", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, trx.ID)
+	assert.Equal(t, 8, trx.ReversalOf)
+	assert.True(t, amount.Neg().Equal(trx.Amount))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryReverseAlreadyReversed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+	columns := []string{"id", "account_id", "origin_cvu", "destination_cvu", "description", "amount", "currency", "date_time", "type", "reversal_of", "reversed_by"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT journal_entry_id FROM transactions WHERE id = ?;")).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"journal_entry_id"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE journal_entry_id = ? ORDER BY id FOR UPDATE;")).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, 2, "0000000000000000000002", "0000000000000000000001", "rent", "-50.00", currency.ARS, now, domain.TransactionTypeTransferOut, nil, 13).
+			AddRow(8, 1, "0000000000000000000002", "0000000000000000000001", "rent", "50.00", currency.ARS, now, domain.TransactionTypeTransferIn, nil, 12))
+	mock.ExpectRollback()
+
+	repo := NewRepository(db)
+	_, err = repo.Reverse(context.Background(), 7, decimal.Zero, "Reversal of transaction 7", nil)
+	assert.Equal(t, ErrAlreadyReversed, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryReverseDepositRefundsCharge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+	cvu := "0000000000000000000001"
+	amount := decimal.NewFromInt(500)
+	columns := []string{"id", "account_id", "origin_cvu", "destination_cvu", "description", "amount", "currency", "date_time", "type", "reversal_of", "reversed_by"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT journal_entry_id FROM transactions WHERE id = ?;")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"journal_entry_id"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE journal_entry_id = ? ORDER BY id FOR UPDATE;")).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, 1, "", cvu, "Deposit from card **** 3704", "500.00", currency.ARS, now, domain.TransactionTypeDeposit, nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT processor_reference FROM transactions WHERE id = ?;")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"processor_reference"}).AddRow("fake-1"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT balance FROM accounts WHERE id = ? FOR UPDATE;")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("500.00"))
+	mock.ExpectExec("INSERT INTO journal_entries").WithArgs(ledger.KindReversal, "Reversal of transaction 3", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectPrepare("INSERT INTO postings")
+	mock.ExpectExec("INSERT INTO postings").WithArgs(6, "wallet:1", amount.Neg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("UPDATE accounts SET balance").ExpectExec().WithArgs(amount.Neg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO postings").WithArgs(6, ledger.DepositsClearing, amount).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().
+		WithArgs(1, cvu, "", "Reversal of transaction 3", amount.Neg(), currency.ARS, sqlmock.AnyArg(), domain.TransactionTypeReversal, 6, 3, "fake-1").
+		WillReturnResult(sqlmock.NewResult(12, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions SET reversed_by = ? WHERE id = ?;")).WithArgs(12, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions SET refund_status = ? WHERE id = ?;")).WithArgs("pending", 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions SET refund_status = ? WHERE id = ?;")).WithArgs("refunded", 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var refunded string
+	refund := func(ctx context.Context, reference string) error {
+		refunded = reference
+		return nil
+	}
+
+	repo := NewRepository(db)
+	trx, err := repo.Reverse(context.Background(), 3, decimal.Zero, "Reversal of transaction 3", refund)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, trx.ID)
+	assert.Equal(t, 3, trx.ReversalOf)
+	assert.Equal(t, "fake-1", refunded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryReverseDepositRetriesFailedRefund(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+	cvu := "0000000000000000000001"
+	columns := []string{"id", "account_id", "origin_cvu", "destination_cvu", "description", "amount", "currency", "date_time", "type", "reversal_of", "reversed_by"}
+
+	// the deposit was reversed, but its refund failed
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT journal_entry_id FROM transactions WHERE id = ?;")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"journal_entry_id"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE journal_entry_id = ? ORDER BY id FOR UPDATE;")).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, 1, "", cvu, "Deposit from card **** 3704", "500.00", currency.ARS, now, domain.TransactionTypeDeposit, nil, 12))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT processor_reference, refund_status FROM transactions WHERE id = ? FOR UPDATE;")).WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"processor_reference", "refund_status"}).AddRow("fake-1", "failed"))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM transactions WHERE id = ?;")).WithArgs(12).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(12, 1, cvu, "", "Reversal of transaction 3", "-500.00", currency.ARS, now, domain.TransactionTypeReversal, 3, nil))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE transactions SET refund_status = ? WHERE id = ?;")).WithArgs("failed", 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// once refunded, it cannot be reversed again
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT journal_entry_id FROM transactions WHERE id = ?;")).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"journal_entry_id"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE journal_entry_id = ? ORDER BY id FOR UPDATE;")).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, 1, "", cvu, "Deposit from card **** 3704", "500.00", currency.ARS, now, domain.TransactionTypeDeposit, nil, 12))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT processor_reference, refund_status FROM transactions WHERE id = ? FOR UPDATE;")).WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"processor_reference", "refund_status"}).AddRow("fake-1", "refunded"))
+	mock.ExpectRollback()
+
+	refund := func(ctx context.Context, reference string) error {
+		return ErrRefundFailed
+	}
+
+	repo := NewRepository(db)
+	_, err = repo.Reverse(context.Background(), 3, decimal.Zero, "Reversal of transaction 3", refund)
+	assert.Equal(t, ErrRefundFailed, err)
+
+	_, err = repo.Reverse(context.Background(), 3, decimal.Zero, "Reversal of transaction 3", refund)
+	assert.Equal(t, ErrAlreadyReversed, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,28 +2,281 @@ package transactions
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/cards"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/fees"
+	"gitlab.com/leorodriguez/grupo-04/internal/processor"
+	"gitlab.com/leorodriguez/grupo-04/pkg/logger"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("amount must be greater than zero")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrSelfTransfer        = errors.New("cannot transfer to the same account")
+	ErrDestinationNotFound = accounts.ErrDestinationNotFound
+	ErrInvalidDestination  = accounts.ErrInvalidDestination
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrInvalidFilter       = errors.New("invalid filter")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrNotReversible       = errors.New("transaction cannot be reversed")
+	ErrAlreadyReversed     = errors.New("transaction already reversed")
+	ErrPartialReversal     = errors.New("partial reversals are not allowed")
+	ErrAlreadyExecuted     = errors.New("scheduled transfer run already executed")
+	ErrRefundFailed        = errors.New("card refund failed")
+	ErrDescriptionTooLong  = fmt.Errorf("description must be at most %d characters", MaxDescriptionLength)
+)
+
+// MaxDescriptionLength is the size of the description column of transactions.
+const MaxDescriptionLength = 50
+
+const (
+	defaultActivityLimit = 20
+	maxActivityLimit     = 100
 )
 
 type Service interface {
 	GetTransactionsLastFive(ctx context.Context, id int) ([]domain.TransactionInfo, error)
+	GetActivity(ctx context.Context, accountID int, filters domain.ActivityFilters) (domain.ActivityPage, error)
+	GetByID(ctx context.Context, accountID, transactionID int) (domain.TransactionInfo, error)
+	Transfer(ctx context.Context, accountID int, rq domain.TransferRequest) (domain.TransactionInfo, error)
+	Deposit(ctx context.Context, accountID int, rq domain.DepositRequest) (domain.TransactionInfo, error)
+	Reverse(ctx context.Context, accountID, transactionID int, rq domain.ReversalRequest) (domain.TransactionInfo, error)
+	ReverseAsOperator(ctx context.Context, transactionID int, rq domain.ReversalRequest) (domain.TransactionInfo, error)
 }
 
 type service struct {
 	transactionsRepository Repository
+	accountsRepository     accounts.Repository
+	cardsRepository        cards.Repository
+	processor              processor.Processor
+	feesService            fees.Service
 }
 
-func NewService(transactionsRepository Repository) Service {
+func NewService(transactionsRepository Repository, accountsRepository accounts.Repository, cardsRepository cards.Repository,
+	processor processor.Processor, feesService fees.Service) Service {
 	return &service{
 		transactionsRepository: transactionsRepository,
+		accountsRepository:     accountsRepository,
+		cardsRepository:        cardsRepository,
+		processor:              processor,
+		feesService:            feesService,
 	}
 }
 
 func (s *service) GetTransactionsLastFive(ctx context.Context, id int) ([]domain.TransactionInfo, error) {
-	trx, err := s.transactionsRepository.GetAllByIDLimit(ctx, id, 5)
+	page, err := s.GetActivity(ctx, id, domain.ActivityFilters{Limit: 5})
 	if err != nil {
 		return []domain.TransactionInfo{}, err
 	}
 
+	return page.Transactions, nil
+}
+
+func (s *service) GetActivity(ctx context.Context, accountID int, filters domain.ActivityFilters) (domain.ActivityPage, error) {
+	if err := validateFilters(filters); err != nil {
+		return domain.ActivityPage{}, err
+	}
+
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+
+	// ask for one more row to know if there is a next page
+	filters.Limit = limit + 1
+	trxs, err := s.transactionsRepository.GetActivity(ctx, accountID, filters)
+	if err != nil {
+		return domain.ActivityPage{}, err
+	}
+
+	page := domain.ActivityPage{Transactions: trxs}
+	if len(trxs) > limit {
+		page.Transactions = trxs[:limit]
+		page.NextCursor = encodeCursor(page.Transactions[limit-1])
+	}
+	if page.Transactions == nil {
+		page.Transactions = []domain.TransactionInfo{}
+	}
+
+	return page, nil
+}
+
+func (s *service) GetByID(ctx context.Context, accountID, transactionID int) (domain.TransactionInfo, error) {
+	return s.transactionsRepository.GetByID(ctx, accountID, transactionID)
+}
+
+func (s *service) Transfer(ctx context.Context, accountID int, rq domain.TransferRequest) (domain.TransactionInfo, error) {
+	if !rq.Amount.IsPositive() {
+		return domain.TransactionInfo{}, ErrInvalidAmount
+	}
+	if utf8.RuneCountInString(rq.Description) > MaxDescriptionLength {
+		return domain.TransactionInfo{}, ErrDescriptionTooLong
+	}
+
+	origin, err := s.accountsRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	destination, err := s.getDestination(ctx, rq)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if origin.ID == destination.ID {
+		return domain.TransactionInfo{}, ErrSelfTransfer
+	}
+
+	fee, err := s.feesService.Compute(ctx, origin.ID, fees.OperationTransfer, rq.Amount)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if origin.Balance.LessThan(rq.Amount.Add(fee)) {
+		return domain.TransactionInfo{}, ErrInsufficientFunds
+	}
+
+	return s.transactionsRepository.Transfer(ctx, origin, destination, rq.Amount, fee, rq.Description, rq.ScheduledRun)
+}
+
+// getDestination returns the account a transfer pays: the one the scheduler
+// already resolved, or the one rq.Destination points to now.
+func (s *service) getDestination(ctx context.Context, rq domain.TransferRequest) (domain.Account, error) {
+	if rq.DestinationAccountID == 0 {
+		return accounts.GetDestination(ctx, s.accountsRepository, rq.Destination)
+	}
+
+	destination, err := s.accountsRepository.GetAccountByID(ctx, rq.DestinationAccountID)
+	if err == accounts.ErrAccountNotFound {
+		return domain.Account{}, ErrDestinationNotFound
+	}
+
+	return destination, err
+}
+
+func (s *service) Deposit(ctx context.Context, accountID int, rq domain.DepositRequest) (domain.TransactionInfo, error) {
+	if !rq.Amount.IsPositive() {
+		return domain.TransactionInfo{}, ErrInvalidAmount
+	}
+
+	account, err := s.accountsRepository.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	var card domain.Card
+	if rq.CardID == 0 {
+		card, err = s.cardsRepository.GetDefault(ctx, accountID)
+	} else {
+		card, err = s.cardsRepository.GetByID(ctx, accountID, rq.CardID)
+	}
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if card.VerificationStatus != domain.CardVerificationVerified {
+		return domain.TransactionInfo{}, cards.ErrCardNotVerified
+	}
+
+	fee, err := s.feesService.Compute(ctx, accountID, fees.OperationDeposit, rq.Amount)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if err = fees.CheckDeposit(rq.Amount, fee); err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	reference, err := s.processor.Charge(ctx, card, rq.Amount)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	description := fmt.Sprintf("Deposit from card %s", cards.MaskPAN(card.LastFour))
+	trx, err := s.transactionsRepository.Deposit(ctx, account, rq.Amount, fee, description, reference)
+	if err != nil {
+		// the money was already taken from the card, give it back
+		if refundErr := s.processor.Refund(ctx, reference); refundErr != nil {
+			logger.Error(fmt.Sprintf("refunding charge %s: %s", reference, refundErr.Error()))
+		}
+		return domain.TransactionInfo{}, err
+	}
+
 	return trx, nil
 }
+
+// Reverse lets the account that received a transfer give the money back, and
+// the account that made a card deposit send it back to the card.
+func (s *service) Reverse(ctx context.Context, accountID, transactionID int, rq domain.ReversalRequest) (domain.TransactionInfo, error) {
+	trx, err := s.transactionsRepository.GetByID(ctx, accountID, transactionID)
+	if err != nil {
+		return domain.TransactionInfo{}, err
+	}
+
+	if trx.Type != domain.TransactionTypeTransferIn && trx.Type != domain.TransactionTypeDeposit {
+		return domain.TransactionInfo{}, ErrNotReversible
+	}
+
+	if trx.ReversedBy != 0 {
+		return domain.TransactionInfo{}, ErrAlreadyReversed
+	}
+
+	return s.ReverseAsOperator(ctx, transactionID, rq)
+}
+
+// ReverseAsOperator reverses any transfer, given either of its two rows, or
+// card deposit, refunding the charge. Reversing a deposit whose refund failed
+// again retries the refund.
+func (s *service) ReverseAsOperator(ctx context.Context, transactionID int, rq domain.ReversalRequest) (domain.TransactionInfo, error) {
+	if rq.Amount.IsNegative() {
+		return domain.TransactionInfo{}, ErrInvalidAmount
+	}
+
+	description := fmt.Sprintf("Reversal of transaction %d", transactionID)
+	return s.transactionsRepository.Reverse(ctx, transactionID, rq.Amount, description, s.refund)
+}
+
+// refund gives back a card charge for a reversed deposit.
+func (s *service) refund(ctx context.Context, reference string) error {
+	if err := s.processor.Refund(ctx, reference); err != nil {
+		logger.Error(fmt.Sprintf("refunding charge %s: %s", reference, err.Error()))
+		return ErrRefundFailed
+	}
+
+	return nil
+}
+
+func validateFilters(filters domain.ActivityFilters) error {
+	for _, t := range filters.Types {
+		switch t {
+		case domain.TransactionTypeDeposit, domain.TransactionTypeTransferIn, domain.TransactionTypeTransferOut,
+			domain.TransactionTypeAdjustment, domain.TransactionTypeReversal, domain.TransactionTypeFee, domain.TransactionTypeConversion:
+		default:
+			return ErrInvalidFilter
+		}
+	}
+
+	if !filters.From.IsZero() && !filters.To.IsZero() && filters.From.After(filters.To) {
+		return ErrInvalidFilter
+	}
+
+	if filters.MinAmount.Valid && filters.MaxAmount.Valid && filters.MinAmount.Decimal.GreaterThan(filters.MaxAmount.Decimal) {
+		return ErrInvalidFilter
+	}
+
+	if filters.Cursor != "" {
+		if _, err := decodeCursor(filters.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,599 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/leorodriguez/grupo-04/internal/accounts"
+	"gitlab.com/leorodriguez/grupo-04/internal/cards"
+	"gitlab.com/leorodriguez/grupo-04/internal/domain"
+	"gitlab.com/leorodriguez/grupo-04/internal/fees"
+	"gitlab.com/leorodriguez/grupo-04/internal/processor"
+)
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) GetActivity(ctx context.Context, accountID int, filters domain.ActivityFilters) ([]domain.TransactionInfo, error) {
+	args := r.Called(ctx, accountID, filters)
+	return args.Get(0).([]domain.TransactionInfo), args.Error(1)
+}
+
+func (r *repositoryMock) GetByID(ctx context.Context, accountID, transactionID int) (domain.TransactionInfo, error) {
+	args := r.Called(ctx, accountID, transactionID)
+	return args.Get(0).(domain.TransactionInfo), args.Error(1)
+}
+
+func (r *repositoryMock) GetByDateRange(ctx context.Context, accountID int, from, to time.Time) ([]domain.TransactionInfo, error) {
+	args := r.Called(ctx, accountID, from, to)
+	return args.Get(0).([]domain.TransactionInfo), args.Error(1)
+}
+
+func (r *repositoryMock) SumSince(ctx context.Context, accountID int, since time.Time) (decimal.Decimal, error) {
+	args := r.Called(ctx, accountID, since)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (r *repositoryMock) Transfer(ctx context.Context, origin, destination domain.Account, amount, fee decimal.Decimal, description string, run *domain.ScheduledRun) (domain.TransactionInfo, error) {
+	args := r.Called(ctx, origin, destination, amount, fee, description, run)
+	return args.Get(0).(domain.TransactionInfo), args.Error(1)
+}
+
+type accountsRepositoryMock struct {
+	mock.Mock
+	accounts.Repository
+}
+
+func (r *accountsRepositoryMock) GetAccountByID(ctx context.Context, id int) (domain.Account, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func (r *accountsRepositoryMock) GetAccountByCVU(ctx context.Context, cvu string) (domain.Account, error) {
+	args := r.Called(ctx, cvu)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func (r *accountsRepositoryMock) GetAccountByAlias(ctx context.Context, alias string) (domain.Account, error) {
+	args := r.Called(ctx, alias)
+	return args.Get(0).(domain.Account), args.Error(1)
+}
+
+func (r *repositoryMock) Deposit(ctx context.Context, account domain.Account, amount, fee decimal.Decimal, description, reference string) (domain.TransactionInfo, error) {
+	args := r.Called(ctx, account, amount, fee, description, reference)
+	return args.Get(0).(domain.TransactionInfo), args.Error(1)
+}
+
+func (r *repositoryMock) Reverse(ctx context.Context, transactionID int, amount decimal.Decimal, description string, refund RefundFunc) (domain.TransactionInfo, error) {
+	args := r.Called(ctx, transactionID, amount, description)
+	return args.Get(0).(domain.TransactionInfo), args.Error(1)
+}
+
+type feesServiceMock struct {
+	mock.Mock
+	fees.Service
+}
+
+func (f *feesServiceMock) Compute(ctx context.Context, accountID int, operation string, amount decimal.Decimal) (decimal.Decimal, error) {
+	args := f.Called(ctx, accountID, operation, amount)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+type cardsRepositoryMock struct {
+	mock.Mock
+	cards.Repository
+}
+
+func (r *cardsRepositoryMock) GetByID(ctx context.Context, accountID, cardID int) (domain.Card, error) {
+	args := r.Called(ctx, accountID, cardID)
+	return args.Get(0).(domain.Card), args.Error(1)
+}
+
+func (r *cardsRepositoryMock) GetDefault(ctx context.Context, accountID int) (domain.Card, error) {
+	args := r.Called(ctx, accountID)
+	return args.Get(0).(domain.Card), args.Error(1)
+}
+
+// vaultStub maps tokens to PANs for the fake processor.
+type vaultStub map[string]string
+
+func (v vaultStub) Detokenize(ctx context.Context, token, purpose string) (string, error) {
+	return v[token], nil
+}
+
+func Test_service_Transfer(t *testing.T) {
+	var ctx = context.Background()
+	origin := domain.Account{ID: 1, CVU: "0000003100000000000017", Alias: "casa.perro.gato", Balance: decimal.NewFromInt(100)}
+	destination := domain.Account{ID: 2, CVU: "0000003100000000000024", Alias: "mesa.silla.sol", Balance: decimal.Zero}
+	amount := decimal.NewFromInt(50)
+	testCases := []struct {
+		name           string
+		rq             domain.TransferRequest
+		repoMock       func(m *mock.Mock)
+		accountsMock   func(m *mock.Mock)
+		feesMock       func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.TransactionInfo
+	}{
+		{
+			name:          "Invalid amount",
+			rq:            domain.TransferRequest{Destination: destination.Alias, Amount: decimal.NewFromInt(-1)},
+			repoMock:      func(m *mock.Mock) {},
+			accountsMock:  func(m *mock.Mock) {},
+			expectedError: ErrInvalidAmount,
+		},
+		{
+			name:          "Description too long",
+			rq:            domain.TransferRequest{Destination: destination.Alias, Amount: amount, Description: strings.Repeat("a", MaxDescriptionLength+1)},
+			repoMock:      func(m *mock.Mock) {},
+			accountsMock:  func(m *mock.Mock) {},
+			expectedError: ErrDescriptionTooLong,
+		},
+		{
+			name:     "Destination not found",
+			rq:       domain.TransferRequest{Destination: "no.existe.alias", Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByAlias", ctx, "no.existe.alias").Return(domain.Account{}, accounts.ErrAccountNotFound)
+			},
+			expectedError: ErrDestinationNotFound,
+		},
+		{
+			name:     "Invalid destination CVU",
+			rq:       domain.TransferRequest{Destination: "0000003100000000000018", Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+			},
+			expectedError: ErrInvalidDestination,
+		},
+		{
+			name:     "Self transfer",
+			rq:       domain.TransferRequest{Destination: origin.CVU, Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByCVU", ctx, origin.CVU).Return(origin, nil)
+			},
+			expectedError: ErrSelfTransfer,
+		},
+		{
+			name:     "Insufficient funds",
+			rq:       domain.TransferRequest{Destination: destination.CVU, Amount: decimal.NewFromInt(1000)},
+			repoMock: func(m *mock.Mock) {},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByCVU", ctx, destination.CVU).Return(destination, nil)
+			},
+			feesMock: func(m *mock.Mock) {
+				m.On("Compute", ctx, origin.ID, fees.OperationTransfer, decimal.NewFromInt(1000)).Return(decimal.Zero, nil)
+			},
+			expectedError: ErrInsufficientFunds,
+		},
+		{
+			name:     "Insufficient funds to pay the fee",
+			rq:       domain.TransferRequest{Destination: destination.CVU, Amount: decimal.NewFromInt(99)},
+			repoMock: func(m *mock.Mock) {},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByCVU", ctx, destination.CVU).Return(destination, nil)
+			},
+			feesMock: func(m *mock.Mock) {
+				m.On("Compute", ctx, origin.ID, fees.OperationTransfer, decimal.NewFromInt(99)).Return(decimal.NewFromInt(2), nil)
+			},
+			expectedError: ErrInsufficientFunds,
+		},
+		{
+			name: "Transfer by alias successfully",
+			rq:   domain.TransferRequest{Destination: destination.Alias, Amount: amount, Description: "rent"},
+			repoMock: func(m *mock.Mock) {
+				m.On("Transfer", ctx, origin, destination, amount, decimal.NewFromInt(1), "rent", (*domain.ScheduledRun)(nil)).Return(domain.TransactionInfo{ID: 10, Amount: amount.Neg()}, nil)
+			},
+			feesMock: func(m *mock.Mock) {
+				m.On("Compute", ctx, origin.ID, fees.OperationTransfer, amount).Return(decimal.NewFromInt(1), nil)
+			},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByAlias", ctx, destination.Alias).Return(destination, nil)
+			},
+			expectedResult: domain.TransactionInfo{ID: 10, Amount: amount.Neg()},
+		},
+		{
+			name: "Scheduled transfer pays the account it was scheduled to",
+			rq:   domain.TransferRequest{Destination: "alias.ya.cambiado", DestinationAccountID: destination.ID, Amount: amount},
+			repoMock: func(m *mock.Mock) {
+				m.On("Transfer", ctx, origin, destination, amount, decimal.Zero, "", (*domain.ScheduledRun)(nil)).Return(domain.TransactionInfo{ID: 11, Amount: amount.Neg()}, nil)
+			},
+			feesMock: func(m *mock.Mock) {
+				m.On("Compute", ctx, origin.ID, fees.OperationTransfer, amount).Return(decimal.Zero, nil)
+			},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByID", ctx, destination.ID).Return(destination, nil)
+			},
+			expectedResult: domain.TransactionInfo{ID: 11, Amount: amount.Neg()},
+		},
+		{
+			name:     "Scheduled destination account closed",
+			rq:       domain.TransferRequest{Destination: destination.Alias, DestinationAccountID: destination.ID, Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByID", ctx, destination.ID).Return(domain.Account{}, accounts.ErrAccountNotFound)
+			},
+			expectedError: ErrDestinationNotFound,
+		},
+		{
+			name: "Repository error",
+			rq:   domain.TransferRequest{Destination: destination.CVU, Amount: amount},
+			repoMock: func(m *mock.Mock) {
+				m.On("Transfer", ctx, origin, destination, amount, decimal.Zero, "", (*domain.ScheduledRun)(nil)).Return(domain.TransactionInfo{}, errors.New("error"))
+			},
+			feesMock: func(m *mock.Mock) {
+				m.On("Compute", ctx, origin.ID, fees.OperationTransfer, amount).Return(decimal.Zero, nil)
+			},
+			accountsMock: func(m *mock.Mock) {
+				m.On("GetAccountByID", ctx, origin.ID).Return(origin, nil)
+				m.On("GetAccountByCVU", ctx, destination.CVU).Return(destination, nil)
+			},
+			expectedError: errors.New("error"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+			accountsMock := new(accountsRepositoryMock)
+			testCase.accountsMock(&accountsMock.Mock)
+			feesMock := new(feesServiceMock)
+			if testCase.feesMock != nil {
+				testCase.feesMock(&feesMock.Mock)
+			}
+
+			transactionsService := NewService(repoMock, accountsMock, new(cardsRepositoryMock), processor.NewFake(nil), feesMock)
+
+			trx, err := transactionsService.Transfer(ctx, origin.ID, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, trx)
+		})
+	}
+}
+
+func Test_service_Deposit(t *testing.T) {
+	var ctx = context.Background()
+	account := domain.Account{ID: 1, CVU: "0000000000000000000001"}
+	card := domain.Card{ID: 3, AccountID: 1, LastFour: "3704", Token: "tok_3", VerificationStatus: domain.CardVerificationVerified}
+	declinedCard := domain.Card{ID: 4, AccountID: 1, LastFour: "0604", Token: "tok_4", VerificationStatus: domain.CardVerificationVerified}
+	unverifiedCard := domain.Card{ID: 5, AccountID: 1, LastFour: "3704", Token: "tok_3", VerificationStatus: domain.CardVerificationPending}
+	cardVault := vaultStub{card.Token: "4509953566233704", declinedCard.Token: "5031755734530604"}
+	amount := decimal.NewFromInt(500)
+	fee := decimal.NewFromInt(5)
+	testCases := []struct {
+		name           string
+		rq             domain.DepositRequest
+		repoMock       func(m *mock.Mock)
+		cardsMock      func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.TransactionInfo
+		expectedCharge bool
+	}{
+		{
+			name:          "Invalid amount",
+			rq:            domain.DepositRequest{CardID: card.ID},
+			repoMock:      func(m *mock.Mock) {},
+			cardsMock:     func(m *mock.Mock) {},
+			expectedError: ErrInvalidAmount,
+		},
+		{
+			name:     "Card not found",
+			rq:       domain.DepositRequest{CardID: 9, Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, account.ID, 9).Return(domain.Card{}, cards.ErrCardNotFound)
+			},
+			expectedError: cards.ErrCardNotFound,
+		},
+		{
+			name:     "No card and no default card",
+			rq:       domain.DepositRequest{Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetDefault", ctx, account.ID).Return(domain.Card{}, cards.ErrNoDefaultCard)
+			},
+			expectedError: cards.ErrNoDefaultCard,
+		},
+		{
+			name:     "Unverified card",
+			rq:       domain.DepositRequest{CardID: unverifiedCard.ID, Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, account.ID, unverifiedCard.ID).Return(unverifiedCard, nil)
+			},
+			expectedError: cards.ErrCardNotVerified,
+		},
+		{
+			name:     "Fee not lower than the amount",
+			rq:       domain.DepositRequest{CardID: card.ID, Amount: fee},
+			repoMock: func(m *mock.Mock) {},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, account.ID, card.ID).Return(card, nil)
+			},
+			expectedError: fees.ErrFeeExceedsAmount,
+		},
+		{
+			name: "Deposit from the default card",
+			rq:   domain.DepositRequest{Amount: amount},
+			repoMock: func(m *mock.Mock) {
+				m.On("Deposit", ctx, account, amount, fee, "Deposit from card **** 3704", "fake-1").Return(domain.TransactionInfo{ID: 1, Amount: amount}, nil)
+			},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetDefault", ctx, account.ID).Return(card, nil)
+			},
+			expectedResult: domain.TransactionInfo{ID: 1, Amount: amount},
+			expectedCharge: true,
+		},
+		{
+			name:     "Payment declined",
+			rq:       domain.DepositRequest{CardID: declinedCard.ID, Amount: amount},
+			repoMock: func(m *mock.Mock) {},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, account.ID, declinedCard.ID).Return(declinedCard, nil)
+			},
+			expectedError: processor.ErrPaymentDeclined,
+		},
+		{
+			name: "Repository error refunds the charge",
+			rq:   domain.DepositRequest{CardID: card.ID, Amount: amount},
+			repoMock: func(m *mock.Mock) {
+				m.On("Deposit", ctx, account, amount, fee, "Deposit from card **** 3704", "fake-1").Return(domain.TransactionInfo{}, errors.New("error"))
+			},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, account.ID, card.ID).Return(card, nil)
+			},
+			expectedError: errors.New("error"),
+		},
+		{
+			name: "Deposit successfully",
+			rq:   domain.DepositRequest{CardID: card.ID, Amount: amount},
+			repoMock: func(m *mock.Mock) {
+				m.On("Deposit", ctx, account, amount, fee, "Deposit from card **** 3704", "fake-1").Return(domain.TransactionInfo{ID: 1, Amount: amount}, nil)
+			},
+			cardsMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, account.ID, card.ID).Return(card, nil)
+			},
+			expectedResult: domain.TransactionInfo{ID: 1, Amount: amount},
+			expectedCharge: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+			accountsMock := new(accountsRepositoryMock)
+			accountsMock.On("GetAccountByID", ctx, account.ID).Return(account, nil)
+			cardsMock := new(cardsRepositoryMock)
+			testCase.cardsMock(&cardsMock.Mock)
+			feesMock := new(feesServiceMock)
+			feesMock.On("Compute", ctx, account.ID, fees.OperationDeposit, testCase.rq.Amount).Return(fee, nil)
+			fakeProcessor := processor.NewFake(cardVault, cardVault[declinedCard.Token])
+
+			transactionsService := NewService(repoMock, accountsMock, cardsMock, fakeProcessor, feesMock)
+
+			trx, err := transactionsService.Deposit(ctx, account.ID, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, trx)
+			_, charged := fakeProcessor.Charged("fake-1")
+			assert.Equal(t, testCase.expectedCharge, charged)
+		})
+	}
+}
+
+func Test_service_GetActivity(t *testing.T) {
+	var ctx = context.Background()
+	accountID := 1
+	now := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+	trxs := []domain.TransactionInfo{
+		{ID: 3, AccountID: accountID, DateTime: now},
+		{ID: 2, AccountID: accountID, DateTime: now.Add(-time.Hour)},
+		{ID: 1, AccountID: accountID, DateTime: now.Add(-2 * time.Hour)},
+	}
+	testCases := []struct {
+		name           string
+		filters        domain.ActivityFilters
+		repoMock       func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.ActivityPage
+	}{
+		{
+			name:          "Invalid type",
+			filters:       domain.ActivityFilters{Types: []string{"payment"}},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: ErrInvalidFilter,
+		},
+		{
+			name:          "Invalid date range",
+			filters:       domain.ActivityFilters{From: now, To: now.Add(-time.Hour)},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: ErrInvalidFilter,
+		},
+		{
+			name:          "Invalid cursor",
+			filters:       domain.ActivityFilters{Cursor: "not-a-cursor"},
+			repoMock:      func(m *mock.Mock) {},
+			expectedError: ErrInvalidCursor,
+		},
+		{
+			name:    "Page with next cursor",
+			filters: domain.ActivityFilters{Limit: 2},
+			repoMock: func(m *mock.Mock) {
+				m.On("GetActivity", ctx, accountID, domain.ActivityFilters{Limit: 3}).Return(trxs, nil)
+			},
+			expectedResult: domain.ActivityPage{
+				Transactions: trxs[:2],
+				NextCursor:   encodeCursor(trxs[1]),
+			},
+		},
+		{
+			name:    "Last page",
+			filters: domain.ActivityFilters{},
+			repoMock: func(m *mock.Mock) {
+				m.On("GetActivity", ctx, accountID, domain.ActivityFilters{Limit: defaultActivityLimit + 1}).Return(trxs, nil)
+			},
+			expectedResult: domain.ActivityPage{Transactions: trxs},
+		},
+		{
+			name:    "Empty page",
+			filters: domain.ActivityFilters{Types: []string{domain.TransactionTypeDeposit}},
+			repoMock: func(m *mock.Mock) {
+				m.On("GetActivity", ctx, accountID, domain.ActivityFilters{Types: []string{domain.TransactionTypeDeposit}, Limit: defaultActivityLimit + 1}).
+					Return([]domain.TransactionInfo(nil), nil)
+			},
+			expectedResult: domain.ActivityPage{Transactions: []domain.TransactionInfo{}},
+		},
+		{
+			name:    "Conversions",
+			filters: domain.ActivityFilters{Types: []string{domain.TransactionTypeConversion}},
+			repoMock: func(m *mock.Mock) {
+				m.On("GetActivity", ctx, accountID, domain.ActivityFilters{Types: []string{domain.TransactionTypeConversion}, Limit: defaultActivityLimit + 1}).
+					Return(trxs[:1], nil)
+			},
+			expectedResult: domain.ActivityPage{Transactions: trxs[:1]},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+
+			transactionsService := NewService(repoMock, new(accountsRepositoryMock), new(cardsRepositoryMock), processor.NewFake(nil), new(feesServiceMock))
+
+			page, err := transactionsService.GetActivity(ctx, accountID, testCase.filters)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, page)
+		})
+	}
+}
+
+func Test_service_Reverse(t *testing.T) {
+	var ctx = context.Background()
+	accountID := 2
+	received := domain.TransactionInfo{ID: 8, AccountID: accountID, Amount: decimal.NewFromInt(50), Type: domain.TransactionTypeTransferIn}
+	testCases := []struct {
+		name           string
+		transactionID  int
+		rq             domain.ReversalRequest
+		repoMock       func(m *mock.Mock)
+		expectedError  error
+		expectedResult domain.TransactionInfo
+	}{
+		{
+			name:          "Transaction of another account",
+			transactionID: 7,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, accountID, 7).Return(domain.TransactionInfo{}, ErrTransactionNotFound)
+			},
+			expectedError: ErrTransactionNotFound,
+		},
+		{
+			name:          "Only the receiving account can reverse",
+			transactionID: 7,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, accountID, 7).Return(domain.TransactionInfo{ID: 7, AccountID: accountID, Type: domain.TransactionTypeTransferOut}, nil)
+			},
+			expectedError: ErrNotReversible,
+		},
+		{
+			name:          "Card deposit",
+			transactionID: 9,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, accountID, 9).Return(domain.TransactionInfo{ID: 9, AccountID: accountID, Type: domain.TransactionTypeDeposit}, nil)
+				m.On("Reverse", ctx, 9, decimal.Decimal{}, "Reversal of transaction 9").
+					Return(domain.TransactionInfo{ID: 13, ReversalOf: 9, Type: domain.TransactionTypeReversal}, nil)
+			},
+			expectedResult: domain.TransactionInfo{ID: 13, ReversalOf: 9, Type: domain.TransactionTypeReversal},
+		},
+		{
+			name:          "Already reversed",
+			transactionID: 8,
+			repoMock: func(m *mock.Mock) {
+				reversed := received
+				reversed.ReversedBy = 12
+				m.On("GetByID", ctx, accountID, 8).Return(reversed, nil)
+			},
+			expectedError: ErrAlreadyReversed,
+		},
+		{
+			name:          "Partial reversal",
+			transactionID: 8,
+			rq:            domain.ReversalRequest{Amount: decimal.NewFromInt(20)},
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, accountID, 8).Return(received, nil)
+				m.On("Reverse", ctx, 8, decimal.NewFromInt(20), "Reversal of transaction 8").Return(domain.TransactionInfo{}, ErrPartialReversal)
+			},
+			expectedError: ErrPartialReversal,
+		},
+		{
+			name:          "Reverse successfully",
+			transactionID: 8,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByID", ctx, accountID, 8).Return(received, nil)
+				m.On("Reverse", ctx, 8, decimal.Decimal{}, "Reversal of transaction 8").
+					Return(domain.TransactionInfo{ID: 12, ReversalOf: 8, Amount: decimal.NewFromInt(-50), Type: domain.TransactionTypeReversal}, nil)
+			},
+			expectedResult: domain.TransactionInfo{ID: 12, ReversalOf: 8, Amount: decimal.NewFromInt(-50), Type: domain.TransactionTypeReversal},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+
+			transactionsService := NewService(repoMock, new(accountsRepositoryMock), new(cardsRepositoryMock), processor.NewFake(nil), new(feesServiceMock))
+
+			trx, err := transactionsService.Reverse(ctx, accountID, testCase.transactionID, testCase.rq)
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedResult, trx)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_service_refund(t *testing.T) {
+	ctx := context.Background()
+	card := domain.Card{ID: 3, Token: "tok_3"}
+	fakeProcessor := processor.NewFake(vaultStub{card.Token: "4509953566233704"})
+	s := &service{processor: fakeProcessor}
+
+	reference, err := fakeProcessor.Charge(ctx, card, decimal.NewFromInt(500))
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.refund(ctx, reference))
+	_, charged := fakeProcessor.Charged(reference)
+	assert.False(t, charged)
+
+	// refunding the same charge again is a no-op
+	assert.NoError(t, s.refund(ctx, reference))
+
+	assert.Equal(t, ErrRefundFailed, s.refund(ctx, "fake-unknown"))
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	trx := domain.TransactionInfo{ID: 42, DateTime: time.Date(2022, 11, 20, 10, 30, 0, 123, time.UTC)}
+
+	c, err := decodeCursor(encodeCursor(trx))
+	assert.NoError(t, err)
+	assert.Equal(t, trx.ID, c.id)
+	assert.True(t, trx.DateTime.Equal(c.dateTime))
+}
@@ -17,6 +17,10 @@ var (
 	ErrEmailAlreadyRegistered = errors.New("email already registered")
 	ErrUserNotExists          = errors.New("user not exists")
 	ErrEmailNotVerified       = errors.New("email not verified")
+	ErrInvalidRefreshToken    = errors.New("invalid refresh token")
+	ErrInvalidToken           = errors.New("invalid token")
+	ErrInvalidCode            = errors.New("invalid or expired code")
+	ErrUnsupported            = errors.New("not supported by the identity provider")
 )
 
 type UserDto struct {
@@ -32,8 +36,11 @@ type UserDto struct {
 
 type Service interface {
 	Login(ctx context.Context, rq domain.LoginRequest) (domain.LoginResponse, error)
-	Logout(ctx context.Context, token string) error
+	Refresh(ctx context.Context, refreshToken string) (domain.LoginResponse, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
 	ForgotPassword(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, code string) error
+	ResetPassword(ctx context.Context, code string, password string) error
 	UpdateUser(ctx context.Context, accountDto domain.AccountDto, id int) error
 	GetByID(ctx context.Context, id int) (domain.UserDB, error)
 }
@@ -58,7 +65,7 @@ func (s *service) Login(ctx context.Context, rq domain.LoginRequest) (domain.Log
 		Password: rq.Password,
 	}
 
-	token, err := s.auth.Login(ctx, lu)
+	session, err := s.auth.Login(ctx, lu)
 	if err != nil {
 		logger.Error(err.Error())
 
@@ -72,25 +79,72 @@ func (s *service) Login(ctx context.Context, rq domain.LoginRequest) (domain.Log
 		}
 	}
 
-	return domain.LoginResponse{Token: token}, nil
+	return session, nil
 }
 
-func (s *service) Logout(ctx context.Context, token string) error {
-	return s.auth.Logout(ctx, token)
+func (s *service) Refresh(ctx context.Context, refreshToken string) (domain.LoginResponse, error) {
+	session, err := s.auth.Refresh(ctx, refreshToken)
+	if err != nil {
+		if err == auth.ErrInvalidRefreshToken {
+			return domain.LoginResponse{}, ErrInvalidRefreshToken
+		}
+		return domain.LoginResponse{}, ErrInternal
+	}
+
+	return session, nil
+}
+
+// Logout ends the session of the refresh token, if it belongs to the user of
+// the access token.
+func (s *service) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	authID, err := s.auth.GetIDFromToken(ctx, accessToken)
+	if err != nil {
+		logger.Error(err.Error())
+		return ErrInvalidToken
+	}
+
+	err = s.auth.Logout(ctx, authID, refreshToken)
+	if err == auth.ErrInvalidRefreshToken {
+		return ErrInvalidRefreshToken
+	}
+
+	return err
 }
 
 func (s *service) ForgotPassword(ctx context.Context, email string) error {
 	filters := domain.GetUserFilters{
 		Email: email,
 	}
-	users, err := s.auth.GetUsersByEmail(ctx, filters)
+	users, err := s.auth.GetUsers(ctx, filters)
 	if err != nil {
 		logger.Error(err.Error())
 
 		return err
 	}
+	if len(users) == 0 {
+		return ErrUserNotExists
+	}
+
+	return s.auth.SendEmail(ctx, users[0].ID)
+}
+
+func (s *service) VerifyEmail(ctx context.Context, code string) error {
+	return codeError(s.auth.VerifyEmail(ctx, code))
+}
+
+func (s *service) ResetPassword(ctx context.Context, code string, password string) error {
+	return codeError(s.auth.ResetPassword(ctx, code, password))
+}
 
-	return s.auth.SendEmail(ctx, *users[0].ID)
+func codeError(err error) error {
+	switch err {
+	case auth.ErrInvalidCode:
+		return ErrInvalidCode
+	case auth.ErrUnsupported:
+		return ErrUnsupported
+	default:
+		return err
+	}
 }
 
 func (s *service) UpdateUser(ctx context.Context, accountDto domain.AccountDto, id int) error {
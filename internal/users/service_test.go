@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/Nerzal/gocloak/v12"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
@@ -49,7 +48,8 @@ func TestService_Logout(t *testing.T) {
 		{
 			name: "Successfully logout",
 			authMock: func(m *mock.Mock) {
-				m.On("Logout", ctx, token).Return(nil).Once()
+				m.On("GetIDFromToken", ctx, "Bearer access").Return("user-1", nil).Once()
+				m.On("Logout", ctx, "user-1", token).Return(nil).Once()
 			},
 		},
 	}
@@ -63,7 +63,7 @@ func TestService_Logout(t *testing.T) {
 
 			usersService := NewUsers(authMock, repo, []string{"Perro", "Gato"})
 
-			err = usersService.Logout(ctx, token)
+			err = usersService.Logout(ctx, "Bearer access", token)
 
 			assert.Equal(t, nil, err)
 		})
@@ -83,13 +83,21 @@ func Test_service_ForgotPassword(t *testing.T) {
 		{
 			name: "Successfully forgot password",
 			domainMock: func(m *mock.Mock) {
-				m.On("GetUsersByEmail", ctx, email).
+				m.On("GetUsers", ctx, domain.GetUserFilters{Email: email}).
 					Return(
-						[]*gocloak.User{}, errors.New("error"),
+						[]domain.Identity{}, errors.New("error"),
 					).Once()
 			},
 			expectedError: errors.New("error"),
 		},
+		{
+			name: "Unknown email",
+			domainMock: func(m *mock.Mock) {
+				m.On("GetUsers", ctx, domain.GetUserFilters{Email: email}).
+					Return([]domain.Identity{}, nil).Once()
+			},
+			expectedError: ErrUserNotExists,
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -0,0 +1,163 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+var (
+	ErrInvalidKey        = errors.New("card encryption key must have 32 bytes")
+	ErrInvalidCiphertext = errors.New("invalid card ciphertext")
+)
+
+const keySize = 32
+
+// Cipher encrypts card numbers with envelope encryption: every PAN gets its own
+// random data key, and the data key is stored wrapped with the master key from
+// config. The master key is also used to derive the fingerprint key, so the
+// same PAN always gets the same fingerprint without storing it in clear.
+type Cipher struct {
+	master         cipher.AEAD
+	fingerprintKey []byte
+}
+
+// EncryptedPAN is a PAN ready to be stored.
+type EncryptedPAN struct {
+	Ciphertext  string
+	DataKey     string
+	Fingerprint string
+	LastFour    string
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, ErrInvalidKey
+	}
+
+	master, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("card-fingerprint"))
+
+	return &Cipher{master: master, fingerprintKey: mac.Sum(nil)}, nil
+}
+
+// NewCipherFromBase64 builds a Cipher from a base64 encoded master key, the way
+// it is kept in config.
+func NewCipherFromBase64(key string) (*Cipher, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return NewCipher(decoded)
+}
+
+func (c *Cipher) Encrypt(pan string) (EncryptedPAN, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return EncryptedPAN{}, err
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return EncryptedPAN{}, err
+	}
+
+	ciphertext, err := seal(data, []byte(pan))
+	if err != nil {
+		return EncryptedPAN{}, err
+	}
+
+	wrappedKey, err := seal(c.master, dataKey)
+	if err != nil {
+		return EncryptedPAN{}, err
+	}
+
+	return EncryptedPAN{
+		Ciphertext:  base64.StdEncoding.EncodeToString(ciphertext),
+		DataKey:     base64.StdEncoding.EncodeToString(wrappedKey),
+		Fingerprint: c.Fingerprint(pan),
+		LastFour:    pan[len(pan)-4:],
+	}, nil
+}
+
+func (c *Cipher) Decrypt(ciphertext, dataKey string) (string, error) {
+	wrappedKey, err := base64.StdEncoding.DecodeString(dataKey)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	key, err := open(c.master, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	pan, err := open(data, sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pan), nil
+}
+
+// Fingerprint is a keyed hash of the PAN, used to find a card without
+// decrypting every stored one.
+func (c *Cipher) Fingerprint(pan string) string {
+	mac := hmac.New(sha256.New, c.fingerprintKey)
+	mac.Write([]byte(pan))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prepends the random nonce to the result.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,48 @@
+package vault
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	cipher := testCipher(t)
+
+	first, err := cipher.Encrypt("4509953566233704")
+	assert.NoError(t, err)
+	second, err := cipher.Encrypt("4509953566233704")
+	assert.NoError(t, err)
+
+	// every encryption uses a new data key, but the fingerprint is stable
+	assert.NotEqual(t, first.Ciphertext, second.Ciphertext)
+	assert.NotEqual(t, first.DataKey, second.DataKey)
+	assert.Equal(t, first.Fingerprint, second.Fingerprint)
+	assert.NotEqual(t, first.Fingerprint, cipher.Fingerprint("4509953566233705"))
+	assert.Equal(t, "3704", first.LastFour)
+
+	pan, err := cipher.Decrypt(first.Ciphertext, first.DataKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "4509953566233704", pan)
+
+	// a data key cannot open another card
+	_, err = cipher.Decrypt(first.Ciphertext, second.DataKey)
+	assert.Equal(t, ErrInvalidCiphertext, err)
+
+	other, err := NewCipher([]byte("fedcba9876543210fedcba9876543210"))
+	assert.NoError(t, err)
+	_, err = other.Decrypt(first.Ciphertext, first.DataKey)
+	assert.Equal(t, ErrInvalidCiphertext, err)
+}
+
+func TestNewCipherFromBase64(t *testing.T) {
+	_, err := NewCipherFromBase64(base64.StdEncoding.EncodeToString(testKey))
+	assert.NoError(t, err)
+
+	_, err = NewCipherFromBase64("")
+	assert.Equal(t, ErrInvalidKey, err)
+
+	_, err = NewCipherFromBase64("not base64!")
+	assert.Equal(t, ErrInvalidKey, err)
+}
@@ -0,0 +1,71 @@
+package vault
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const mysqlDuplicateEntry = 1062
+
+type Repository interface {
+	// Save stores the entry and returns it. If the PAN was already stored, it
+	// returns the existing entry instead.
+	Save(ctx context.Context, entry Entry) (Entry, error)
+	GetByToken(ctx context.Context, token string) (Entry, error)
+	GetByFingerprint(ctx context.Context, fingerprint string) (Entry, error)
+	LogAccess(ctx context.Context, log AccessLog) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Save(ctx context.Context, entry Entry) (Entry, error) {
+	query := "INSERT INTO vault_tokens(token, fingerprint, ciphertext, data_key, last_four, created_at) VALUES(?, ?, ?, ?, ?, ?);"
+	_, err := r.db.ExecContext(ctx, query, entry.Token, entry.Fingerprint, entry.Ciphertext, entry.DataKey, entry.LastFour, entry.CreatedAt)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return r.GetByFingerprint(ctx, entry.Fingerprint)
+		}
+		return Entry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *repository) GetByToken(ctx context.Context, token string) (Entry, error) {
+	query := "SELECT token, fingerprint, ciphertext, data_key, last_four, created_at FROM vault_tokens WHERE token = ?;"
+	return scanEntry(r.db.QueryRowContext(ctx, query, token))
+}
+
+func (r *repository) GetByFingerprint(ctx context.Context, fingerprint string) (Entry, error) {
+	query := "SELECT token, fingerprint, ciphertext, data_key, last_four, created_at FROM vault_tokens WHERE fingerprint = ?;"
+	return scanEntry(r.db.QueryRowContext(ctx, query, fingerprint))
+}
+
+func (r *repository) LogAccess(ctx context.Context, log AccessLog) error {
+	query := "INSERT INTO vault_access_log(token, action, purpose, success, created_at) VALUES(?, ?, ?, ?, ?);"
+	_, err := r.db.ExecContext(ctx, query, log.Token, log.Action, log.Purpose, log.Success, log.CreatedAt)
+	return err
+}
+
+func scanEntry(row *sql.Row) (Entry, error) {
+	var entry Entry
+	err := row.Scan(&entry.Token, &entry.Fingerprint, &entry.Ciphertext, &entry.DataKey, &entry.LastFour, &entry.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Entry{}, ErrTokenNotFound
+		}
+		return Entry{}, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,157 @@
+// Package vault keeps the card numbers of the wallet and hands out opaque
+// tokens in their place. The rest of the codebase stores and passes tokens;
+// only the vault can turn a token back into a PAN, and every time it does so it
+// leaves a record in the access log.
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrInvalidPAN    = errors.New("invalid pan")
+)
+
+const (
+	ActionTokenize   = "tokenize"
+	ActionDetokenize = "detokenize"
+
+	tokenPrefix = "tok_"
+)
+
+// Tokenizer swaps card numbers for tokens. It is what the cards package gets.
+type Tokenizer interface {
+	// Tokenize returns the token of the PAN, the same one every time the same
+	// PAN is given.
+	Tokenize(ctx context.Context, pan string) (Token, error)
+}
+
+// Detokenizer gives back the PAN of a token. It must only be handed to the card
+// processor; purpose is recorded in the access log.
+type Detokenizer interface {
+	Detokenize(ctx context.Context, token, purpose string) (string, error)
+}
+
+type Token struct {
+	Value    string
+	LastFour string
+}
+
+// Entry is a stored card number.
+type Entry struct {
+	Token       string
+	Fingerprint string
+	Ciphertext  string
+	DataKey     string
+	LastFour    string
+	CreatedAt   time.Time
+}
+
+// AccessLog is an audit record of a use of the vault.
+type AccessLog struct {
+	Token     string
+	Action    string
+	Purpose   string
+	Success   bool
+	CreatedAt time.Time
+}
+
+type Vault struct {
+	repository Repository
+	cipher     *Cipher
+	now        func() time.Time
+}
+
+func New(repository Repository, cipher *Cipher) *Vault {
+	return &Vault{repository: repository, cipher: cipher, now: time.Now}
+}
+
+func (v *Vault) Tokenize(ctx context.Context, pan string) (Token, error) {
+	if len(pan) < 4 {
+		return Token{}, ErrInvalidPAN
+	}
+
+	entry, err := v.repository.GetByFingerprint(ctx, v.cipher.Fingerprint(pan))
+	if err == nil {
+		return Token{Value: entry.Token, LastFour: entry.LastFour}, nil
+	}
+	if err != ErrTokenNotFound {
+		return Token{}, err
+	}
+
+	encrypted, err := v.cipher.Encrypt(pan)
+	if err != nil {
+		return Token{}, err
+	}
+
+	value, err := newToken()
+	if err != nil {
+		return Token{}, err
+	}
+
+	entry = Entry{
+		Token:       value,
+		Fingerprint: encrypted.Fingerprint,
+		Ciphertext:  encrypted.Ciphertext,
+		DataKey:     encrypted.DataKey,
+		LastFour:    encrypted.LastFour,
+		CreatedAt:   v.now().UTC(),
+	}
+
+	// a concurrent request may have stored the same PAN first, use its token
+	entry, err = v.repository.Save(ctx, entry)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if err = v.log(ctx, entry.Token, ActionTokenize, "", true); err != nil {
+		return Token{}, err
+	}
+
+	return Token{Value: entry.Token, LastFour: entry.LastFour}, nil
+}
+
+// Detokenize returns the PAN of the token. Failed attempts are logged too.
+func (v *Vault) Detokenize(ctx context.Context, token, purpose string) (string, error) {
+	pan, err := v.detokenize(ctx, token)
+	if logErr := v.log(ctx, token, ActionDetokenize, purpose, err == nil); logErr != nil {
+		// a PAN is never given out without its audit record
+		return "", logErr
+	}
+
+	return pan, err
+}
+
+func (v *Vault) detokenize(ctx context.Context, token string) (string, error) {
+	entry, err := v.repository.GetByToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	return v.cipher.Decrypt(entry.Ciphertext, entry.DataKey)
+}
+
+func (v *Vault) log(ctx context.Context, token, action, purpose string, success bool) error {
+	return v.repository.LogAccess(ctx, AccessLog{
+		Token:     token,
+		Action:    action,
+		Purpose:   purpose,
+		Success:   success,
+		CreatedAt: v.now().UTC(),
+	})
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+
+	return tokenPrefix + hex.EncodeToString(b), nil
+}
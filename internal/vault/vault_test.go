@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func testCipher(t *testing.T) *Cipher {
+	cipher, err := NewCipher(testKey)
+	assert.NoError(t, err)
+	return cipher
+}
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) Save(ctx context.Context, entry Entry) (Entry, error) {
+	args := r.Called(ctx, entry)
+	return args.Get(0).(Entry), args.Error(1)
+}
+
+func (r *repositoryMock) GetByToken(ctx context.Context, token string) (Entry, error) {
+	args := r.Called(ctx, token)
+	return args.Get(0).(Entry), args.Error(1)
+}
+
+func (r *repositoryMock) GetByFingerprint(ctx context.Context, fingerprint string) (Entry, error) {
+	args := r.Called(ctx, fingerprint)
+	return args.Get(0).(Entry), args.Error(1)
+}
+
+func (r *repositoryMock) LogAccess(ctx context.Context, log AccessLog) error {
+	args := r.Called(ctx, log)
+	return args.Error(0)
+}
+
+func TestVault_Tokenize(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	cipher := testCipher(t)
+	pan := "4509953566233704"
+	stored := Entry{Token: "tok_existing", Fingerprint: cipher.Fingerprint(pan), LastFour: "3704"}
+
+	testCases := []struct {
+		name          string
+		repoMock      func(m *mock.Mock)
+		expectedError error
+		expectedToken string
+	}{
+		{
+			name: "PAN already in the vault",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByFingerprint", ctx, stored.Fingerprint).Return(stored, nil)
+			},
+			expectedToken: stored.Token,
+		},
+		{
+			name: "Repository error",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByFingerprint", ctx, stored.Fingerprint).Return(Entry{}, errors.New("error"))
+			},
+			expectedError: errors.New("error"),
+		},
+		{
+			name: "New PAN",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByFingerprint", ctx, stored.Fingerprint).Return(Entry{}, ErrTokenNotFound)
+				m.On("Save", ctx, mock.MatchedBy(func(e Entry) bool {
+					return e.Fingerprint == stored.Fingerprint && e.LastFour == "3704" && !strings.Contains(e.Ciphertext, pan)
+				})).Return(Entry{Token: "tok_new", LastFour: "3704"}, nil)
+				m.On("LogAccess", ctx, AccessLog{Token: "tok_new", Action: ActionTokenize, Success: true, CreatedAt: now}).Return(nil)
+			},
+			expectedToken: "tok_new",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+
+			v := &Vault{repository: repoMock, cipher: cipher, now: func() time.Time { return now }}
+
+			token, err := v.Tokenize(ctx, pan)
+
+			assert.Equal(t, testCase.expectedError, err)
+			if err == nil {
+				assert.Equal(t, Token{Value: testCase.expectedToken, LastFour: "3704"}, token)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestVault_Detokenize(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	cipher := testCipher(t)
+	encrypted, err := cipher.Encrypt("4509953566233704")
+	assert.NoError(t, err)
+	entry := Entry{Token: "tok_1", Fingerprint: encrypted.Fingerprint, Ciphertext: encrypted.Ciphertext, DataKey: encrypted.DataKey, LastFour: "3704"}
+
+	testCases := []struct {
+		name          string
+		token         string
+		repoMock      func(m *mock.Mock)
+		expectedError error
+		expectedPAN   string
+	}{
+		{
+			name:  "Unknown token is logged as failed",
+			token: "tok_unknown",
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByToken", ctx, "tok_unknown").Return(Entry{}, ErrTokenNotFound)
+				m.On("LogAccess", ctx, AccessLog{Token: "tok_unknown", Action: ActionDetokenize, Purpose: "charge", CreatedAt: now}).Return(nil)
+			},
+			expectedError: ErrTokenNotFound,
+		},
+		{
+			name:  "No PAN without audit record",
+			token: entry.Token,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByToken", ctx, entry.Token).Return(entry, nil)
+				m.On("LogAccess", ctx, mock.Anything).Return(errors.New("error"))
+			},
+			expectedError: errors.New("error"),
+		},
+		{
+			name:  "Detokenized",
+			token: entry.Token,
+			repoMock: func(m *mock.Mock) {
+				m.On("GetByToken", ctx, entry.Token).Return(entry, nil)
+				m.On("LogAccess", ctx, AccessLog{Token: entry.Token, Action: ActionDetokenize, Purpose: "charge", Success: true, CreatedAt: now}).Return(nil)
+			},
+			expectedPAN: "4509953566233704",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			repoMock := new(repositoryMock)
+			testCase.repoMock(&repoMock.Mock)
+
+			v := &Vault{repository: repoMock, cipher: cipher, now: func() time.Time { return now }}
+
+			pan, err := v.Detokenize(ctx, testCase.token, "charge")
+
+			assert.Equal(t, testCase.expectedError, err)
+			assert.Equal(t, testCase.expectedPAN, pan)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
@@ -6,8 +6,6 @@ import (
 	context "context"
 	"gitlab.com/leorodriguez/grupo-04/internal/domain"
 
-	gocloak "github.com/Nerzal/gocloak/v12"
-
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -16,22 +14,22 @@ type Auth struct {
 	mock.Mock
 }
 
-// GetUsersByEmail provides a mock function with given fields: ctx, email
-func (_m *Auth) GetUsersByEmail(ctx context.Context, email string) ([]*gocloak.User, error) {
-	ret := _m.Called(ctx, email)
+// GetUsers provides a mock function with given fields: ctx, filters
+func (_m *Auth) GetUsers(ctx context.Context, filters domain.GetUserFilters) ([]domain.Identity, error) {
+	ret := _m.Called(ctx, filters)
 
-	var r0 []*gocloak.User
-	if rf, ok := ret.Get(0).(func(context.Context, string) []*gocloak.User); ok {
-		r0 = rf(ctx, email)
+	var r0 []domain.Identity
+	if rf, ok := ret.Get(0).(func(context.Context, domain.GetUserFilters) []domain.Identity); ok {
+		r0 = rf(ctx, filters)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*gocloak.User)
+			r0 = ret.Get(0).([]domain.Identity)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, email)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.GetUserFilters) error); ok {
+		r1 = rf(ctx, filters)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -40,19 +38,26 @@ func (_m *Auth) GetUsersByEmail(ctx context.Context, email string) ([]*gocloak.U
 }
 
 // Login provides a mock function with given fields: ctx, rq
-func (_m *Auth) Login(ctx context.Context, rq domain.LoginUser) (string, error) {
+func (_m *Auth) Login(ctx context.Context, rq domain.LoginUser) (domain.LoginResponse, error) {
 	ret := _m.Called(ctx, rq)
 
-	return ret.String(0), ret.Error(1)
+	return ret.Get(0).(domain.LoginResponse), ret.Error(1)
+}
+
+// Refresh provides a mock function with given fields: ctx, refreshToken
+func (_m *Auth) Refresh(ctx context.Context, refreshToken string) (domain.LoginResponse, error) {
+	ret := _m.Called(ctx, refreshToken)
+
+	return ret.Get(0).(domain.LoginResponse), ret.Error(1)
 }
 
-// Logout provides a mock function with given fields: ctx, token
-func (_m *Auth) Logout(ctx context.Context, token string) error {
-	ret := _m.Called(ctx, token)
+// Logout provides a mock function with given fields: ctx, authID, refreshToken
+func (_m *Auth) Logout(ctx context.Context, authID string, refreshToken string) error {
+	ret := _m.Called(ctx, authID, refreshToken)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, token)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, authID, refreshToken)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -123,6 +128,41 @@ func (_m *Auth) UserExists(ctx context.Context, email string) bool {
 	return r0
 }
 
+// GetIDFromToken provides a mock function with given fields: ctx, accessToken
+func (_m *Auth) GetIDFromToken(ctx context.Context, accessToken string) (string, error) {
+	ret := _m.Called(ctx, accessToken)
+
+	return ret.String(0), ret.Error(1)
+}
+
+// HasRole provides a mock function with given fields: ctx, accessToken, role
+func (_m *Auth) HasRole(ctx context.Context, accessToken string, role string) (bool, error) {
+	ret := _m.Called(ctx, accessToken, role)
+
+	return ret.Bool(0), ret.Error(1)
+}
+
+// Update provides a mock function with given fields: ctx, rq, authID
+func (_m *Auth) Update(ctx context.Context, rq domain.RegisterUser, authID string) error {
+	ret := _m.Called(ctx, rq, authID)
+
+	return ret.Error(0)
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, code
+func (_m *Auth) VerifyEmail(ctx context.Context, code string) error {
+	ret := _m.Called(ctx, code)
+
+	return ret.Error(0)
+}
+
+// ResetPassword provides a mock function with given fields: ctx, code, password
+func (_m *Auth) ResetPassword(ctx context.Context, code string, password string) error {
+	ret := _m.Called(ctx, code, password)
+
+	return ret.Error(0)
+}
+
 type mockConstructorTestingTNewAuth interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,155 @@
+package jwks
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// minRefreshInterval keeps tokens with made up key ids from turning every
+// request into a call to the identity provider.
+const minRefreshInterval = time.Minute
+
+// KeySet finds the public key a token was signed with by its key id.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// StaticKeys is a fixed key set, for tests and for running without the
+// identity provider.
+type StaticKeys map[string]*rsa.PublicKey
+
+func (k StaticKeys) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	key, ok := k[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// Remote is the key set published at a JWKS endpoint. Keys are cached and the
+// set is fetched again when a token names a key id that is not cached, which
+// is how rotated keys get picked up.
+type Remote struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	lastRefresh time.Time
+}
+
+func NewRemote(url string) *Remote {
+	return &Remote{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		now:    time.Now,
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+func (r *Remote) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[kid]; ok {
+		return key, nil
+	}
+
+	if !r.lastRefresh.IsZero() && r.now().Sub(r.lastRefresh) < minRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+
+	if err := r.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// refresh replaces the cached keys with the ones currently published.
+func (r *Remote) refresh(ctx context.Context) error {
+	r.lastRefresh = r.now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching keys: unexpected status %d", res.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return fmt.Errorf("fetching keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		// encryption keys are published in the same set, only signing keys matter here
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		key, err := rsaKey(k)
+		if err != nil {
+			return fmt.Errorf("fetching keys: key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	r.keys = keys
+
+	return nil
+}
+
+func rsaKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 || exponent.Int64() < 2 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,122 @@
+package jwks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Every token error wraps ErrInvalidToken, so callers that do not care why a
+// token was rejected can check just that one.
+var (
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrTokenMalformed   = fmt.Errorf("%w: malformed", ErrInvalidToken)
+	ErrTokenSignature   = fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	ErrTokenExpired     = fmt.Errorf("%w: expired", ErrInvalidToken)
+	ErrTokenNotYetValid = fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	ErrWrongIssuer      = fmt.Errorf("%w: wrong issuer", ErrInvalidToken)
+	ErrWrongAudience    = fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+)
+
+// DefaultLeeway is the clock skew allowed between us and the token issuer.
+const DefaultLeeway = 30 * time.Second
+
+type VerifierSettings struct {
+	Keys     KeySet
+	Issuer   string
+	Audience string
+	// Leeway defaults to DefaultLeeway when nil. A zero leeway allows no skew.
+	Leeway *time.Duration
+	// Now defaults to time.Now, issuers with their own clock can share it.
+	Now func() time.Time
+}
+
+// Verifier checks access tokens locally: the signature against the key set,
+// then issuer, audience, expiry and not before.
+type Verifier struct {
+	keys     KeySet
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+	parser   *jwt.Parser
+}
+
+func NewVerifier(settings VerifierSettings) *Verifier {
+	leeway := DefaultLeeway
+	if settings.Leeway != nil {
+		leeway = *settings.Leeway
+	}
+
+	now := settings.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Verifier{
+		keys:     settings.Keys,
+		issuer:   settings.Issuer,
+		audience: settings.Audience,
+		leeway:   leeway,
+		now:      now,
+		// the claims are checked here instead, the library has no leeway
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}), jwt.WithoutClaimsValidation()),
+	}
+}
+
+// Verify returns the claims of the token if it is valid. The token may come
+// with its Bearer prefix.
+func (v *Verifier) Verify(ctx context.Context, accessToken string) (jwt.MapClaims, error) {
+	accessToken = strings.TrimPrefix(accessToken, "Bearer ")
+
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			return nil, ErrTokenMalformed
+		case errors.Is(err, ErrUnknownKey):
+			return nil, ErrTokenSignature
+		case errors.Is(err, jwt.ErrTokenUnverifiable):
+			// the key set could not be loaded, that is on us and not on the token
+			return nil, fmt.Errorf("loading signing keys: %w", errors.Unwrap(err))
+		default:
+			return nil, ErrTokenSignature
+		}
+	}
+
+	if err = v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) validate(claims jwt.MapClaims) error {
+	now := v.now()
+
+	if _, ok := claims["exp"].(float64); !ok {
+		return ErrTokenMalformed
+	}
+	if !claims.VerifyExpiresAt(now.Add(-v.leeway).Unix(), true) {
+		return ErrTokenExpired
+	}
+	if !claims.VerifyNotBefore(now.Add(v.leeway).Unix(), false) {
+		return ErrTokenNotYetValid
+	}
+	if !claims.VerifyIssuer(v.issuer, true) {
+		return ErrWrongIssuer
+	}
+	if !claims.VerifyAudience(v.audience, true) {
+		return ErrWrongAudience
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package jwks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "http://localhost:8086/realms/realm-test"
+	testAudience = "account"
+)
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestVerifierVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Date(2023, 3, 15, 14, 30, 0, 0, time.UTC)
+	claims := func(changes jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "user-1",
+			"iss": testIssuer,
+			"aud": []string{testAudience, "other"},
+			"exp": now.Add(5 * time.Minute).Unix(),
+			"iat": now.Unix(),
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	verifier := NewVerifier(VerifierSettings{
+		Keys:     StaticKeys{"key-1": &key.PublicKey},
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	})
+	verifier.now = func() time.Time { return now }
+
+	testCases := []struct {
+		name          string
+		token         string
+		expectedError error
+	}{
+		{
+			name:  "Valid",
+			token: sign(t, key, "key-1", claims(nil)),
+		},
+		{
+			name:  "Valid with Bearer prefix",
+			token: "Bearer " + sign(t, key, "key-1", claims(nil)),
+		},
+		{
+			name:  "Expired within the leeway",
+			token: sign(t, key, "key-1", claims(jwt.MapClaims{"exp": now.Add(-10 * time.Second).Unix()})),
+		},
+		{
+			name:          "Expired",
+			token:         sign(t, key, "key-1", claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})),
+			expectedError: ErrTokenExpired,
+		},
+		{
+			name:          "Without expiry",
+			token:         sign(t, key, "key-1", claims(jwt.MapClaims{"exp": nil})),
+			expectedError: ErrTokenMalformed,
+		},
+		{
+			name:          "Not valid yet",
+			token:         sign(t, key, "key-1", claims(jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()})),
+			expectedError: ErrTokenNotYetValid,
+		},
+		{
+			name:          "Wrong issuer",
+			token:         sign(t, key, "key-1", claims(jwt.MapClaims{"iss": "http://localhost:8086/realms/other"})),
+			expectedError: ErrWrongIssuer,
+		},
+		{
+			name:          "Wrong audience",
+			token:         sign(t, key, "key-1", claims(jwt.MapClaims{"aud": "other"})),
+			expectedError: ErrWrongAudience,
+		},
+		{
+			name:          "Unknown key",
+			token:         sign(t, key, "key-2", claims(nil)),
+			expectedError: ErrTokenSignature,
+		},
+		{
+			name:          "Signed with another key",
+			token:         sign(t, otherKey, "key-1", claims(nil)),
+			expectedError: ErrTokenSignature,
+		},
+		{
+			name:          "Not a token",
+			token:         "abc",
+			expectedError: ErrTokenMalformed,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := verifier.Verify(context.Background(), testCase.token)
+
+			assert.Equal(t, testCase.expectedError, err)
+			if testCase.expectedError == nil {
+				assert.Equal(t, "user-1", result["sub"])
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestVerifierWithoutLeeway(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	now := time.Now()
+
+	noLeeway := time.Duration(0)
+	verifier := NewVerifier(VerifierSettings{
+		Keys:     StaticKeys{"key-1": &key.PublicKey},
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		Leeway:   &noLeeway,
+		Now:      func() time.Time { return now },
+	})
+
+	token := sign(t, key, "key-1", jwt.MapClaims{
+		"sub": "user-1",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": now.Add(-10 * time.Second).Unix(),
+	})
+	_, err = verifier.Verify(context.Background(), token)
+	assert.Equal(t, ErrTokenExpired, err)
+}
+
+func TestRemoteKeyRotation(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	published := map[string]*rsa.PublicKey{"key-1": &first.PublicKey}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		var keys []jsonWebKey
+		for kid, key := range published {
+			keys = append(keys, jsonWebKey{
+				Kid: kid,
+				Kty: "RSA",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer server.Close()
+
+	now := time.Date(2023, 3, 15, 14, 30, 0, 0, time.UTC)
+	remote := NewRemote(server.URL)
+	remote.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	key, err := remote.Key(ctx, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &first.PublicKey, key)
+
+	// cached keys are not fetched again
+	_, err = remote.Key(ctx, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+
+	// a rotated key shows up once the refresh interval is over
+	published["key-2"] = &second.PublicKey
+	_, err = remote.Key(ctx, "key-2")
+	assert.Equal(t, ErrUnknownKey, err)
+	assert.Equal(t, 1, fetches)
+
+	now = now.Add(minRefreshInterval)
+	key, err = remote.Key(ctx, "key-2")
+	assert.NoError(t, err)
+	assert.Equal(t, &second.PublicKey, key)
+	assert.Equal(t, 2, fetches)
+}
@@ -5,7 +5,8 @@ import (
 	"log"
 )
 
-var zapLog *zap.Logger
+// zapLog discards everything until Init is called, so packages can log from tests.
+var zapLog = zap.NewNop()
 
 func Init() {
 	logger, err := zap.NewDevelopment()
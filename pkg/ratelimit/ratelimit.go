@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows up to limit calls per key in fixed windows of the given
+// length. It keeps its counters in memory, so each replica limits on its own.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+type counter struct {
+	count int
+	reset time.Time
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:    limit,
+		window:   window,
+		now:      time.Now,
+		counters: make(map[string]*counter),
+	}
+}
+
+// Allow counts a call for key. If the key is over its limit it returns false
+// and how long until its window resets.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	c, ok := l.counters[key]
+	if !ok || !now.Before(c.reset) {
+		c = &counter{reset: now.Add(l.window)}
+		l.counters[key] = c
+	}
+
+	if c.count >= l.limit {
+		return false, c.reset.Sub(now)
+	}
+
+	c.count++
+	return true, 0
+}
+
+// sweep drops the counters of finished windows, at most once per window.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+
+	for key, c := range l.counters {
+		if !now.Before(c.reset) {
+			delete(l.counters, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -13,9 +13,10 @@ type response struct {
 }
 
 type errorResponse struct {
-	Status  int    `json:"-"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
 }
 
 func Response(c *gin.Context, status int, data interface{}) {
@@ -31,3 +32,16 @@ func Error(c *gin.Context, status int, format string, args ...interface{}) {
 
 	Response(c, status, err)
 }
+
+// ErrorWithDetails is Error with extra data about the failure, such as the list
+// of invalid fields of a request.
+func ErrorWithDetails(c *gin.Context, status int, details interface{}, format string, args ...interface{}) {
+	err := errorResponse{
+		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
+		Message: fmt.Sprintf(format, args...),
+		Details: details,
+		Status:  status,
+	}
+
+	Response(c, status, err)
+}